@@ -13,10 +13,16 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"os"
+	"strings"
 
 	"github.com/benhoyt/goawk/interp"
 	"github.com/benhoyt/goawk/parser"
+	"github.com/gomoni/gio/pipe"
 	"github.com/gomoni/gio/unix"
+	"github.com/gomoni/gonix"
+	"github.com/gomoni/gonix/internal"
+	"github.com/spf13/pflag"
 )
 
 func NewConfig() *interp.Config {
@@ -42,6 +48,14 @@ func New(prog *parser.Program, config *interp.Config) AWK {
 	}
 }
 
+func init() {
+	gonix.Register(gonix.Cmd{
+		Name:  "awk",
+		Usage: "pattern scanning and processing language",
+		New:   gonix.FromArgs(AWK{}.FromArgs),
+	})
+}
+
 func Compile(src []byte, config *interp.Config) (AWK, error) {
 	if config == nil {
 		return AWK{}, fmt.Errorf("nil config")
@@ -61,6 +75,66 @@ func Compile(src []byte, config *interp.Config) (AWK, error) {
 	}, nil
 }
 
+// FromArgs builds an AWK from standard argv except the command name (os.Argv[1:])
+//
+//	awk [-F fs] [-v var=value]... ['prog' | -f progfile] [file ...]
+//
+// Configuration starts from NewConfig, except that files passed on a command
+// line can be read.
+func (c AWK) FromArgs(argv []string) (AWK, error) {
+	flag := pflag.FlagSet{}
+	flag.SetInterspersed(false)
+	fs := flag.StringP("field-separator", "F", "", "use fs for the input field separator")
+	assigns := flag.StringArrayP("assign", "v", nil, "assign value to variable var before the program starts")
+	progFile := flag.StringP("file", "f", "", "read the program source from progfile")
+
+	err := flag.Parse(argv)
+	if err != nil {
+		return AWK{}, pipe.NewErrorf(1, "awk: parsing failed: %w", err)
+	}
+
+	args := flag.Args()
+	var src []byte
+	if *progFile != "" {
+		src, err = os.ReadFile(*progFile)
+		if err != nil {
+			return AWK{}, pipe.NewErrorf(2, "awk: %w", err)
+		}
+	} else {
+		if len(args) == 0 {
+			return AWK{}, pipe.NewErrorf(2, "awk: missing program")
+		}
+		src = []byte(args[0])
+		args = args[1:]
+	}
+
+	config := NewConfig()
+	if c.config != nil {
+		cp := *c.config
+		config = &cp
+	}
+	if *fs != "" {
+		config.Vars = append(config.Vars, "FS", *fs)
+	}
+	for _, assign := range *assigns {
+		name, value, ok := strings.Cut(assign, "=")
+		if !ok {
+			return AWK{}, pipe.NewErrorf(2, "awk: expected var=value, got %q", assign)
+		}
+		config.Vars = append(config.Vars, name, value)
+	}
+	if len(args) > 0 {
+		config.Args = args
+		config.NoFileReads = false
+	}
+
+	prog, err := Compile(src, config)
+	if err != nil {
+		return AWK{}, pipe.NewErrorf(2, "awk: %w", err)
+	}
+	return prog, nil
+}
+
 func (c AWK) Run(ctx context.Context, stdio unix.StandardIO) error {
 	if c.config == nil {
 		return fmt.Errorf("nil config")
@@ -72,6 +146,15 @@ func (c AWK) Run(ctx context.Context, stdio unix.StandardIO) error {
 	config.Stdin = stdio.Stdin()
 	config.Output = stdio.Stdout()
 	config.Error = stdio.Stderr()
-	_, err := interp.ExecProgram(c.program, &config)
-	return err
+	status, err := interp.ExecProgram(c.program, &config)
+	if internal.Status(err) != 0 {
+		return internal.NewError(1, fmt.Errorf("awk: %w", err))
+	}
+	if err != nil {
+		return err
+	}
+	if status != 0 {
+		return pipe.NewErrorf(status, "awk: exit status %d", status)
+	}
+	return nil
 }
@@ -5,21 +5,22 @@
 package cat
 
 import (
+	"bufio"
 	"bytes"
 	"context"
 	"fmt"
 	"io"
-	"log"
+	"io/fs"
+	"strconv"
+	"unicode"
 
 	"github.com/gomoni/gio/pipe"
 	"github.com/gomoni/gio/unix"
-	"github.com/gomoni/gonix/awk"
+	"github.com/gomoni/gonix"
 	"github.com/gomoni/gonix/internal"
 	"github.com/gomoni/gonix/internal/dbg"
 
 	"github.com/spf13/pflag"
-
-	_ "embed"
 )
 
 type number int
@@ -34,23 +35,10 @@ var (
 	ErrNothingToDo = pipe.NewErrorf(1, "cat: nothing to do")
 )
 
-//go:embed show_ends.awk
-var showEndsAwk []byte
-
-//go:embed show_number.awk
-var showNumberAwk []byte
-
-//go:embed show_number_nonblank.awk
-var showNumberNonBlankAwk []byte
-
-//go:embed squeeze_blanks.awk
-var squeezeBlanksAwk []byte
-
-//go:embed show_tabs.awk
-var showTabsAwk []byte
-
 type Cat struct {
 	debug           bool
+	files0From      string
+	fsys            fs.FS
 	files           []string
 	showNumber      number
 	showEnds        bool
@@ -63,6 +51,14 @@ func New() Cat {
 	return Cat{}
 }
 
+func init() {
+	gonix.Register(gonix.Cmd{
+		Name:  "cat",
+		Usage: "concatenate files and print on the standard output",
+		New:   gonix.FromArgs(New().FromArgs),
+	})
+}
+
 // FromArgs build a Cat from standard argv except the command name (os.Argv[1:])
 func (c Cat) FromArgs(argv []string) (Cat, error) {
 	var zero Cat
@@ -75,6 +71,7 @@ func (c Cat) FromArgs(argv []string) (Cat, error) {
 	flag.Bool("u", false, "ignored, for compatibility with POSIX")
 	flag.BoolVarP(&c.showTabs, "show-tabs", "T", false, "print TAB as ^I")
 	flag.BoolVarP(&c.showNonPrinting, "show-nonprinting", "v", false, "use ^ and M- notation for non printing characters")
+	flag.StringVar(&c.files0From, "files0-from", "", "read input from the files specified by NUL-terminated names in file F")
 
 	// compound options
 	var all, e, t bool
@@ -89,24 +86,27 @@ func (c Cat) FromArgs(argv []string) (Cat, error) {
 	}
 
 	if all {
-		c.ShowNonPrinting(true).ShowEnds(true).ShowTabs(true)
+		c = c.ShowNonPrinting(true).ShowEnds(true).ShowTabs(true)
 	}
 	if e {
-		c.ShowNonPrinting(true).ShowEnds(true)
+		c = c.ShowNonPrinting(true).ShowEnds(true)
 	}
 	if t {
-		c.ShowNonPrinting(true).ShowTabs(true)
+		c = c.ShowNonPrinting(true).ShowTabs(true)
 	}
 
 	if len(flag.Args()) > 0 {
+		if c.files0From != "" {
+			return zero, pipe.NewErrorf(1, "cat: %w", internal.ErrFiles0Operands(flag.Args()[0]))
+		}
 		c.files = flag.Args()
 	}
 
 	// post process
 	if *nb {
-		c.ShowNumber(NonBlank)
+		c = c.ShowNumber(NonBlank)
 	} else if *na {
-		c.ShowNumber(All)
+		c = c.ShowNumber(All)
 	}
 
 	return c, nil
@@ -118,6 +118,18 @@ func (c Cat) Files(f ...string) Cat {
 	return c
 }
 
+// Files0From reads NUL terminated names of files from a file, - means stdin
+func (c Cat) Files0From(name string) Cat {
+	c.files0From = name
+	return c
+}
+
+// FS sets a filesystem used to open files, nil means the OS filesystem
+func (c Cat) FS(fsys fs.FS) Cat {
+	c.fsys = fsys
+	return c
+}
+
 // ShowNumber adds none all or non empty output lines
 func (c Cat) ShowNumber(n number) Cat {
 	c.showNumber = n
@@ -154,72 +166,130 @@ func (c Cat) SetDebug(debug bool) Cat {
 	return c
 }
 
-func (c Cat) modifyStdout() bool {
-	return c.showNumber != None || c.showEnds || c.squeezeBlanks || c.showTabs || c.showNonPrinting
-}
-
 func (c Cat) Run(ctx context.Context, stdio unix.StandardIO) error {
 	debug := dbg.Logger(c.debug, "cat", stdio.Stderr())
-	var filters []unix.Filter
-	if !c.modifyStdout() {
-		filters = []unix.Filter{cat{debug: c.debug}}
-	} else {
-		progs, err := c.awk(debug)
-		if err != nil {
-			return err
-		}
-		filters = make([]unix.Filter, len(progs))
-		for idx, prog := range progs {
-			filters[idx] = unix.Filter(prog)
-		}
-	}
-	if c.showNonPrinting {
-		filters = append(filters, catNonPrinting{})
-	}
-	if len(filters) == 0 {
-		return ErrNothingToDo
+	debug.Printf("c=%+v", c)
+	var filter unix.Filter
+	switch {
+	case c.lineMode():
+		filter = catLines{c: c}
+	case c.showNonPrinting:
+		filter = catNonPrinting{}
+	default:
+		filter = cat{debug: c.debug}
 	}
 
 	cat := func(ctx context.Context, stdio unix.StandardIO, _ int, _ string) error {
-		err := unix.NewLine().Run(ctx, stdio, filters...)
+		err := filter.Run(ctx, stdio)
 		if err != nil {
-			return pipe.NewError(1, fmt.Errorf("cat: fail to run: %w", err))
+			return internal.NewError(1, fmt.Errorf("cat: fail to run: %w", err))
 		}
 		return nil
 	}
 
-	runFiles := internal.NewRunFiles(c.files, stdio, cat)
+	runFiles := internal.NewRunFiles(c.files, stdio, cat).FS(c.fsys).Files0From(c.files0From)
 	return runFiles.Do(ctx)
 }
 
-func (c Cat) awk(debug *log.Logger) ([]awk.AWK, error) {
-	debug.Printf("c=%+v", c)
-	var sources [][]byte
-	if c.showEnds {
-		sources = append(sources, showEndsAwk)
-	}
-	if c.showNumber == All {
-		sources = append(sources, showNumberAwk)
-	} else if c.showNumber == NonBlank {
-		sources = append(sources, showNumberNonBlankAwk)
-	}
-	if c.squeezeBlanks {
-		sources = append(sources, squeezeBlanksAwk)
+// lineMode is true if lines are transformed
+func (c Cat) lineMode() bool {
+	return c.showNumber != None || c.showEnds || c.squeezeBlanks || c.showTabs
+}
+
+// catLines transforms lines in a single pass. Transformations are applied in
+// the order -E, -n/-b, -s, -T and -v and each works on a result of the
+// previous one, so -bE numbers empty lines as they end with $. A line is
+// blank if it contains white space only. Each transformation drops one
+// trailing \r like a line oriented awk program does.
+type catLines struct {
+	c Cat
+}
+
+func (f catLines) Run(ctx context.Context, stdio unix.StandardIO) error {
+	c := f.c
+	w := bufio.NewWriter(stdio.Stdout())
+	var line, tmp []byte
+	var np bytes.Buffer
+	n := 1
+	squeeze := false
+	err := internal.Lines(ctx, stdio.Stdin(), '\n', func(in []byte) (bool, error) {
+		// internal.Lines already dropped \r for the first transformation
+		first := true
+		next := func() {
+			if !first {
+				line = dropCR(line)
+			}
+			first = false
+		}
+
+		line = append(line[:0], in...)
+		if c.showEnds {
+			next()
+			line = append(line, '$')
+		}
+		if c.showNumber != None {
+			next()
+			if c.showNumber == All || !blank(line) {
+				tmp = append(appendLineNumber(tmp[:0], n), line...)
+				line, tmp = tmp, line
+				n++
+			}
+		}
+		if c.squeezeBlanks {
+			next()
+			if blank(line) {
+				if squeeze {
+					return true, nil
+				}
+				squeeze = true
+			} else {
+				squeeze = false
+			}
+		}
+		if c.showTabs {
+			next()
+			// replaces the first tab only
+			if idx := bytes.IndexByte(line, '\t'); idx != -1 {
+				tmp = append(tmp[:0], line[:idx]...)
+				tmp = append(tmp, "^I"...)
+				tmp = append(tmp, line[idx+1:]...)
+				line, tmp = tmp, line
+			}
+		}
+
+		b := line
+		if c.showNonPrinting {
+			nonPrinting(line, &np)
+			b = np.Bytes()
+		}
+		w.Write(b)
+		return true, w.WriteByte('\n')
+	})
+	if err != nil {
+		return err
 	}
-	if c.showTabs {
-		sources = append(sources, showTabsAwk)
+	return w.Flush()
+}
+
+func dropCR(line []byte) []byte {
+	if len(line) > 0 && line[len(line)-1] == '\r' {
+		return line[:len(line)-1]
 	}
+	return line
+}
 
-	progs := make([]awk.AWK, len(sources))
-	for idx, src := range sources {
-		debug.Printf("goawk src[%d] = %q", idx, src)
-		var err error
-		progs[idx], err = awk.Compile(src, awk.NewConfig())
-		if err != nil {
-			return nil, err
-		}
+// blank is true if line is empty or contains white space only
+func blank(line []byte) bool {
+	return len(bytes.TrimLeftFunc(line, unicode.IsSpace)) == 0
+}
+
+// appendLineNumber appends a line number formatted like %6d\t
+func appendLineNumber(b []byte, n int) []byte {
+	for i := n; i < 100000; i *= 10 {
+		b = append(b, ' ')
 	}
-	return progs, nil
+	b = strconv.AppendInt(b, int64(n), 10)
+	return append(b, '\t')
 }
 
 type cat struct {
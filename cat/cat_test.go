@@ -10,6 +10,7 @@ import (
 	"io"
 	"strings"
 	"testing"
+	"testing/fstest"
 
 	. "github.com/gomoni/gonix/cat"
 	"github.com/gomoni/gonix/internal/test"
@@ -87,10 +88,39 @@ func TestCat(t *testing.T) {
 			Input:    string(rune(127)) + "\tthree\nsmall\t\npi\tgs\n",
 			Expected: "^?^Ithree$\nsmall^I$\npi^Igs$\n",
 		},
+		{
+			Name:     "cat -nE",
+			Filter:   New().ShowNumber(All).ShowEnds(true),
+			FromArgs: fromArgs(t, []string{"-nE"}),
+			Input:    "three\r\n\nsmall\npigs",
+			Expected: "     1\tthree$\n     2\t$\n     3\tsmall$\n     4\tpigs$\n",
+		},
+		{
+			Name:     "cat -bs",
+			Filter:   New().ShowNumber(NonBlank).SqueezeBlanks(true),
+			FromArgs: fromArgs(t, []string{"-b", "-s"}),
+			Input:    "three\n\n \t\nsmall\n\n\npigs\n",
+			Expected: "     1\tthree\n\n     2\tsmall\n\n     3\tpigs\n",
+		},
 	}
 	test.RunAll(t, testCases)
 }
 
+func TestFS(t *testing.T) {
+	test.Parallel(t)
+	fsys := fstest.MapFS{
+		"three":      {Data: []byte("three\n")},
+		"small/pigs": {Data: []byte("small\npigs\n")},
+	}
+	var stdout, stderr strings.Builder
+	stdio := unix.NewStdio(strings.NewReader("stdin\n"), &stdout, &stderr)
+	err := New().FS(fsys).Files("three", "-", "/small/pigs", "../escape").Run(context.Background(), stdio)
+	require.Error(t, err)
+	require.EqualValues(t, 1, pipe.FromError(err).Code)
+	require.Equal(t, "three\nstdin\nsmall\npigs\n", stdout.String())
+	require.Contains(t, stderr.String(), "../escape")
+}
+
 // TODO: think about how this can be more generic
 func TestError(t *testing.T) {
 	ctx := context.Background()
@@ -0,0 +1,139 @@
+// Copyright 2023 Michal Vyskocil. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package cksum
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"runtime"
+
+	"github.com/gomoni/gonix/internal"
+	"github.com/gomoni/gonix/internal/dbg"
+)
+
+// Digest is a checksum of one input. Sum is a hex digest or a decimal
+// checksum for legacy crc, sysv and bsd algorithms.
+type Digest struct {
+	Algorithm Algorithm
+	Sum       string
+	Size      int64 // number of bytes read
+}
+
+// FileDigest is a Digest of a named file, Err is set if the file could not
+// be read
+type FileDigest struct {
+	Name string
+	Digest
+	Err error
+}
+
+// Sum computes a checksum of r, NONE means crc like for cksum command
+func Sum(ctx context.Context, algorithm Algorithm, r io.Reader) (Digest, error) {
+	if algorithm == NONE {
+		algorithm = CRC
+	}
+	hash, ok := newHash(algorithm)
+	if !ok {
+		return Digest{}, fmt.Errorf("unsupported algorithm %q", algorithm)
+	}
+
+	d := Digest{Algorithm: algorithm}
+	var err error
+	r = ctxReader{ctx: ctx, r: r}
+	if algorithm.legacy() {
+		var buf [4096]byte
+		d.Size, err = io.CopyBuffer(hash, r, buf[:])
+		d.Sum = string(hash.Sum(nil))
+	} else {
+		d.Sum, d.Size, err = digest(hash, r)
+	}
+	if err != nil {
+		return Digest{}, err
+	}
+	return d, nil
+}
+
+// SumFiles computes checksums of files from fsys using up to threads
+// goroutines, nil fsys means the OS filesystem and 0 threads means GOMAXPROCS.
+// Results are in the order of files, a file which can't be read has Err set
+// and does not stop the others.
+func SumFiles(ctx context.Context, fsys fs.FS, algorithm Algorithm, files []string, threads uint) ([]FileDigest, error) {
+	if algorithm == NONE {
+		algorithm = CRC
+	}
+	if _, ok := newHash(algorithm); !ok {
+		return nil, fmt.Errorf("unsupported algorithm %q", algorithm)
+	}
+	if threads == 0 {
+		threads = uint(runtime.GOMAXPROCS(0))
+	}
+
+	sumOne := func(ctx context.Context, name string) (FileDigest, error) {
+		f, err := internal.Open(fsys, name)
+		if err != nil {
+			return FileDigest{Name: name, Err: err}, nil
+		}
+		defer f.Close()
+		d, err := Sum(ctx, algorithm, f)
+		return FileDigest{Name: name, Digest: d, Err: err}, nil
+	}
+
+	results, err := internal.PMap(ctx, threads, files, sumOne)
+	if err == nil {
+		err = ctx.Err()
+	}
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// Verify checks a list of checksums in a tagged or untagged format like
+// cksum --check does. Algorithms of untagged lines are detected by a size
+// of a digest. Listed files are opened from fsys, nil means the OS filesystem.
+// There is one result for each line in the order of lines.
+func Verify(ctx context.Context, fsys fs.FS, checklist io.Reader) ([]CheckResult, error) {
+	c := New().FS(fsys).Parallel(uint(runtime.GOMAXPROCS(0)))
+	results, err := c.verify(ctx, checklist, dbg.Logger(false, "cksum", io.Discard))
+	if err == nil {
+		err = ctx.Err()
+	}
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// newHash returns a new instance of a hash including legacy algorithms
+func newHash(algorithm Algorithm) (simpleHash, bool) {
+	switch algorithm {
+	case CRC:
+		return &crc{}, true
+	case SYSV:
+		return &sysv{}, true
+	case BSD:
+		return &bsd{}, true
+	}
+	hashFunc, _, ok := algorithm.hashFunc()
+	if !ok {
+		return nil, false
+	}
+	return hashFunc(), true
+}
+
+// ctxReader stops reading once ctx is done
+type ctxReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (r ctxReader) Read(p []byte) (int, error) {
+	if err := r.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return r.r.Read(p)
+}
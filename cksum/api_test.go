@@ -0,0 +1,92 @@
+// Copyright 2023 Michal Vyskocil. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package cksum_test
+
+import (
+	"context"
+	"io/fs"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	. "github.com/gomoni/gonix/cksum"
+	"github.com/gomoni/gonix/internal/test"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSum(t *testing.T) {
+	test.Parallel(t)
+	testCases := []struct {
+		algorithm Algorithm
+		expected  Digest
+	}{
+		{CRC, Digest{Algorithm: CRC, Sum: "3210800587", Size: 17}},
+		{SYSV, Digest{Algorithm: SYSV, Sum: "1538", Size: 17}},
+		{BSD, Digest{Algorithm: BSD, Sum: "64120", Size: 17}},
+		{XXH64, Digest{Algorithm: XXH64, Sum: "875e5ea3f4aa28ef", Size: 17}},
+		{SHA3_256, Digest{Algorithm: SHA3_256, Sum: "43b199a8dc24d94481936ed0455fd5ec3ace15229468fcd98bbedea99f8ab176", Size: 17}},
+	}
+	for _, tt := range testCases {
+		tt := tt
+		t.Run(tt.algorithm.String(), func(t *testing.T) {
+			test.Parallel(t)
+			d, err := Sum(context.Background(), tt.algorithm, strings.NewReader("three\nsmall\npigs\n"))
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, d)
+		})
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := Sum(ctx, MD5, strings.NewReader("three\nsmall\npigs\n"))
+	require.ErrorIs(t, err, context.Canceled)
+}
+
+func TestSumFiles(t *testing.T) {
+	test.Parallel(t)
+	pigs := test.Testdata(t, "three-small-pigs")
+	results, err := SumFiles(context.Background(), nil, XXH64, []string{pigs, "does-not-exist", pigs}, 2)
+	require.NoError(t, err)
+	require.Len(t, results, 3)
+
+	expected := FileDigest{Name: pigs, Digest: Digest{Algorithm: XXH64, Sum: "875e5ea3f4aa28ef", Size: 17}}
+	require.Equal(t, expected, results[0])
+	require.Equal(t, expected, results[2])
+	require.Equal(t, "does-not-exist", results[1].Name)
+	require.ErrorIs(t, results[1].Err, fs.ErrNotExist)
+
+	_, err = SumFiles(context.Background(), nil, Algorithm(42), []string{pigs}, 0)
+	require.Error(t, err)
+
+	fsys := fstest.MapFS{"pigs": {Data: []byte("three\nsmall\npigs\n")}}
+	results, err = SumFiles(context.Background(), fsys, XXH64, []string{"pigs", pigs}, 0)
+	require.NoError(t, err)
+	require.Equal(t, FileDigest{Name: "pigs", Digest: expected.Digest}, results[0])
+	require.ErrorIs(t, results[1].Err, fs.ErrNotExist)
+}
+
+func TestVerify(t *testing.T) {
+	test.Parallel(t)
+	pigs := test.Testdata(t, "three-small-pigs")
+	checklist := "875e5ea3f4aa28ef  " + pigs + "\n" +
+		"XXH64 (" + pigs + ") = 0000000000000000\n" +
+		"garbage\n" +
+		"XXH64 (does-not-exist) = 875e5ea3f4aa28ef\n"
+
+	results, err := Verify(context.Background(), nil, strings.NewReader(checklist))
+	require.NoError(t, err)
+	require.Len(t, results, 4)
+	require.Equal(t, CheckResult{Name: pigs, State: CheckOK}, results[0])
+	require.Equal(t, CheckResult{Name: pigs, State: CheckFailed}, results[1])
+	require.Equal(t, CheckBad, results[2].State)
+	require.Error(t, results[2].Err)
+	require.Equal(t, CheckIO, results[3].State)
+	require.ErrorIs(t, results[3].Err, fs.ErrNotExist)
+
+	fsys := fstest.MapFS{"pigs": {Data: []byte("three\nsmall\npigs\n")}}
+	results, err = Verify(context.Background(), fsys, strings.NewReader("XXH64 (pigs) = 875e5ea3f4aa28ef\n"))
+	require.NoError(t, err)
+	require.Equal(t, []CheckResult{{Name: "pigs", State: CheckOK}}, results)
+}
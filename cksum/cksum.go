@@ -15,20 +15,23 @@ what is implemented
 ✅ untagged format requires explicit --algorithm switch
 ✅ tagged format - happy path
 ✅ --check --algorithm returns no properly formatted lines error for a different hash
-🚀 autodetect hash for untagged format - this include sha512 and blake2b
+🚀 autodetect hash for untagged format - digests of the same size (sha256, sha3-256 and blake3 or
+   sha512, blake2b and sha3-512) are tried one by one
+✅ legacy sysv (sum -s) and bsd (sum -r) checksums
+✅ sha3-256, sha3-512, blake3 and xxh64 (XXH64 tag as xxhsum --tag uses)
 🚀 parallel check or checksums from one file limited by -j/--threds, defaults to GOMAXPROC
- parallel generation of checksums
-🚀 parallel check or checksums from one file limited by -j/--threds, defaults to GOMAXPROC
-
+🚀 parallel generation of checksums
+✅ GNU options:
+    -l/--length  digest length in bits for blake2b, BLAKE2b-N tags are checked too
+    -z/--zero    end each output line with NUL, check lists are NUL separated
+    --strict     exit non-zero for improperly formatted checksum lines
+    -w/--warn    warn about improperly formatted checksum lines
+    --debug      print debugging messages to stderr
+✅ counting of improperly formatted lines, unreadable files and mismatched
+   checksums with GNU summary warnings on stderr
 
 what is not (yet)
-❌ GNU options:
-    -l/--length
-    -z/--zero
-    --strict    - cksum will return 1 by default
-    -w/--warn
-    --debug
-❌ everything around warns and counting various errors - this looks to be completely random at least
+❌ file name escaping
 
 */
 
@@ -36,6 +39,7 @@ package cksum
 
 import (
 	"bufio"
+	"bytes"
 	"context"
 	"crypto/md5"
 	"crypto/sha1"
@@ -45,39 +49,52 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"io/fs"
 	"log"
-	"os"
 	"regexp"
 	"runtime"
+	"strconv"
 	"strings"
 
+	"github.com/cespare/xxhash/v2"
 	"github.com/gomoni/gio/pipe"
 	"github.com/gomoni/gio/unix"
+	"github.com/gomoni/gonix"
 	"github.com/gomoni/gonix/internal"
 	"github.com/gomoni/gonix/internal/dbg"
 	"github.com/spf13/pflag"
 	"golang.org/x/crypto/blake2b"
+	"golang.org/x/crypto/sha3"
+	"lukechampine.com/blake3"
 )
 
 type Algorithm int
 
 const (
-	NONE Algorithm = 0
-	//sysv algorithm = 1
-	//bsd  algorithm = 2
-	CRC     Algorithm = 3
-	MD5     Algorithm = 4
-	SHA1    Algorithm = 5
-	SHA224  Algorithm = 6
-	SHA256  Algorithm = 7
-	SHA384  Algorithm = 8
-	SHA512  Algorithm = 9
-	BLAKE2B Algorithm = 10
+	NONE     Algorithm = 0
+	SYSV     Algorithm = 1
+	BSD      Algorithm = 2
+	CRC      Algorithm = 3
+	MD5      Algorithm = 4
+	SHA1     Algorithm = 5
+	SHA224   Algorithm = 6
+	SHA256   Algorithm = 7
+	SHA384   Algorithm = 8
+	SHA512   Algorithm = 9
+	BLAKE2B  Algorithm = 10
+	SHA3_256 Algorithm = 11
+	SHA3_512 Algorithm = 12
+	BLAKE3   Algorithm = 13
+	XXH64    Algorithm = 14
 )
 
 // https://pkg.go.dev/github.com/spf13/pflag#Value
 func (a Algorithm) String() string {
 	switch a {
+	case SYSV:
+		return `sysv`
+	case BSD:
+		return `bsd`
 	case CRC:
 		return `crc`
 	case MD5:
@@ -89,11 +106,19 @@ func (a Algorithm) String() string {
 	case SHA256:
 		return `sha256`
 	case SHA384:
-		return `sha1384`
+		return `sha384`
 	case SHA512:
 		return `sha512`
 	case BLAKE2B:
 		return `blake2b`
+	case SHA3_256:
+		return `sha3-256`
+	case SHA3_512:
+		return `sha3-512`
+	case BLAKE3:
+		return `blake3`
+	case XXH64:
+		return `xxh64`
 	default:
 		return `!unknown`
 	}
@@ -105,6 +130,10 @@ func (a Algorithm) Type() string {
 
 func (a *Algorithm) Set(value string) error {
 	switch value {
+	case `sysv`:
+		*a = SYSV
+	case `bsd`:
+		*a = BSD
 	case `crc`:
 		*a = CRC
 	case `md5`:
@@ -121,6 +150,14 @@ func (a *Algorithm) Set(value string) error {
 		*a = SHA512
 	case `blake2b`:
 		*a = BLAKE2B
+	case `sha3-256`:
+		*a = SHA3_256
+	case `sha3-512`:
+		*a = SHA3_512
+	case `blake3`:
+		*a = BLAKE3
+	case `xxh64`:
+		*a = XXH64
 	default:
 		return fmt.Errorf("invalid argument %q for --algorithm", value)
 	}
@@ -136,6 +173,12 @@ type CKSum struct {
 	ignoreMissing bool
 	quiet         bool
 	status        bool
+	strict        bool
+	warn          bool
+	zero          bool
+	length        int
+	files0From    string
+	fsys          fs.FS
 	files         []string
 }
 
@@ -143,19 +186,39 @@ func New() CKSum {
 	return CKSum{}
 }
 
+func init() {
+	gonix.Register(gonix.Cmd{
+		Name:  "cksum",
+		Usage: "compute and verify file checksums",
+		New:   gonix.FromArgs(New().FromArgs),
+	})
+}
+
 // Files are input files, where - denotes stdin
 func (c CKSum) Files(f ...string) CKSum {
 	c.files = append(c.files, f...)
 	return c
 }
 
+// Files0From reads NUL terminated names of files from a file, - means stdin
+func (c CKSum) Files0From(name string) CKSum {
+	c.files0From = name
+	return c
+}
+
+// FS sets a filesystem used to open files, nil means the OS filesystem
+func (c CKSum) FS(fsys fs.FS) CKSum {
+	c.fsys = fsys
+	return c
+}
+
 func (c CKSum) Algorithm(algorithm Algorithm) CKSum {
 	c.algorithm = algorithm
 	return c
 }
 
 func (c CKSum) Check(check bool) CKSum {
-	c.check = true
+	c.check = check
 	return c
 }
 
@@ -183,6 +246,32 @@ func (c CKSum) Status(status bool) CKSum {
 	c.status = status
 	return c
 }
+
+// Strict exits with non-zero code for improperly formatted checksum lines
+func (c CKSum) Strict(strict bool) CKSum {
+	c.strict = strict
+	return c
+}
+
+// Warn warns about improperly formatted checksum lines
+func (c CKSum) Warn(warn bool) CKSum {
+	c.warn = warn
+	return c
+}
+
+// Zero ends each output line with NUL instead of newline and reads NUL
+// separated check lists
+func (c CKSum) Zero(zero bool) CKSum {
+	c.zero = zero
+	return c
+}
+
+// Length is a digest length in bits for blake2b, 0 means the default 512
+func (c CKSum) Length(bits int) CKSum {
+	c.length = bits
+	return c
+}
+
 func (c CKSum) SetDebug(debug bool) CKSum {
 	c.debug = debug
 	return c
@@ -198,6 +287,12 @@ func (c CKSum) FromArgs(argv []string) (CKSum, error) {
 	ignoreMissing := flag.Bool("ignore-missing", false, "ignore missing files")
 	quiet := flag.Bool("quiet", false, "do not print OK for every verified file")
 	status := flag.Bool("status", false, "report status code only")
+	flag.IntVarP(&c.length, "length", "l", 0, "digest length in bits; must not exceed the max for the blake2b algorithm and must be a multiple of 8")
+	flag.BoolVarP(&c.zero, "zero", "z", false, "end each output line with NUL, not newline")
+	flag.BoolVar(&c.strict, "strict", false, "exit non-zero for improperly formatted checksum lines")
+	flag.BoolVarP(&c.warn, "warn", "w", false, "warn about improperly formatted checksum lines")
+	flag.BoolVar(&c.debug, "debug", c.debug, "print debugging messages")
+	flag.StringVar(&c.files0From, "files0-from", "", "read input from the files specified by NUL-terminated names in file F")
 
 	// GNU is not consistent with parallel naming (make uses -j/--jobs, xargs -P and so
 	// used -j/--threads as ripgrep does
@@ -209,11 +304,14 @@ func (c CKSum) FromArgs(argv []string) (CKSum, error) {
 	}
 
 	if len(flag.Args()) > 0 {
+		if c.files0From != "" {
+			return CKSum{}, pipe.NewErrorf(1, "cksum: %w", internal.ErrFiles0Operands(flag.Args()[0]))
+		}
 		c.files = flag.Args()
 	}
 
 	c.algorithm = algorithm
-	if c.algorithm == CRC || *untagged {
+	if c.algorithm.legacy() || *untagged {
 		c.untagged = true
 	}
 	c.check = *check
@@ -221,125 +319,268 @@ func (c CKSum) FromArgs(argv []string) (CKSum, error) {
 	c.quiet = *quiet
 	c.status = *status
 	c.threads = threads
+	if err := c.validate(); err != nil {
+		return CKSum{}, pipe.NewErrorf(1, "cksum: %w", err)
+	}
 	return c, nil
 }
 
+func (c CKSum) validate() error {
+	if c.length == 0 {
+		return nil
+	}
+	if c.algorithm != BLAKE2B {
+		return fmt.Errorf("--length is only supported with --algorithm=blake2b")
+	}
+	if c.length < 0 || c.length > 512 {
+		return fmt.Errorf("invalid length: %d, maximum digest length for BLAKE2b is 512 bits", c.length)
+	}
+	if c.length%8 != 0 {
+		return fmt.Errorf("invalid length: %d, length is not a multiple of 8", c.length)
+	}
+	return nil
+}
+
 func (c CKSum) Run(ctx context.Context, stdio unix.StandardIO) error {
 	debug := dbg.Logger(c.debug, "cksum", stdio.Stderr())
 	if c.threads == 0 {
 		c.threads = uint(runtime.GOMAXPROCS(0))
 	}
 	debug.Printf("running with --threads %d", c.threads)
+	if err := c.validate(); err != nil {
+		return pipe.NewErrorf(1, "cksum: %w", err)
+	}
 
+	var err error
 	if c.check {
 		debug.Printf("about to call c.checkSum")
-		return c.checkSum(ctx, stdio, debug)
+		err = c.checkSum(ctx, stdio, debug)
+	} else {
+		err = c.makeSum(ctx, stdio, debug)
 	}
-	return c.makeSum(ctx, stdio, debug)
+	return err
 }
 
 func (c CKSum) makeSum(ctx context.Context, stdio unix.StandardIO, _ *log.Logger) error {
 	if c.algorithm == NONE {
 		c.algorithm = CRC
 	}
+	delim := c.delim()
 
 	var makeSum func(context.Context, unix.StandardIO, int, string) error
 
 	switch c.algorithm {
 	case CRC:
 		makeSum = func(ctx context.Context, stdio unix.StandardIO, _ int, name string) error {
-			cksum, size, err := docrc(ctx, func() simpleHash { return &crc{} }, stdio.Stdin())
+			d, err := Sum(ctx, CRC, stdio.Stdin())
 			if err != nil {
 				return err
 			}
-			fmt.Fprintf(stdio.Stdout(), "%s %d %s\n", cksum, size, name)
+			out := fmt.Sprintf("%s %d", d.Sum, d.Size)
+			if name != "" {
+				out += " " + name
+			}
+			fmt.Fprintf(stdio.Stdout(), "%s%c", out, delim)
 			return nil
 		}
+	case SYSV, BSD:
+		makeSum = newSumFunc(c.algorithm, delim)
 	default:
-		hash, name, ok := c.algorithm.hashFunc()
+		hash, name, _, ok := c.hashFunc(c.algorithm, c.length)
 		if !ok {
 			return fmt.Errorf("invalid argument %q for --algorithm", c.algorithm)
 		}
-		makeSum = newDigestFunc(hash, name, c.untagged)
+		makeSum = newDigestFunc(hash, name, c.untagged, delim)
 	}
 
 	runFiles := internal.NewRunFiles(
 		c.files,
 		stdio,
 		makeSum,
-	)
+	).FS(c.fsys).Files0From(c.files0From)
 	return runFiles.DoThreads(ctx, c.threads)
 }
 
-func (c CKSum) checkSum(ctx context.Context, stdio unix.StandardIO, debug *log.Logger) error {
-	if c.check && c.algorithm == CRC {
-		return fmt.Errorf("--check is not supported with algorithm=%s", c.algorithm)
+func (c CKSum) delim() byte {
+	if c.zero {
+		return 0
 	}
+	return '\n'
+}
 
-	ckSumOne := func(_ context.Context, line string) (checkResult, error) {
-		res, err := c.checkLine(line, debug)
-		if !c.ignoreMissing && err != nil {
-			return res, err
-		}
-		return res, nil
+// hashFunc returns a hash, a tag and a size of a hex digest, bits are used
+// by blake2b only and 0 means the default length
+func (c CKSum) hashFunc(algorithm Algorithm, bits int) (func() simpleHash, string, int, bool) {
+	if algorithm == BLAKE2B && bits != 0 && bits != 512 {
+		return blake2bFunc(bits), fmt.Sprintf("BLAKE2b-%d", bits), bits / 4, true
+	}
+	hash, name, ok := algorithm.hashFunc()
+	return hash, name, algorithm.Size(), ok
+}
+
+// checkCounts counts results of one check list
+type checkCounts struct {
+	ok         int
+	improper   int
+	unreadable int
+	mismatched int
+}
+
+func (c CKSum) checkSum(ctx context.Context, stdio unix.StandardIO, debug *log.Logger) error {
+	if c.check && c.algorithm.legacy() {
+		return fmt.Errorf("--check is not supported with algorithm=%s", c.algorithm)
 	}
 
 	ckSum := func(ctx context.Context, stdio unix.StandardIO, _ int, name string) error {
-		r := bufio.NewScanner(stdio.Stdin())
-		input := make([]string, 0, 16)
-		for r.Scan() {
-			if r.Err() != nil {
-				return pipe.Error{Code: 1, Err: r.Err()}
-			}
-			input = append(input, r.Text())
+		if name == "" || name == "-" {
+			name = "standard input"
 		}
-
-		results, err := internal.PMap(ctx, c.threads, input, ckSumOne)
-		var ret int
+		results, err := c.verify(ctx, stdio.Stdin(), debug)
 		if err != nil {
-			ret = 1
+			return internal.NewError(1, fmt.Errorf("cksum: %s: %w", name, err))
 		}
 
-		for _, result := range results {
-			switch result.state {
-			case stNONE:
+		var counts checkCounts
+		for idx, result := range results {
+			switch result.State {
+			case CheckNone:
 				continue
-			case stOK:
+			case CheckBad:
+				counts.improper++
+				if c.warn {
+					fmt.Fprintf(stdio.Stderr(), "cksum: %s: %d: improperly formatted %schecksum line\n", name, idx+1, c.tagName())
+				}
+			case CheckOK:
+				counts.ok++
 				if !c.quiet && !c.status {
-					fmt.Fprintf(stdio.Stdout(), "%s: OK\n", result.name)
+					fmt.Fprintf(stdio.Stdout(), "%s: OK\n", result.Name)
 				}
-			case stFAILED:
+			case CheckFailed:
+				counts.mismatched++
 				if !c.status {
-					fmt.Fprintf(stdio.Stdout(), "%s: FAILED\n", result.name)
-					ret = 1
+					fmt.Fprintf(stdio.Stdout(), "%s: FAILED\n", result.Name)
 				}
-			case stIO:
-				if c.ignoreMissing {
+			case CheckIO:
+				if c.ignoreMissing && errors.Is(result.Err, fs.ErrNotExist) {
 					continue
 				}
+				counts.unreadable++
 				if !c.status {
-					fmt.Fprintf(stdio.Stdout(), "%s: FAILED open or read error\n", result.name)
-					ret = 1
+					fmt.Fprintf(stdio.Stderr(), "cksum: %s\n", result.Err)
+					fmt.Fprintf(stdio.Stdout(), "%s: FAILED open or read\n", result.Name)
 				}
 			default:
 				panic("unknown result state")
 			}
 		}
-
-		if ret != 0 {
-			return pipe.NewError(ret, err)
-		}
-		return nil
+		return c.summary(stdio.Stderr(), name, counts)
 	}
 
 	runFiles := internal.NewRunFiles(
 		c.files,
 		stdio,
 		ckSum,
-	)
+	).FS(c.fsys).Files0From(c.files0From)
 	return runFiles.Do(ctx)
 }
 
+// verify checks all lines of a check list concurrently, there is one result
+// for each line in the order of lines
+func (c CKSum) verify(ctx context.Context, list io.Reader, debug *log.Logger) ([]CheckResult, error) {
+	ckSumOne := func(_ context.Context, line string) (CheckResult, error) {
+		res, err := c.checkLine(line, debug)
+		if err != nil {
+			debug.Printf("checkLine: %s", err)
+			return CheckResult{State: CheckBad, Err: err}, nil
+		}
+		return res, nil
+	}
+
+	r := bufio.NewScanner(list)
+	if c.zero {
+		r.Split(scanZero)
+	}
+	input := make([]string, 0, 16)
+	for r.Scan() {
+		input = append(input, r.Text())
+	}
+	if r.Err() != nil {
+		return nil, r.Err()
+	}
+	return internal.PMap(ctx, c.threads, input, ckSumOne)
+}
+
+// summary prints GNU warnings about check failures and returns an error
+// if the check failed
+func (c CKSum) summary(stderr io.Writer, name string, counts checkCounts) error {
+	if counts.ok+counts.mismatched+counts.unreadable == 0 && !c.ignoreMissing {
+		err := fmt.Errorf("cksum: %s: no properly formatted %schecksum lines found", name, c.tagName())
+		fmt.Fprintf(stderr, "%s\n", err)
+		return pipe.NewError(1, internal.Reported(err))
+	}
+
+	errs := make([]error, 0, 4)
+	if counts.improper > 0 {
+		errs = append(errs, fmt.Errorf("cksum: WARNING: %s improperly formatted", plural(counts.improper, "line is", "lines are")))
+	}
+	if counts.unreadable > 0 {
+		errs = append(errs, fmt.Errorf("cksum: WARNING: %s could not be read", plural(counts.unreadable, "listed file", "listed files")))
+	}
+	if counts.mismatched > 0 {
+		errs = append(errs, fmt.Errorf("cksum: WARNING: %s did NOT match", plural(counts.mismatched, "computed checksum", "computed checksums")))
+	}
+	if c.ignoreMissing && counts.ok+counts.mismatched == 0 {
+		errs = append(errs, fmt.Errorf("cksum: %s: no file was verified", name))
+	}
+	if !c.status {
+		for _, err := range errs {
+			fmt.Fprintf(stderr, "%s\n", err)
+		}
+	}
+
+	failed := counts.unreadable > 0 || counts.mismatched > 0 || (c.strict && counts.improper > 0)
+	failed = failed || (c.ignoreMissing && counts.ok+counts.mismatched == 0)
+	if failed {
+		// printed above or silenced by --status
+		return pipe.NewError(1, internal.Reported(errors.Join(errs...)))
+	}
+	return nil
+}
+
+// tagName returns a name of an algorithm used in GNU messages like
+// "improperly formatted MD5 checksum line"
+func (c CKSum) tagName() string {
+	if c.algorithm == NONE {
+		return ""
+	}
+	_, name, _, ok := c.hashFunc(c.algorithm, c.length)
+	if !ok {
+		return ""
+	}
+	return name + " "
+}
+
+func plural(n int, one, more string) string {
+	if n == 1 {
+		return "1 " + one
+	}
+	return fmt.Sprintf("%d %s", n, more)
+}
+
+// scanZero is a bufio.SplitFunc for NUL separated records
+func scanZero(data []byte, atEOF bool) (int, []byte, error) {
+	if atEOF && len(data) == 0 {
+		return 0, nil, nil
+	}
+	if i := bytes.IndexByte(data, 0); i >= 0 {
+		return i + 1, data[:i], nil
+	}
+	if atEOF {
+		return len(data), data, nil
+	}
+	return 0, nil, nil
+}
+
 // copy from https://git.suckless.org/sbase/file/cksum.c.html#l11
 var crctab = [256]uint32{0x00000000,
 	0x04c11db7, 0x09823b6e, 0x0d4326d9, 0x130476dc, 0x17c56b6b,
@@ -395,16 +636,6 @@ var crctab = [256]uint32{0x00000000,
 	0xa2f33668, 0xbcb4666d, 0xb8757bda, 0xb5365d03, 0xb1f740b4,
 }
 
-func docrc(_ context.Context, hashFunc func() simpleHash, stdin io.Reader) (string, int64, error) {
-	var buf [4096]byte
-	hash := hashFunc()
-	n, err := io.CopyBuffer(hash, stdin, buf[:])
-	if err != nil {
-		return "", -1, err
-	}
-	return string(hash.Sum(nil)), n, nil
-}
-
 type crc struct {
 	ck   uint32
 	size int
@@ -430,6 +661,11 @@ func (c crc) Sum(_ []byte) []byte {
 	return []byte(fmt.Sprintf("%d", ^ck))
 }
 
+// legacy algorithms print a checksum and a size and do not support --check
+func (a Algorithm) legacy() bool {
+	return a == SYSV || a == BSD || a == CRC
+}
+
 func (a Algorithm) Size() int {
 	switch a {
 	case MD5:
@@ -446,6 +682,14 @@ func (a Algorithm) Size() int {
 		return 128
 	case BLAKE2B:
 		return 128
+	case SHA3_256:
+		return 64
+	case SHA3_512:
+		return 128
+	case BLAKE3:
+		return 64
+	case XXH64:
+		return 16
 	default:
 		return -1
 	}
@@ -462,20 +706,35 @@ func (a Algorithm) hashFunc() (func() simpleHash, string, bool) {
 	case SHA256:
 		return func() simpleHash { return sha256.New() }, "SHA256", true
 	case SHA384:
-		return func() simpleHash { return sha512.New384() }, "SHA338", true
+		return func() simpleHash { return sha512.New384() }, "SHA384", true
 	case SHA512:
 		return func() simpleHash { return sha512.New() }, "SHA512", true
 	case BLAKE2B:
-		hash, err := blake2b.New(64, nil)
-		if err != nil {
-			return nil, "", false
-		}
-		return func() simpleHash { return hash }, "BLAKE2b", true
+		return blake2bFunc(512), "BLAKE2b", true
+	case SHA3_256:
+		return func() simpleHash { return sha3.New256() }, "SHA3-256", true
+	case SHA3_512:
+		return func() simpleHash { return sha3.New512() }, "SHA3-512", true
+	case BLAKE3:
+		return func() simpleHash { return blake3.New(32, nil) }, "BLAKE3", true
+	case XXH64:
+		return func() simpleHash { return xxhash.New() }, "XXH64", true
 	default:
 		return nil, "", false
 	}
 }
 
+// blake2bFunc returns blake2b with a digest truncated to bits
+func blake2bFunc(bits int) func() simpleHash {
+	return func() simpleHash {
+		hash, err := blake2b.New(bits/8, nil)
+		if err != nil {
+			panic(err)
+		}
+		return hash
+	}
+}
+
 func parseAlgorithm(s string) (Algorithm, error) {
 	var a Algorithm
 	switch strings.ToUpper(s) {
@@ -493,6 +752,14 @@ func parseAlgorithm(s string) (Algorithm, error) {
 		a = SHA512
 	case "BLAKE2B":
 		a = BLAKE2B
+	case "SHA3-256":
+		a = SHA3_256
+	case "SHA3-512":
+		a = SHA3_512
+	case "BLAKE3":
+		a = BLAKE3
+	case "XXH64":
+		a = XXH64
 	default:
 		return NONE, fmt.Errorf("invalid argument %q for --algorithm", s)
 	}
@@ -507,21 +774,101 @@ type simpleHash interface {
 }
 
 // digest implements a digest for hash.Hash compatible stuff
-// md5, sha256
-func digest(hash simpleHash, stdin io.Reader) (string, error) {
+// md5, sha256 and returns a number of bytes read
+func digest(hash simpleHash, stdin io.Reader) (string, int64, error) {
 	var buf [4096]byte
-	_, err := io.CopyBuffer(hash, stdin, buf[:])
+	n, err := io.CopyBuffer(hash, stdin, buf[:])
 	if err != nil {
-		return "", err
+		return "", n, err
+	}
+
+	return hex.EncodeToString(hash.Sum(nil)), n, nil
+}
+
+// newSumFunc prints legacy sysv (sum -s) or bsd (sum -r) checksums and
+// a number of blocks
+func newSumFunc(algorithm Algorithm, delim byte) func(ctx context.Context, stdio unix.StandardIO, _ int, name string) error {
+	return func(ctx context.Context, stdio unix.StandardIO, _ int, name string) error {
+		var hash sum
+		if algorithm == BSD {
+			hash = &bsd{}
+		} else {
+			hash = &sysv{}
+		}
+		var buf [4096]byte
+		_, err := io.CopyBuffer(hash, stdio.Stdin(), buf[:])
+		if err != nil {
+			return err
+		}
+		out := hash.String()
+		if name != "" {
+			out += " " + name
+		}
+		fmt.Fprintf(stdio.Stdout(), "%s%c", out, delim)
+		return nil
+	}
+}
+
+type sum interface {
+	simpleHash
+	fmt.Stringer
+}
+
+// sysv is System V checksum, see sum -s
+type sysv struct {
+	s    uint32
+	size int64
+}
+
+func (h *sysv) Write(buf []byte) (int, error) {
+	for _, b := range buf {
+		h.s += uint32(b)
 	}
+	h.size += int64(len(buf))
+	return len(buf), nil
+}
+
+// Sum returns a decimal checksum, so sysv is a simpleHash too
+func (h sysv) Sum(_ []byte) []byte {
+	r := (h.s & 0xffff) + (h.s >> 16)
+	ck := (r & 0xffff) + (r >> 16)
+	return []byte(strconv.FormatUint(uint64(ck), 10))
+}
 
-	return hex.EncodeToString(hash.Sum(nil)), nil
+func (h sysv) String() string {
+	return fmt.Sprintf("%s %d", h.Sum(nil), (h.size+511)/512)
 }
 
-func newDigestFunc(hashFunc func() simpleHash, hashName string, untagged bool) func(ctx context.Context, stdio unix.StandardIO, _ int, name string) error {
+// bsd is BSD checksum, see sum -r
+type bsd struct {
+	ck   uint32
+	size int64
+}
+
+func (h *bsd) Write(buf []byte) (int, error) {
+	ck := h.ck
+	for _, b := range buf {
+		ck = (ck >> 1) + ((ck & 1) << 15)
+		ck = (ck + uint32(b)) & 0xffff
+	}
+	h.ck = ck
+	h.size += int64(len(buf))
+	return len(buf), nil
+}
+
+// Sum returns a decimal checksum, so bsd is a simpleHash too
+func (h bsd) Sum(_ []byte) []byte {
+	return []byte(fmt.Sprintf("%05d", h.ck))
+}
+
+func (h bsd) String() string {
+	return fmt.Sprintf("%s %5d", h.Sum(nil), (h.size+1023)/1024)
+}
+
+func newDigestFunc(hashFunc func() simpleHash, hashName string, untagged bool, delim byte) func(ctx context.Context, stdio unix.StandardIO, _ int, name string) error {
 	return func(ctx context.Context, stdio unix.StandardIO, _ int, name string) error {
 		hash := hashFunc()
-		cksum, err := digest(hash, stdio.Stdin())
+		cksum, _, err := digest(hash, stdio.Stdin())
 		if err != nil {
 			return err
 		}
@@ -529,9 +876,9 @@ func newDigestFunc(hashFunc func() simpleHash, hashName string, untagged bool) f
 			name = "-"
 		}
 		if untagged {
-			fmt.Fprintf(stdio.Stdout(), "%s  %s\n", cksum, name)
+			fmt.Fprintf(stdio.Stdout(), "%s  %s%c", cksum, name, delim)
 		} else {
-			fmt.Fprintf(stdio.Stdout(), "%s (%s) = %s\n", hashName, name, cksum)
+			fmt.Fprintf(stdio.Stdout(), "%s (%s) = %s%c", hashName, name, cksum, delim)
 		}
 		return nil
 	}
@@ -550,30 +897,52 @@ func (e BadLineFormatError) Error() string {
 	return fmt.Sprintf("BadLineFormatError(%q)", string(e))
 }
 
-type checkState int
+// CheckState is a result of checking one line of a check list
+type CheckState int
 
 const (
-	stNONE   checkState = 0
-	stOK     checkState = 1
-	stFAILED checkState = 2
-	stIO     checkState = 3
+	CheckNone   CheckState = 0
+	CheckOK     CheckState = 1
+	CheckFailed CheckState = 2 // computed checksum did NOT match
+	CheckIO     CheckState = 3 // listed file could not be read
+	CheckBad    CheckState = 4 // improperly formatted line
 )
 
-type checkResult struct {
-	name  string
-	state checkState
+func (s CheckState) String() string {
+	switch s {
+	case CheckNone:
+		return "NONE"
+	case CheckOK:
+		return "OK"
+	case CheckFailed:
+		return "FAILED"
+	case CheckIO:
+		return "FAILED open or read"
+	case CheckBad:
+		return "BAD"
+	default:
+		return "!unknown"
+	}
 }
 
-func stateOK(name string) checkResult {
-	return checkResult{name: name, state: stOK}
+// CheckResult is a result of checking one line of a check list, Err is set
+// for CheckIO and CheckBad states
+type CheckResult struct {
+	Name  string
+	State CheckState
+	Err   error
 }
 
-func stateFAILED(name string) checkResult {
-	return checkResult{name: name, state: stFAILED}
+func stateOK(name string) CheckResult {
+	return CheckResult{Name: name, State: CheckOK}
 }
 
-func stateIO(name string) checkResult {
-	return checkResult{name: name, state: stIO}
+func stateFAILED(name string) CheckResult {
+	return CheckResult{Name: name, State: CheckFailed}
+}
+
+func stateIO(name string, err error) CheckResult {
+	return CheckResult{Name: name, State: CheckIO, Err: err}
 }
 
 // parse untagged and tagged formats
@@ -585,8 +954,8 @@ func stateIO(name string) checkResult {
 // 2. BadLineFormatError for tagged format and a different hash
 // 3. BadLineFormatError for wrong size of a hash
 // 4. MismatchError for mismatched hash
-func (c CKSum) checkLine(line string, debug *log.Logger) (checkResult, error) {
-	var zero checkResult
+func (c CKSum) checkLine(line string, debug *log.Logger) (CheckResult, error) {
+	var zero CheckResult
 
 	if len(line) == 0 {
 		return zero, BadLineFormatError("empty")
@@ -604,6 +973,8 @@ func (c CKSum) checkLine(line string, debug *log.Logger) (checkResult, error) {
 				goto cantDetect
 			}
 			switch len(expected) {
+			case XXH64.Size():
+				c.algorithm = XXH64
 			case MD5.Size():
 				c.algorithm = MD5
 			case SHA1.Size():
@@ -611,13 +982,15 @@ func (c CKSum) checkLine(line string, debug *log.Logger) (checkResult, error) {
 			case SHA224.Size():
 				c.algorithm = SHA224
 			case SHA256.Size():
-				c.algorithm = SHA256
+				c.algorithm = SHA256 // or sha3-256 or blake3
+				algorithms = []Algorithm{SHA256, SHA3_256, BLAKE3}
+				debug.Printf("checLine: detected 256 bits, trying SHA256, SHA3-256 or BLAKE3")
 			case SHA384.Size():
 				c.algorithm = SHA384
 			case SHA512.Size():
-				c.algorithm = SHA512 // or blake2b
-				algorithms = []Algorithm{SHA512, BLAKE2B}
-				debug.Printf("checLine: detected 512 bytes, trying SHA512 or BLAKE2b")
+				c.algorithm = SHA512 // or blake2b or sha3-512
+				algorithms = []Algorithm{SHA512, BLAKE2B, SHA3_512}
+				debug.Printf("checLine: detected 512 bits, trying SHA512, BLAKE2b or SHA3-512")
 			default:
 				goto cantDetect
 			}
@@ -628,39 +1001,37 @@ func (c CKSum) checkLine(line string, debug *log.Logger) (checkResult, error) {
 		}
 
 	detected:
-		checkSum := func(algorithm Algorithm) (checkResult, error) {
-			// untagged format is hash<space><space>name: check there are two spaces there
-			if line[algorithm.Size()] != ' ' || line[algorithm.Size()+1] != ' ' {
-				return zero, BadLineFormatError("--untagged must have two spaces between sum and file name")
-			}
-
-			hash, _, ok := algorithm.hashFunc()
+		checkSum := func(algorithm Algorithm) (CheckResult, error) {
+			hash, _, size, ok := c.hashFunc(algorithm, c.length)
 			if !ok {
 				return zero, fmt.Errorf("unsupported --algorithm %q", c.algorithm)
 			}
 
-			name := line[algorithm.Size()+2:]
-			err := checkSum(name, hash, line[:algorithm.Size()])
+			// untagged format is hash<space><space>name: check there are two spaces there
+			if len(line) <= size+2 || line[size] != ' ' || line[size+1] != ' ' {
+				return zero, BadLineFormatError("--untagged must have two spaces between sum and file name")
+			}
+
+			name := line[size+2:]
+			err := checkSum(c.fsys, name, hash, line[:size])
 			if err == nil {
 				return stateOK(name), nil
 			}
 			if errors.Is(err, errMismatch) {
 				return stateFAILED(name), nil
 			}
-			return stateIO(name), nil
+			return stateIO(name, err), nil
 		}
 		if len(algorithms) == 0 {
 			return checkSum(c.algorithm)
 		}
-		res, err := checkSum(algorithms[0])
-		if err == nil {
-			return res, nil
-		}
-		var blferr BadLineFormatError
-		if errors.As(err, &blferr) {
-			res, err = checkSum(algorithms[1])
-			if err == nil {
-				return res, nil
+		// the same digest size: the first matching algorithm wins
+		var res CheckResult
+		var err error
+		for _, algorithm := range algorithms {
+			res, err = checkSum(algorithm)
+			if err != nil || res.State != CheckFailed {
+				return res, err
 			}
 		}
 		return res, err
@@ -672,6 +1043,15 @@ func (c CKSum) checkLine(line string, debug *log.Logger) (checkResult, error) {
 	if !ok {
 		return zero, BadLineFormatError("no space after digest tag")
 	}
+	// BLAKE2b-N tag contains the digest length in bits
+	var bits int
+	if base, n, ok := strings.Cut(tag, "-"); ok && strings.EqualFold(base, BLAKE2B.String()) {
+		b, err := strconv.Atoi(n)
+		if err != nil || b <= 0 || b > 512 || b%8 != 0 {
+			return zero, badLineFormatErrorf("invalid digest length in tag %q", tag)
+		}
+		tag, bits = base, b
+	}
 	algorithm, err := parseAlgorithm(tag)
 	if err != nil {
 		return zero, badLineFormatErrorf("unsupported --algorithm tag %q", tag)
@@ -681,13 +1061,17 @@ func (c CKSum) checkLine(line string, debug *log.Logger) (checkResult, error) {
 		return zero, badLineFormatErrorf("line tag %q does not match --algorithm %q", tag, c.algorithm.String())
 	}
 
-	if len(rest) <= algorithm.Size() {
-		return zero, badLineFormatErrorf("wrong size of hash: expected %d, got %d", algorithm.Size(), len(rest))
+	hash, _, size, ok := c.hashFunc(algorithm, bits)
+	if !ok {
+		return zero, fmt.Errorf("unsupported --algorithm %q", c.algorithm)
+	}
+	if len(rest) <= size {
+		return zero, badLineFormatErrorf("wrong size of hash: expected %d, got %d", size, len(rest))
 	}
-	expected := rest[len(rest)-algorithm.Size():]
+	expected := rest[len(rest)-size:]
 
 	// rest is now (name) =
-	rest = rest[:len(rest)-algorithm.Size()]
+	rest = rest[:len(rest)-size]
 	// so check and remove all remaining bytes
 	lr := len(rest)
 	if lr <= 5 || rest[0] != '(' || rest[lr-4:] != ") = " {
@@ -695,11 +1079,7 @@ func (c CKSum) checkLine(line string, debug *log.Logger) (checkResult, error) {
 	}
 	name := rest[1 : lr-4]
 
-	hash, _, ok := algorithm.hashFunc()
-	if !ok {
-		return zero, fmt.Errorf("unsupported --algorithm %q", c.algorithm)
-	}
-	err = checkSum(name, hash, expected)
+	err = checkSum(c.fsys, name, hash, expected)
 	if err == nil {
 		return stateOK(name), nil
 	}
@@ -707,20 +1087,20 @@ func (c CKSum) checkLine(line string, debug *log.Logger) (checkResult, error) {
 		return stateFAILED(name), nil
 	}
 	if err != nil {
-		return stateIO(name), err
+		return stateIO(name, err), nil
 	}
 	panic("checkLine: tagged: should never go there")
 }
 
 var errMismatch = errors.New("checksum mismatch") // never returned upper
 
-func checkSum(name string, hashFunc func() simpleHash, expected string) error {
-	f, err := os.Open(name)
+func checkSum(fsys fs.FS, name string, hashFunc func() simpleHash, expected string) error {
+	f, err := internal.Open(fsys, name)
 	if err != nil {
 		return err
 	}
 	defer f.Close()
-	checkSum, err := digest(hashFunc(), f)
+	checkSum, _, err := digest(hashFunc(), f)
 	if err != nil {
 		return err
 	}
@@ -8,6 +8,7 @@ import (
 	"path/filepath"
 	"strings"
 	"testing"
+	"testing/fstest"
 
 	"github.com/gomoni/gio/unix"
 	. "github.com/gomoni/gonix/cksum"
@@ -22,14 +23,14 @@ func TestCKSum(t *testing.T) {
 			Name:     "default",
 			Filter:   fromArgs(t, nil),
 			Input:    "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12\n",
-			Expected: "1340348198 27 \n",
+			Expected: "1340348198 27\n",
 		},
 		{
 			Name:     "default untagged",
 			Filter:   New().Untagged(false),
 			FromArgs: fromArgs(t, nil),
 			Input:    "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12\n",
-			Expected: "1340348198 27 \n",
+			Expected: "1340348198 27\n",
 		},
 		{
 			Name:     "md5",
@@ -45,9 +46,81 @@ func TestCKSum(t *testing.T) {
 			Input:    "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12\n",
 			Expected: "f4699b80440c0403b31fce987f9cd8af  -\n",
 		},
+		{
+			Name:     "md5 --zero",
+			Filter:   New().Algorithm(MD5).Zero(true),
+			FromArgs: fromArgs(t, []string{"--algorithm", "md5", "-z"}),
+			Input:    "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12\n",
+			Expected: "MD5 (-) = f4699b80440c0403b31fce987f9cd8af\x00",
+		},
+		{
+			Name:     "sha384",
+			Filter:   New().Algorithm(SHA384),
+			FromArgs: fromArgs(t, []string{"--algorithm", "sha384"}),
+			Input:    "three\nsmall\npigs\n",
+			Expected: "SHA384 (-) = 1cfa81af3ce060a294f61fafc33cf6b82c6d6912448502b52c17faaaae89068b0d756d3b26179950d5a74517fe389330\n",
+		},
+		{
+			Name:     "sysv",
+			Filter:   New().Algorithm(SYSV).Untagged(true),
+			FromArgs: fromArgs(t, []string{"--algorithm", "sysv"}),
+			Input:    "three\nsmall\npigs\n",
+			Expected: "1538 1\n",
+		},
+		{
+			Name:     "bsd",
+			Filter:   New().Algorithm(BSD).Untagged(true),
+			FromArgs: fromArgs(t, []string{"--algorithm", "bsd"}),
+			Input:    "three\nsmall\npigs\n",
+			Expected: "64120     1\n",
+		},
+		{
+			Name:     "sha3-256",
+			Filter:   New().Algorithm(SHA3_256),
+			FromArgs: fromArgs(t, []string{"--algorithm", "sha3-256"}),
+			Input:    "three\nsmall\npigs\n",
+			Expected: "SHA3-256 (-) = 43b199a8dc24d94481936ed0455fd5ec3ace15229468fcd98bbedea99f8ab176\n",
+		},
+		{
+			Name:     "sha3-512 untagged",
+			Filter:   New().Algorithm(SHA3_512).Untagged(true),
+			FromArgs: fromArgs(t, []string{"--algorithm", "sha3-512", "--untagged"}),
+			Input:    "three\nsmall\npigs\n",
+			Expected: "ee70d3130c636a9ab3ebf01da989e5d9de7ebd39f983b589666437f95ee2d5deccc4f92d8a6a486c8e760cd5625a5d91c34722da5db3303d61ea00dd2d1db277  -\n",
+		},
+		{
+			Name:     "blake3",
+			Filter:   New().Algorithm(BLAKE3),
+			FromArgs: fromArgs(t, []string{"--algorithm", "blake3"}),
+			Input:    "",
+			Expected: "BLAKE3 (-) = af1349b9f5f9a1a6a0404dea36dcc9499bcb25c9adc112b7cc9a93cae41f3262\n",
+		},
+		{
+			Name:     "xxh64",
+			Filter:   New().Algorithm(XXH64),
+			FromArgs: fromArgs(t, []string{"--algorithm", "xxh64"}),
+			Input:    "",
+			Expected: "XXH64 (-) = ef46db3751d8e999\n",
+		},
+		{
+			Name:     "blake2b --length 128",
+			Filter:   New().Algorithm(BLAKE2B).Length(128),
+			FromArgs: fromArgs(t, []string{"--algorithm", "blake2b", "-l", "128"}),
+			Input:    "three\nsmall\npigs\n",
+			Expected: "BLAKE2b-128 (-) = 30957b2bc441767f75cbc8a85e6e7de6\n",
+		},
 	}
 
 	test.RunAll(t, testCases)
+
+	for _, argv := range [][]string{
+		{"-l", "128"},
+		{"-a", "blake2b", "-l", "12"},
+		{"-a", "blake2b", "-l", "1024"},
+	} {
+		_, err := New().FromArgs(argv)
+		require.Error(t, err, argv)
+	}
 }
 
 func initTemp(t *testing.T, name string) string {
@@ -194,7 +267,7 @@ func TestCheck(t *testing.T) {
 		{
 			name:          "error --algorithm mismatch",
 			cksum:         New().Check(true).Algorithm(SHA224).Files(tsp + ".tag.md5"),
-			expectedError: "BadLineFormatError",
+			expectedError: "no properly formatted SHA224 checksum lines found",
 		},
 		{
 			name:          "error not found file",
@@ -252,6 +325,139 @@ func TestCheck(t *testing.T) {
 
 }
 
+func TestCheckFS(t *testing.T) {
+	test.Parallel(t)
+	fsys := fstest.MapFS{
+		"three-small-pigs":     {Data: []byte("three\nsmall\npigs\n")},
+		"sums/tag.md5":         {Data: []byte("MD5 (three-small-pigs) = 5f707e2a346cc0dac73e1323198a503c\n")},
+		"sums/tag.broken.md5":  {Data: []byte("MD5 (/three-small-pigs) = 1f707e2a346cc0dac73e1323198a503c\n")},
+		"sums/tag.missing.md5": {Data: []byte("MD5 (missing-file) = 5f707e2a346cc0dac73e1323198a503c\n")},
+	}
+
+	var stdout strings.Builder
+	stdio := unix.NewStdio(nil, &stdout, io.Discard)
+	err := New().Check(true).FS(fsys).Files("sums/tag.md5").Run(context.Background(), stdio)
+	require.NoError(t, err)
+	require.Equal(t, "three-small-pigs: OK\n", stdout.String())
+
+	stdout.Reset()
+	err = New().Check(true).FS(fsys).Files("sums/tag.broken.md5").Run(context.Background(), stdio)
+	require.Error(t, err)
+	require.Equal(t, "/three-small-pigs: FAILED\n", stdout.String())
+
+	err = New().Check(true).FS(fsys).Files("sums/tag.missing.md5").Run(context.Background(), stdio)
+	require.Error(t, err)
+}
+
+func TestCheckAutodetect(t *testing.T) {
+	test.Parallel(t)
+	fsys := fstest.MapFS{
+		"three-small-pigs": {Data: []byte("three\nsmall\npigs\n")},
+		"sums": {Data: []byte(
+			"43b199a8dc24d94481936ed0455fd5ec3ace15229468fcd98bbedea99f8ab176  three-small-pigs\n" +
+				"df6dcdeb51efc7d15fda87cf45d9d2ff88841b22580a62867f2177f2ed8b1eb7  three-small-pigs\n" +
+				"875e5ea3f4aa28ef  three-small-pigs\n" +
+				"ee70d3130c636a9ab3ebf01da989e5d9de7ebd39f983b589666437f95ee2d5deccc4f92d8a6a486c8e760cd5625a5d91c34722da5db3303d61ea00dd2d1db277  three-small-pigs\n" +
+				"SHA3-256 (three-small-pigs) = 43b199a8dc24d94481936ed0455fd5ec3ace15229468fcd98bbedea99f8ab176\n" +
+				"BLAKE3 (three-small-pigs) = df6dcdeb51efc7d15fda87cf45d9d2ff88841b22580a62867f2177f2ed8b1eb7\n" +
+				"XXH64 (three-small-pigs) = 875e5ea3f4aa28ef\n" +
+				"0000000000000000000000000000000000000000000000000000000000000000  three-small-pigs\n",
+		)},
+	}
+
+	var stdout strings.Builder
+	stdio := unix.NewStdio(nil, &stdout, io.Discard)
+	err := New().Check(true).FS(fsys).Files("sums").Run(context.Background(), stdio)
+	require.Error(t, err)
+	require.Equal(t, strings.Repeat("three-small-pigs: OK\n", 7)+"three-small-pigs: FAILED\n", stdout.String())
+
+	err = New().Check(true).Algorithm(BSD).FS(fsys).Files("sums").Run(context.Background(), stdio)
+	require.EqualError(t, err, "--check is not supported with algorithm=bsd")
+}
+
+func TestCheckWarnings(t *testing.T) {
+	test.Parallel(t)
+	fsys := fstest.MapFS{
+		"three-small-pigs": {Data: []byte("three\nsmall\npigs\n")},
+		"sums": {Data: []byte(
+			"BLAKE2b-256 (three-small-pigs) = 24ed404c930a448768501365642b6db9bfe44cdba781d04fc21de94492d84acc\n" +
+				"garbage\n" +
+				"MD5 (missing) = 5f707e2a346cc0dac73e1323198a503c\n" +
+				"MD5 (three-small-pigs) = 1f707e2a346cc0dac73e1323198a503c\n",
+		)},
+		"sums.ok": {Data: []byte(
+			"BLAKE2b-256 (three-small-pigs) = 24ed404c930a448768501365642b6db9bfe44cdba781d04fc21de94492d84acc\n" +
+				"garbage\n",
+		)},
+		"sums.zero": {Data: []byte(
+			"MD5 (three-small-pigs) = 5f707e2a346cc0dac73e1323198a503c\x00" +
+				"5f707e2a346cc0dac73e1323198a503c  three-small-pigs\x00",
+		)},
+	}
+
+	testCases := []struct {
+		name           string
+		cksum          CKSum
+		expectedStdout string
+		expectedStderr string
+		expectedError  bool
+	}{
+		{
+			name:           "warn",
+			cksum:          New().Warn(true).Files("sums"),
+			expectedStdout: "three-small-pigs: OK\nmissing: FAILED open or read\nthree-small-pigs: FAILED\n",
+			expectedStderr: "cksum: sums: 2: improperly formatted checksum line\n" +
+				"cksum: open missing: file does not exist\n" +
+				"cksum: WARNING: 1 line is improperly formatted\n" +
+				"cksum: WARNING: 1 listed file could not be read\n" +
+				"cksum: WARNING: 1 computed checksum did NOT match\n",
+			expectedError: true,
+		},
+		{
+			name:           "status",
+			cksum:          New().Status(true).Files("sums"),
+			expectedStdout: "",
+			expectedStderr: "",
+			expectedError:  true,
+		},
+		{
+			name:           "improperly formatted",
+			cksum:          New().Files("sums.ok"),
+			expectedStdout: "three-small-pigs: OK\n",
+			expectedStderr: "cksum: WARNING: 1 line is improperly formatted\n",
+		},
+		{
+			name:           "strict",
+			cksum:          New().Strict(true).Files("sums.ok"),
+			expectedStdout: "three-small-pigs: OK\n",
+			expectedStderr: "cksum: WARNING: 1 line is improperly formatted\n",
+			expectedError:  true,
+		},
+		{
+			name:           "zero",
+			cksum:          New().Zero(true).Algorithm(MD5).Files("sums.zero"),
+			expectedStdout: "three-small-pigs: OK\nthree-small-pigs: OK\n",
+		},
+	}
+
+	for _, tt := range testCases {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			test.Parallel(t)
+			var stdout, stderr strings.Builder
+			stdio := unix.NewStdio(nil, &stdout, &stderr)
+			err := tt.cksum.Check(true).FS(fsys).Parallel(1).Run(context.Background(), stdio)
+			if tt.expectedError {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+			}
+			require.Equal(t, tt.expectedStdout, stdout.String())
+			require.Equal(t, tt.expectedStderr, stderr.String())
+		})
+	}
+}
+
 func fromArgs(t *testing.T, argv []string) CKSum {
 	t.Helper()
 	n := New()
@@ -0,0 +1,166 @@
+// Copyright 2023 Michal Vyskocil. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+/*
+gonix is a busybox-like multi-call binary running gonix filters
+
+	gonix APPLET [ARG]...
+	gonix --list
+	gonix --install DIR
+
+When called via a symlink named after an applet (created by --install), it
+runs the applet directly, so ./cat is the same as ./gonix cat.
+
+An exit status of the process is the status of the returned error, see
+pipeline.Code. An applet interrupted by a signal exits with 130.
+*/
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+
+	"github.com/gomoni/gio/pipe"
+	"github.com/gomoni/gio/unix"
+	"github.com/gomoni/gonix"
+	"github.com/gomoni/gonix/internal"
+	"github.com/gomoni/gonix/pipeline"
+
+	// applets register itself to gonix.Default
+	_ "github.com/gomoni/gonix/awk"
+	_ "github.com/gomoni/gonix/cat"
+	_ "github.com/gomoni/gonix/cksum"
+	_ "github.com/gomoni/gonix/cut"
+	_ "github.com/gomoni/gonix/grep"
+	_ "github.com/gomoni/gonix/head"
+	_ "github.com/gomoni/gonix/jq"
+	_ "github.com/gomoni/gonix/sed"
+	_ "github.com/gomoni/gonix/sort"
+	_ "github.com/gomoni/gonix/tail"
+	_ "github.com/gomoni/gonix/timeout"
+	_ "github.com/gomoni/gonix/tr"
+	_ "github.com/gomoni/gonix/uniq"
+	_ "github.com/gomoni/gonix/wc"
+)
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	code := run(ctx, unix.NewStdio(os.Stdin, os.Stdout, os.Stderr), os.Args)
+	stop()
+	os.Exit(code)
+}
+
+func run(ctx context.Context, stdio unix.StandardIO, argv []string) int {
+	if len(argv) == 0 {
+		usage(stdio.Stderr())
+		return 1
+	}
+
+	// symlink mode: ln -s gonix cat
+	name := filepath.Base(argv[0])
+	if _, ok := gonix.Lookup(name); ok {
+		return runApplet(ctx, stdio, name, argv[1:])
+	}
+
+	if len(argv) < 2 {
+		usage(stdio.Stderr())
+		return 1
+	}
+	switch argv[1] {
+	case "-h", "--help":
+		usage(stdio.Stdout())
+		fmt.Fprintf(stdio.Stdout(), "\nApplets:\n")
+		for _, cmd := range gonix.Default.Cmds() {
+			fmt.Fprintf(stdio.Stdout(), "  %-8s %s\n", cmd.Name, cmd.Usage)
+		}
+		return 0
+	case "--list":
+		for _, name := range gonix.Names() {
+			fmt.Fprintln(stdio.Stdout(), name)
+		}
+		return 0
+	case "--install":
+		if len(argv) != 3 {
+			fmt.Fprintf(stdio.Stderr(), "gonix: --install expects exactly one DIR argument\n")
+			return 1
+		}
+		return exitCode(stdio.Stderr(), install(argv[2], stdio.Stderr()))
+	}
+	return runApplet(ctx, stdio, argv[1], argv[2:])
+}
+
+func runApplet(ctx context.Context, stdio unix.StandardIO, name string, argv []string) int {
+	cmd, ok := gonix.Lookup(name)
+	if !ok {
+		fmt.Fprintf(stdio.Stderr(), "gonix: %s: applet not found\n", name)
+		return pipe.NotFound
+	}
+
+	filter, err := cmd.New(argv)
+	if err != nil {
+		return exitCode(stdio.Stderr(), err)
+	}
+	err = filter.Run(ctx, stdio)
+	if err != nil && errors.Is(ctx.Err(), context.Canceled) {
+		// an applet may fail differently when interrupted, a shell reports 128+SIGINT
+		err = pipe.NewError(pipeline.Interrupted, pipe.FromError(err).Err)
+	}
+	return exitCode(stdio.Stderr(), err)
+}
+
+// exitCode maps an error to the process exit status, an error message is
+// printed to stderr unless the applet did it already
+func exitCode(stderr io.Writer, err error) int {
+	if err == nil {
+		return 0
+	}
+	code := pipeline.Code(err)
+	if code == pipeline.BrokenPipe {
+		// silent like a process killed by SIGPIPE
+		return code
+	}
+	e := pipe.FromError(err)
+	if e.Err != nil && !internal.IsReported(e.Err) {
+		fmt.Fprintf(stderr, "gonix: %s\n", e.Err)
+	}
+	return code
+}
+
+// install creates a symlink for each applet in a directory dir
+func install(dir string, stderr io.Writer) error {
+	exe, err := os.Executable()
+	if err != nil {
+		return pipe.NewErrorf(1, "--install: %w", err)
+	}
+	exe, err = filepath.EvalSymlinks(exe)
+	if err != nil {
+		return pipe.NewErrorf(1, "--install: %w", err)
+	}
+
+	var failed bool
+	for _, name := range gonix.Names() {
+		err := os.Symlink(exe, filepath.Join(dir, name))
+		if err != nil {
+			fmt.Fprintf(stderr, "gonix: --install: %s\n", err)
+			failed = true
+		}
+	}
+	if failed {
+		return pipe.NewError(1, nil)
+	}
+	return nil
+}
+
+func usage(w io.Writer) {
+	fmt.Fprintf(w, "Usage: gonix APPLET [ARG]...\n")
+	fmt.Fprintf(w, "   or: gonix --list\n")
+	fmt.Fprintf(w, "   or: gonix --install DIR\n")
+	fmt.Fprintf(w, "   or: APPLET [ARG]... when called via symlink\n")
+}
@@ -0,0 +1,258 @@
+// Copyright 2023 Michal Vyskocil. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+
+	"github.com/gomoni/gio/pipe"
+	"github.com/gomoni/gio/unix"
+	"github.com/gomoni/gonix"
+	"github.com/gomoni/gonix/internal/test"
+	"github.com/gomoni/gonix/pipeline"
+	"github.com/gomoni/gonix/sh"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRun(t *testing.T) {
+	test.Parallel(t)
+
+	testCases := []struct {
+		name     string
+		argv     []string
+		input    string
+		expected string
+		code     int
+	}{
+		{
+			name:     "gonix --list",
+			argv:     []string{"gonix", "--list"},
+			expected: "awk\ncat\ncksum\ncut\ngrep\nhead\njq\nsed\nsort\ntail\ntimeout\ntr\nuniq\nwc\n",
+		},
+		{
+			name:     "gonix wc -l",
+			argv:     []string{"gonix", "wc", "-l"},
+			input:    "three\nsmall\npigs\n",
+			expected: "3\n",
+		},
+		{
+			name:     "symlink head -n 1",
+			argv:     []string{"/usr/local/bin/head", "-n", "1"},
+			input:    "three\nsmall\npigs\n",
+			expected: "three\n",
+		},
+		{
+			name:     "gonix awk -F:",
+			argv:     []string{"gonix", "awk", "-F:", "{print $2}"},
+			input:    "1:three\n2:small\n3:pigs\n",
+			expected: "three\nsmall\npigs\n",
+		},
+		{
+			name:     "gonix tr -d aeiou",
+			argv:     []string{"gonix", "tr", "-d", "aeiou"},
+			input:    "three\nsmall\npigs\n",
+			expected: "thr\nsmll\npgs\n",
+		},
+		{
+			name: "gonix not-found",
+			argv: []string{"gonix", "not-found"},
+			code: pipe.NotFound,
+		},
+		{
+			name: "gonix cat main.c",
+			argv: []string{"gonix", "cat", "main.c"},
+			code: 1,
+		},
+		{
+			name: "gonix awk exit 3",
+			argv: []string{"gonix", "awk", "BEGIN {exit 3}"},
+			code: 3,
+		},
+		{
+			name:     "gonix timeout 1m wc -l",
+			argv:     []string{"gonix", "timeout", "1m", "wc", "-l"},
+			input:    "three\nsmall\npigs\n",
+			expected: "3\n",
+		},
+		{
+			name: "gonix timeout 1m",
+			argv: []string{"gonix", "timeout", "1m"},
+			code: 125,
+		},
+		{
+			name: "gonix wc --unknown",
+			argv: []string{"gonix", "wc", "--unknown"},
+			code: 1,
+		},
+	}
+
+	for _, tt := range testCases {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			test.Parallel(t)
+			var stdout strings.Builder
+			var stderr strings.Builder
+			stdio := unix.NewStdio(
+				strings.NewReader(tt.input),
+				&stdout,
+				&stderr,
+			)
+			code := run(context.Background(), stdio, tt.argv)
+			t.Logf("stderr=%q", stderr.String())
+			require.Equal(t, tt.code, code)
+			require.Equal(t, tt.expected, stdout.String())
+		})
+	}
+}
+
+func TestInstall(t *testing.T) {
+	test.Parallel(t)
+	dir := t.TempDir()
+
+	var stderr strings.Builder
+	stdio := unix.NewStdio(nil, &stderr, &stderr)
+	code := run(context.Background(), stdio, []string{"gonix", "--install", dir})
+	require.Equal(t, 0, code, stderr.String())
+
+	exe, err := os.Executable()
+	require.NoError(t, err)
+	exe, err = filepath.EvalSymlinks(exe)
+	require.NoError(t, err)
+	for _, name := range gonix.Names() {
+		dest, err := os.Readlink(filepath.Join(dir, name))
+		require.NoError(t, err)
+		require.Equal(t, exe, dest)
+	}
+
+	// second install fails as symlinks exist
+	code = run(context.Background(), stdio, []string{"gonix", "--install", dir})
+	require.Equal(t, 1, code)
+}
+
+func TestInterrupted(t *testing.T) {
+	test.Parallel(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	var stderr strings.Builder
+	stdio := unix.NewStdio(strings.NewReader("three\nsmall\npigs\n"), io.Discard, &stderr)
+	code := run(ctx, stdio, []string{"gonix", "head", "-n", "1"})
+	require.Equal(t, 130, code)
+	require.Contains(t, stderr.String(), "context canceled")
+}
+
+func TestCksumStatus(t *testing.T) {
+	test.Parallel(t)
+	sums := filepath.Join(t.TempDir(), "sums")
+	err := os.WriteFile(sums, []byte("garbage\nMD5 (missing) = d41d8cd98f00b204e9800998ecf8427e\n"), 0o644)
+	require.NoError(t, err)
+
+	var stdout, stderr strings.Builder
+	stdio := unix.NewStdio(strings.NewReader(""), &stdout, &stderr)
+	code := run(context.Background(), stdio, []string{"gonix", "cksum", "-c", "--status", sums})
+	require.Equal(t, 1, code)
+	require.Empty(t, stdout.String())
+	require.Empty(t, stderr.String())
+}
+
+func TestTimeoutPreserveStatus(t *testing.T) {
+	test.Parallel(t)
+	for _, tt := range []struct {
+		argv []string
+		code int
+	}{
+		{[]string{"gonix", "timeout", "0.01", "cat"}, 124},
+		{[]string{"gonix", "timeout", "--preserve-status", "0.01", "cat"}, 143},
+	} {
+		stdio := unix.NewStdio(zeros{}, io.Discard, io.Discard)
+		code := run(context.Background(), stdio, tt.argv)
+		require.Equal(t, tt.code, code, tt.argv)
+	}
+}
+
+// zeros is an endless input
+type zeros struct{}
+
+func (zeros) Read(p []byte) (int, error) {
+	for i := range p {
+		p[i] = 0
+	}
+	return len(p), nil
+}
+
+func TestExitCode(t *testing.T) {
+	test.Parallel(t)
+	var stderr strings.Builder
+	code := exitCode(&stderr, fmt.Errorf("awk: %w", syscall.EPIPE))
+	require.Equal(t, 141, code)
+	require.Empty(t, stderr.String())
+}
+
+func TestErrorPrintedOnce(t *testing.T) {
+	test.Parallel(t)
+	for _, argv := range [][]string{
+		{"gonix", "cat", "main.c"},
+		{"gonix", "cksum", "-j", "2", "main.c", "main.go"},
+	} {
+		var stderr strings.Builder
+		stdio := unix.NewStdio(strings.NewReader(""), io.Discard, &stderr)
+		code := run(context.Background(), stdio, argv)
+		require.Equal(t, 1, code)
+		require.Equal(t, 1, strings.Count(stderr.String(), "main.c"), stderr.String())
+	}
+}
+
+// TestBrokenPipe checks that an applet stopped by head gets BrokenPipe
+// status, which does not fail the pipeline
+func TestBrokenPipe(t *testing.T) {
+	test.Parallel(t)
+	var sb strings.Builder
+	for i := 0; i < 100000; i++ {
+		fmt.Fprintf(&sb, "%d\n", i)
+	}
+	input := sb.String()
+
+	testCases := []struct {
+		cmdline string
+		// short outputs may be read before head stops
+		statuses []int
+	}{
+		{"awk {print}", []int{pipeline.BrokenPipe}},
+		{"cat", []int{pipeline.BrokenPipe}},
+		{"cksum", []int{0, pipeline.BrokenPipe}},
+		{"cut -c 1", []int{pipeline.BrokenPipe}},
+		{"grep 1", []int{pipeline.BrokenPipe}},
+		{"head -n 5", []int{0, pipeline.BrokenPipe}},
+		{"jq .", []int{pipeline.BrokenPipe}},
+		{"sed s/1/2/", []int{pipeline.BrokenPipe}},
+		{"sort", []int{pipeline.BrokenPipe}},
+		{"tail -n +1", []int{pipeline.BrokenPipe}},
+		{"timeout 1m cat", []int{pipeline.BrokenPipe}},
+		{"tr 1 2", []int{pipeline.BrokenPipe}},
+		{"uniq", []int{pipeline.BrokenPipe}},
+		{"wc -l", []int{0, pipeline.BrokenPipe}},
+	}
+	for _, tt := range testCases {
+		tt := tt
+		cmdline := tt.cmdline + " | head -n 1"
+		t.Run(cmdline, func(t *testing.T) {
+			test.Parallel(t)
+			stdio := unix.NewStdio(strings.NewReader(input), io.Discard, io.Discard)
+			res, err := sh.New(gonix.Default.Builtins(), nil).Exec(context.Background(), stdio, cmdline)
+			require.NoError(t, err)
+			require.Contains(t, tt.statuses, res.PipeStatus[0])
+			require.Equal(t, 0, res.PipeStatus[1])
+			require.Equal(t, 0, res.Status)
+			require.NoError(t, res.Err())
+		})
+	}
+}
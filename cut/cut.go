@@ -0,0 +1,345 @@
+// Copyright 2023 Michal Vyskocil. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+/*
+cut removes sections from each line of files
+
+	-b/--bytes LIST          select only these bytes
+	-c/--characters LIST     select only these characters
+	-f/--fields LIST         select only these fields
+	-d/--delimiter DELIM     use DELIM instead of TAB for field delimiter
+	-s/--only-delimited      do not print lines not containing delimiters
+	--complement             complement the set of selected bytes, characters or fields
+	--output-delimiter STR   use STR as the output delimiter
+	-z/--zero-terminated     line delimiter is NUL, not newline
+
+LIST is made up of ranges separated by commas, see ParseList. Characters are
+UTF-8 runes, an invalid byte counts as one character. Selected bytes or
+characters are printed in the input order, each at most once.
+*/
+package cut
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"unicode/utf8"
+
+	"github.com/gomoni/gio/pipe"
+	"github.com/gomoni/gio/unix"
+	"github.com/gomoni/gonix"
+	"github.com/gomoni/gonix/internal"
+	"github.com/gomoni/gonix/internal/dbg"
+	"github.com/spf13/pflag"
+)
+
+type mode int
+
+const (
+	none mode = iota
+	bytesMode
+	charsMode
+	fieldsMode
+)
+
+type Cut struct {
+	debug           bool
+	mode            mode
+	list            []Range
+	delimiter       string
+	onlyDelimited   bool
+	complement      bool
+	outputDelimiter *string
+	zeroTerminated  bool
+	fsys            fs.FS
+	files           []string
+}
+
+func New() Cut {
+	return Cut{
+		delimiter: "\t",
+	}
+}
+
+func init() {
+	gonix.Register(gonix.Cmd{
+		Name:  "cut",
+		Usage: "remove sections from each line of files",
+		New:   gonix.FromArgs(New().FromArgs),
+	})
+}
+
+// FromArgs build a Cut from standard argv except the command name (os.Argv[1:])
+func (c Cut) FromArgs(argv []string) (Cut, error) {
+	flag := pflag.FlagSet{}
+
+	bytesList := flag.StringP("bytes", "b", "", "select only these bytes")
+	charsList := flag.StringP("characters", "c", "", "select only these characters")
+	fieldsList := flag.StringP("fields", "f", "", "select only these fields")
+	flag.StringVarP(&c.delimiter, "delimiter", "d", c.delimiter, "use DELIM instead of TAB for field delimiter")
+	flag.BoolVarP(&c.onlyDelimited, "only-delimited", "s", false, "do not print lines not containing delimiters")
+	flag.BoolVar(&c.complement, "complement", false, "complement the set of selected bytes, characters or fields")
+	outputDelimiter := flag.String("output-delimiter", "", "use STR as the output delimiter")
+	flag.BoolVarP(&c.zeroTerminated, "zero-terminated", "z", false, "line delimiter is NUL, not newline")
+	_ = flag.BoolP("n", "n", false, "ignored")
+
+	err := flag.Parse(argv)
+	if err != nil {
+		return Cut{}, pipe.NewErrorf(1, "cut: parsing failed: %w", err)
+	}
+
+	var list string
+	for _, m := range []struct {
+		name string
+		mode mode
+		list string
+	}{
+		{"bytes", bytesMode, *bytesList},
+		{"characters", charsMode, *charsList},
+		{"fields", fieldsMode, *fieldsList},
+	} {
+		if !flag.Changed(m.name) {
+			continue
+		}
+		if c.mode != none {
+			return Cut{}, pipe.NewErrorf(1, "cut: only one type of list may be specified")
+		}
+		c.mode, list = m.mode, m.list
+	}
+	if c.mode == none {
+		return Cut{}, pipe.NewErrorf(1, "cut: you must specify a list of bytes, characters, or fields")
+	}
+	c.list, err = ParseList(list)
+	if err != nil {
+		return Cut{}, pipe.NewErrorf(1, "cut: %w", err)
+	}
+
+	if c.mode != fieldsMode && (flag.Changed("delimiter") || c.onlyDelimited) {
+		return Cut{}, pipe.NewErrorf(1, "cut: an input delimiter or -s may be specified only when operating on fields")
+	}
+	if utf8.RuneCountInString(c.delimiter) != 1 {
+		return Cut{}, pipe.NewErrorf(1, "cut: the delimiter must be a single character")
+	}
+	if flag.Changed("output-delimiter") {
+		c.outputDelimiter = outputDelimiter
+	}
+
+	if len(flag.Args()) > 0 {
+		c.files = flag.Args()
+	}
+	return c, nil
+}
+
+// Bytes selects bytes from each line
+func (c Cut) Bytes(list ...Range) Cut {
+	c.mode = bytesMode
+	c.list = list
+	return c
+}
+
+// Chars selects characters (runes) from each line
+func (c Cut) Chars(list ...Range) Cut {
+	c.mode = charsMode
+	c.list = list
+	return c
+}
+
+// Fields selects fields separated by a delimiter
+func (c Cut) Fields(list ...Range) Cut {
+	c.mode = fieldsMode
+	c.list = list
+	return c
+}
+
+// Delimiter is a single character separating fields, TAB by default
+func (c Cut) Delimiter(delimiter string) Cut {
+	c.delimiter = delimiter
+	return c
+}
+
+// OnlyDelimited skips lines not containing a delimiter
+func (c Cut) OnlyDelimited(b bool) Cut {
+	c.onlyDelimited = b
+	return c
+}
+
+// Complement selects all bytes, characters or fields not in a list
+func (c Cut) Complement(b bool) Cut {
+	c.complement = b
+	return c
+}
+
+// OutputDelimiter joins selected fields or distinct ranges of bytes or characters.
+// Fields are joined by the input delimiter by default.
+func (c Cut) OutputDelimiter(delimiter string) Cut {
+	c.outputDelimiter = &delimiter
+	return c
+}
+
+func (c Cut) ZeroTerminated(b bool) Cut {
+	c.zeroTerminated = b
+	return c
+}
+
+// Files are input files, where - denotes stdin
+func (c Cut) Files(f ...string) Cut {
+	c.files = append(c.files, f...)
+	return c
+}
+
+// FS sets a filesystem used to open files, nil means the OS filesystem
+func (c Cut) FS(fsys fs.FS) Cut {
+	c.fsys = fsys
+	return c
+}
+
+func (c Cut) SetDebug(debug bool) Cut {
+	c.debug = debug
+	return c
+}
+
+func (c Cut) Run(ctx context.Context, stdio unix.StandardIO) error {
+	debug := dbg.Logger(c.debug, "cut", stdio.Stderr())
+	debug.Printf("mode=%d, list=%+v", c.mode, c.list)
+
+	if c.mode == none || len(c.list) == 0 {
+		return pipe.NewErrorf(1, "cut: you must specify a list of bytes, characters, or fields")
+	}
+	if c.mode == fieldsMode && utf8.RuneCountInString(c.delimiter) != 1 {
+		return pipe.NewErrorf(1, "cut: the delimiter must be a single character")
+	}
+
+	out := bufio.NewWriter(stdio.Stdout())
+	cutter := newCutter(c, out)
+	cut := func(ctx context.Context, stdio unix.StandardIO, _ int, _ string) error {
+		err := cutter.cut(ctx, stdio.Stdin())
+		if err != nil {
+			return internal.NewError(1, fmt.Errorf("cut: fail to run: %w", err))
+		}
+		return nil
+	}
+	errs := internal.NewRunFiles(c.files, stdio, cut).FS(c.fsys).Do(ctx)
+	if err := out.Flush(); err != nil {
+		return internal.NewError(1, fmt.Errorf("cut: fail to run: %w", err))
+	}
+	return errs
+}
+
+type cutter struct {
+	c         Cut
+	sel       selection
+	delim     byte
+	fieldSep  []byte
+	outputSep []byte
+	out       *bufio.Writer
+}
+
+func newCutter(c Cut, out *bufio.Writer) *cutter {
+	x := &cutter{
+		c:        c,
+		sel:      newSelection(c.list, c.complement),
+		delim:    '\n',
+		fieldSep: []byte(c.delimiter),
+		out:      out,
+	}
+	if c.zeroTerminated {
+		x.delim = 0
+	}
+	if c.outputDelimiter != nil {
+		x.outputSep = []byte(*c.outputDelimiter)
+	} else if c.mode == fieldsMode {
+		x.outputSep = x.fieldSep
+	}
+	return x
+}
+
+func (x *cutter) cut(ctx context.Context, in io.Reader) error {
+	r := bufio.NewReaderSize(in, 64*1024)
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		line, err := r.ReadSlice(x.delim)
+		if errors.Is(err, bufio.ErrBufferFull) {
+			// ReadSlice is fast for common lines, long ones needs a copy
+			var rest []byte
+			rest, err = r.ReadBytes(x.delim)
+			line = append(append([]byte{}, line...), rest...)
+		}
+		if len(line) > 0 && line[len(line)-1] == x.delim {
+			line = line[:len(line)-1]
+		} else if len(line) == 0 && errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil && !errors.Is(err, io.EOF) {
+			return err
+		}
+
+		switch x.c.mode {
+		case fieldsMode:
+			x.fields(line)
+		case charsMode:
+			x.positions(line, true)
+		default:
+			x.positions(line, false)
+		}
+
+		if err != nil {
+			return nil
+		}
+	}
+}
+
+// positions prints selected bytes or characters
+func (x *cutter) positions(line []byte, chars bool) {
+	var printed, prev bool
+	for pos := 1; len(line) > 0; pos++ {
+		size := 1
+		if chars {
+			_, size = utf8.DecodeRune(line)
+		}
+		if x.sel.selected(pos) {
+			if printed && !prev && x.outputSep != nil {
+				x.out.Write(x.outputSep)
+			}
+			x.out.Write(line[:size])
+			printed, prev = true, true
+		} else {
+			prev = false
+		}
+		line = line[size:]
+	}
+	x.out.WriteByte(x.delim)
+}
+
+// fields prints selected fields
+func (x *cutter) fields(line []byte) {
+	if !bytes.Contains(line, x.fieldSep) {
+		if !x.c.onlyDelimited {
+			x.out.Write(line)
+			x.out.WriteByte(x.delim)
+		}
+		return
+	}
+	var printed bool
+	for pos := 1; ; pos++ {
+		field, rest, found := bytes.Cut(line, x.fieldSep)
+		if x.sel.selected(pos) {
+			if printed {
+				x.out.Write(x.outputSep)
+			}
+			x.out.Write(field)
+			printed = true
+		}
+		if !found {
+			break
+		}
+		line = rest
+	}
+	x.out.WriteByte(x.delim)
+}
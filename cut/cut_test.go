@@ -0,0 +1,145 @@
+// Copyright 2023 Michal Vyskocil. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package cut_test
+
+import (
+	"testing"
+
+	. "github.com/gomoni/gonix/cut"
+	"github.com/gomoni/gonix/internal/test"
+	"github.com/stretchr/testify/require"
+)
+
+const passwd = "root:x:0:0:root:/root:/bin/bash\nbin:x:1:1:bin:/bin:/sbin/nologin\n"
+
+func TestCut(t *testing.T) {
+	test.Parallel(t)
+	testCases := []test.Case[Cut]{
+		{
+			Name:     "-b",
+			Filter:   New().Bytes(Range{1, 1}, Range{3, 4}),
+			FromArgs: fromArgs(t, []string{"-b", "1,3-4"}),
+			Input:    "abcdef\n",
+			Expected: "acd\n",
+		},
+		{
+			Name:     "-b open ranges",
+			Filter:   New().Bytes(Range{Start: 5}, Range{1, 2}),
+			FromArgs: fromArgs(t, []string{"-b", "5-,-2"}),
+			Input:    "abcdef\n",
+			Expected: "abef\n",
+		},
+		{
+			Name:     "-b utf-8",
+			Filter:   New().Bytes(Range{1, 2}),
+			FromArgs: fromArgs(t, []string{"-b", "-2"}),
+			Input:    "žluť\n",
+			Expected: "ž\n",
+		},
+		{
+			Name:     "-c utf-8",
+			Filter:   New().Chars(Range{2, 3}),
+			FromArgs: fromArgs(t, []string{"-c", "2-3"}),
+			Input:    "žluť\nkůň\n",
+			Expected: "lu\nůň\n",
+		},
+		{
+			Name:     "-c --complement",
+			Filter:   New().Chars(Range{2, 3}).Complement(true),
+			FromArgs: fromArgs(t, []string{"-c", "2-3", "--complement"}),
+			Input:    "žluť\n",
+			Expected: "žť\n",
+		},
+		{
+			Name:     "-c --output-delimiter",
+			Filter:   New().Chars(Range{1, 2}, Range{4, 0}).OutputDelimiter("|"),
+			FromArgs: fromArgs(t, []string{"-c", "1-2,4-", "--output-delimiter", "|"}),
+			Input:    "abcdef\n",
+			Expected: "ab|def\n",
+		},
+		{
+			Name:     "-f",
+			Filter:   New().Fields(Range{2, 2}),
+			FromArgs: fromArgs(t, []string{"-f", "2"}),
+			Input:    "a\tb\tc\nnone\n",
+			Expected: "b\nnone\n",
+		},
+		{
+			Name:     "-f -d",
+			Filter:   New().Fields(Range{1, 1}, Range{6, 0}).Delimiter(":"),
+			FromArgs: fromArgs(t, []string{"-d:", "-f", "1,6-"}),
+			Input:    passwd,
+			Expected: "root:/root:/bin/bash\nbin:/bin:/sbin/nologin\n",
+		},
+		{
+			Name:     "-f -s",
+			Filter:   New().Fields(Range{1, 1}).Delimiter(":").OnlyDelimited(true),
+			FromArgs: fromArgs(t, []string{"-s", "-d", ":", "-f", "1"}),
+			Input:    "none\na:b\n",
+			Expected: "a\n",
+		},
+		{
+			Name:     "-f --complement --output-delimiter",
+			Filter:   New().Fields(Range{2, 5}).Delimiter(":").Complement(true).OutputDelimiter(" "),
+			FromArgs: fromArgs(t, []string{"-d:", "-f2-5", "--complement", "--output-delimiter= "}),
+			Input:    passwd,
+			Expected: "root /root /bin/bash\nbin /bin /sbin/nologin\n",
+		},
+		{
+			Name:     "-f utf-8 delimiter",
+			Filter:   New().Fields(Range{2, 2}).Delimiter("→"),
+			FromArgs: fromArgs(t, []string{"-d→", "-f2"}),
+			Input:    "a→b→c\n",
+			Expected: "b\n",
+		},
+		{
+			Name:     "-z",
+			Filter:   New().Bytes(Range{1, 1}).ZeroTerminated(true),
+			FromArgs: fromArgs(t, []string{"-z", "-b1"}),
+			Input:    "ab\x00cd",
+			Expected: "a\x00c\x00",
+		},
+	}
+	test.RunAll(t, testCases)
+}
+
+func TestParseList(t *testing.T) {
+	test.Parallel(t)
+	list, err := ParseList("1,3-5,7-")
+	require.NoError(t, err)
+	require.Equal(t, []Range{{1, 1}, {3, 5}, {7, 0}}, list)
+
+	list, err = ParseList("-2 4")
+	require.NoError(t, err)
+	require.Equal(t, []Range{{1, 2}, {4, 4}}, list)
+
+	for _, s := range []string{"", "0", "-", "3-1", "a", "1-b", "+1"} {
+		_, err := ParseList(s)
+		require.Error(t, err, s)
+	}
+}
+
+func TestErrors(t *testing.T) {
+	test.Parallel(t)
+	for _, argv := range [][]string{
+		{},
+		{"-b1", "-c1"},
+		{"-b1", "-d:"},
+		{"-c1", "-s"},
+		{"-f1", "-d", "::"},
+		{"-f", "2-1"},
+	} {
+		_, err := New().FromArgs(argv)
+		require.Error(t, err, argv)
+	}
+}
+
+func fromArgs(t *testing.T, argv []string) Cut {
+	t.Helper()
+	n := New()
+	f, err := n.FromArgs(argv)
+	require.NoError(t, err)
+	return f
+}
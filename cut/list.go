@@ -0,0 +1,123 @@
+// Copyright 2023 Michal Vyskocil. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package cut
+
+import (
+	"fmt"
+	stdsort "sort"
+	"strconv"
+	"strings"
+)
+
+// Range selects bytes, characters or fields from Start to End inclusive.
+// Positions are numbered from 1, End 0 means to the end of line.
+type Range struct {
+	Start int
+	End   int
+}
+
+// ParseList parses a list of ranges separated by commas or blanks. Each range
+// is one of N, N-M, N- or -M.
+func ParseList(s string) ([]Range, error) {
+	items := strings.FieldsFunc(s, func(r rune) bool {
+		return r == ',' || r == ' ' || r == '\t'
+	})
+	if len(items) == 0 {
+		return nil, fmt.Errorf("invalid empty list %q", s)
+	}
+	ret := make([]Range, 0, len(items))
+	for _, item := range items {
+		r, err := parseRange(item)
+		if err != nil {
+			return nil, err
+		}
+		ret = append(ret, r)
+	}
+	return ret, nil
+}
+
+func parseRange(s string) (Range, error) {
+	number := func(s string) (int, error) {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 0 || strings.HasPrefix(s, "+") {
+			return 0, fmt.Errorf("invalid byte, character or field list %q", s)
+		}
+		if n == 0 {
+			return 0, fmt.Errorf("byte, character or field positions are numbered from 1")
+		}
+		return n, nil
+	}
+
+	before, after, found := strings.Cut(s, "-")
+	if !found {
+		n, err := number(s)
+		return Range{Start: n, End: n}, err
+	}
+	if before == "" && after == "" {
+		return Range{}, fmt.Errorf("invalid range with no endpoint: -")
+	}
+
+	r := Range{Start: 1}
+	var err error
+	if before != "" {
+		r.Start, err = number(before)
+		if err != nil {
+			return Range{}, err
+		}
+	}
+	if after != "" {
+		r.End, err = number(after)
+		if err != nil {
+			return Range{}, err
+		}
+		if r.End < r.Start {
+			return Range{}, fmt.Errorf("invalid decreasing range %q", s)
+		}
+	}
+	return r, nil
+}
+
+// selection tells which positions are selected by a list of ranges
+type selection struct {
+	ranges     []Range
+	complement bool
+}
+
+func newSelection(list []Range, complement bool) selection {
+	ranges := make([]Range, len(list))
+	copy(ranges, list)
+	stdsort.Slice(ranges, func(i, j int) bool {
+		return ranges[i].Start < ranges[j].Start
+	})
+
+	// merge overlapping and adjacent ranges
+	merged := ranges[:0]
+	for _, r := range ranges {
+		if len(merged) > 0 {
+			last := &merged[len(merged)-1]
+			if last.End == 0 || r.Start <= last.End+1 {
+				if last.End != 0 && (r.End == 0 || r.End > last.End) {
+					last.End = r.End
+				}
+				continue
+			}
+		}
+		merged = append(merged, r)
+	}
+	return selection{ranges: merged, complement: complement}
+}
+
+// selected returns true if position pos (starting from 1) is selected
+func (s selection) selected(pos int) bool {
+	for _, r := range s.ranges {
+		if pos < r.Start {
+			break
+		}
+		if r.End == 0 || pos <= r.End {
+			return !s.complement
+		}
+	}
+	return s.complement
+}
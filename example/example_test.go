@@ -12,7 +12,9 @@ import (
 
 	"github.com/gomoni/gio/unix"
 	"github.com/gomoni/gonix/cat"
+	"github.com/gomoni/gonix/exec"
 	"github.com/gomoni/gonix/head"
+	"github.com/gomoni/gonix/sh"
 	"github.com/gomoni/gonix/wc"
 )
 
@@ -57,16 +59,35 @@ func Example_from_args() {
 	// 3
 }
 
-/* FIXME: NewExec shall be ported back to gio
-func ExampleRun_exec() {
+// This example shows the sh.Sh running a shell-like pipeline with native filters
+func Example_sh() {
 	stdio := unix.NewStdio(
-		os.Stdin,
+		bytes.NewBufferString("three\nsmall\npigs\n"),
+		os.Stdout,
+		os.Stderr,
+	)
+	ctx := context.Background()
+	builtins := sh.Builtins{
+		"cat": func(a []string) (unix.Filter, error) { return cat.New().FromArgs(a) },
+		"wc":  func(a []string) (unix.Filter, error) { return wc.New().FromArgs(a) },
+	}
+	err := sh.New(builtins, sh.Fields).Run(ctx, stdio, `cat | wc -l`)
+	if err != nil {
+		log.Fatal(err)
+	}
+	// Output:
+	// 3
+}
+
+// This example shows the unix.NewLine().Run with external process and a native wc
+func Example_exec() {
+	stdio := unix.NewStdio(
+		bytes.NewReader(nil),
 		os.Stdout,
 		os.Stderr,
 	)
 	ctx := context.Background()
-	cmd := exec.Command("go", "version")
-	goVersion := pipe.NewExec(cmd)
+	goVersion := exec.Command("go", "version")
 	wc, err := wc.New().FromArgs([]string{"-l"})
 	if err != nil {
 		log.Fatal(err)
@@ -79,7 +100,6 @@ func ExampleRun_exec() {
 	// Output:
 	// 1
 }
-*/
 
 func ExampleHead_Run() {
 	head := head.New().Lines(2)
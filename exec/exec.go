@@ -0,0 +1,129 @@
+// Copyright 2023 Michal Vyskocil. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+/*
+exec runs external processes as a [unix.Filter], so real binaries can be mixed
+with native filters in unix.NewLine().
+
+The process is started in own process group and the whole group is killed
+when the context is canceled. Exit status of a process is translated to
+pipe.Error code, a process killed by signal N returns 128+N like shell does.
+Not found or not executable binaries return pipe.NotFound or pipe.NotExecutable.
+
+Note that a process reading from a Go io.Reader (not *os.File) is connected via a
+goroutine, which waits until the reader returns. Use WaitDelay if stdin can
+block forever.
+*/
+package exec
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	osexec "os/exec"
+	"path/filepath"
+	"time"
+
+	"github.com/gomoni/gio/pipe"
+	"github.com/gomoni/gio/unix"
+)
+
+type Exec struct {
+	path      string
+	args      []string
+	env       []string
+	dir       string
+	waitDelay time.Duration
+}
+
+// New returns Exec with a path, arguments, environment and a working directory of cmd.
+// The cmd itself is never started, so it can be reused.
+func New(cmd *osexec.Cmd) Exec {
+	return Exec{
+		path: cmd.Path,
+		args: cmd.Args,
+		env:  cmd.Env,
+		dir:  cmd.Dir,
+	}
+}
+
+// Command is like os/exec.Command, name is looked up in PATH
+func Command(name string, arg ...string) Exec {
+	return New(osexec.Command(name, arg...))
+}
+
+// Env sets the environment of the process in a form key=value, nil means the
+// environment of the current process
+func (c Exec) Env(env []string) Exec {
+	c.env = env
+	return c
+}
+
+// Dir sets the working directory of the process
+func (c Exec) Dir(dir string) Exec {
+	c.dir = dir
+	return c
+}
+
+// WaitDelay bounds the time spent waiting for I/O after the process exits or
+// the context is canceled, see os/exec.Cmd.WaitDelay
+func (c Exec) WaitDelay(d time.Duration) Exec {
+	c.waitDelay = d
+	return c
+}
+
+func (c Exec) Run(ctx context.Context, stdio unix.StandardIO) error {
+	name := c.name()
+	cmd := osexec.CommandContext(ctx, c.path)
+	cmd.Args = c.args
+	cmd.Env = c.env
+	cmd.Dir = c.dir
+	cmd.WaitDelay = c.waitDelay
+	cmd.Stdin = stdio.Stdin()
+	cmd.Stdout = stdio.Stdout()
+	cmd.Stderr = stdio.Stderr()
+	setProcessGroup(cmd)
+	cmd.Cancel = func() error {
+		return killProcessGroup(cmd)
+	}
+
+	err := cmd.Start()
+	if err != nil {
+		e := pipe.FromError(err)
+		if e.Code == pipe.UnknownError {
+			e.Code = pipe.NotExecutable
+		}
+		e.Err = fmt.Errorf("exec: %s: %w", name, err)
+		return e
+	}
+
+	err = cmd.Wait()
+	if err == nil {
+		return nil
+	}
+	var exitErr *osexec.ExitError
+	if errors.As(err, &exitErr) {
+		return pipe.NewErrorf(exitCode(exitErr), "exec: %s: %w", name, err)
+	}
+	return pipe.NewErrorf(1, "exec: %s: %w", name, err)
+}
+
+// FromPath looks the name up in PATH and returns Exec running it. It can be used
+// as a sh.NotFoundFunc to enable an execution of binaries like shell does.
+func FromPath(name string, args []string) (unix.Filter, error) {
+	path, err := osexec.LookPath(name)
+	if err != nil {
+		return nil, pipe.NewErrorf(pipe.NotFound, "exec: %s: command not found", name)
+	}
+	cmd := osexec.Command(path, args...)
+	cmd.Args[0] = name
+	return New(cmd), nil
+}
+
+func (c Exec) name() string {
+	if len(c.args) > 0 {
+		return c.args[0]
+	}
+	return filepath.Base(c.path)
+}
@@ -0,0 +1,24 @@
+// Copyright 2023 Michal Vyskocil. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+//go:build !unix
+
+package exec
+
+import (
+	osexec "os/exec"
+)
+
+func setProcessGroup(*osexec.Cmd) {}
+
+func killProcessGroup(cmd *osexec.Cmd) error {
+	if cmd.Process == nil {
+		return nil
+	}
+	return cmd.Process.Kill()
+}
+
+func exitCode(err *osexec.ExitError) int {
+	return err.ExitCode()
+}
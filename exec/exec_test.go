@@ -0,0 +1,143 @@
+// Copyright 2023 Michal Vyskocil. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package exec_test
+
+import (
+	"context"
+	"io"
+	osexec "os/exec"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gomoni/gio/pipe"
+	"github.com/gomoni/gio/unix"
+	"github.com/gomoni/gonix/cat"
+	. "github.com/gomoni/gonix/exec"
+	"github.com/gomoni/gonix/internal/test"
+	"github.com/gomoni/gonix/wc"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/goleak"
+)
+
+func TestMain(m *testing.M) {
+	goleak.VerifyTestMain(m)
+}
+
+func requireSh(t *testing.T) {
+	t.Helper()
+	if _, err := osexec.LookPath("sh"); err != nil {
+		t.Skipf("sh not found: %s", err)
+	}
+}
+
+func TestExec(t *testing.T) {
+	requireSh(t)
+	test.Parallel(t)
+
+	testCases := []test.Case[Exec]{
+		{
+			Name:     "sh -c 'tr a-z A-Z'",
+			Filter:   Command("sh", "-c", "tr a-z A-Z"),
+			Input:    "three\nsmall\npigs\n",
+			Expected: "THREE\nSMALL\nPIGS\n",
+		},
+		{
+			Name:     "sh -c 'echo $GONIX'",
+			Filter:   Command("sh", "-c", "echo $GONIX").Env([]string{"GONIX=rocks"}),
+			Input:    "",
+			Expected: "rocks\n",
+		},
+		{
+			Name:     "sh -c pwd",
+			Filter:   Command("sh", "-c", "pwd").Dir("/"),
+			Input:    "",
+			Expected: "/\n",
+		},
+	}
+	test.RunAll(t, testCases)
+}
+
+func TestLine(t *testing.T) {
+	requireSh(t)
+	test.Parallel(t)
+
+	var out strings.Builder
+	stdio := unix.NewStdio(
+		strings.NewReader("three\nsmall\npigs\n"),
+		&out,
+		io.Discard,
+	)
+	// cat | grep -v small | wc -l
+	err := unix.NewLine().Run(
+		context.Background(),
+		stdio,
+		cat.New(),
+		Command("sh", "-c", "grep -v small"),
+		wc.New().Lines(true),
+	)
+	require.NoError(t, err)
+	require.Equal(t, "2\n", out.String())
+}
+
+func TestExitCode(t *testing.T) {
+	requireSh(t)
+	test.Parallel(t)
+
+	testCases := []struct {
+		name string
+		exec Exec
+		code int
+	}{
+		{"exit 3", Command("sh", "-c", "exit 3"), 3},
+		{"kill -TERM", Command("sh", "-c", "kill -TERM $$"), 128 + 15},
+		{"not found", Command("gonix-does-not-exist"), pipe.NotFound},
+		{"not executable", Command("/"), pipe.NotExecutable},
+	}
+
+	for _, tt := range testCases {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			test.Parallel(t)
+			stdio := unix.NewStdio(strings.NewReader(""), io.Discard, io.Discard)
+			err := tt.exec.Run(context.Background(), stdio)
+			require.Error(t, err)
+			require.Equal(t, tt.code, pipe.FromError(err).Code)
+		})
+	}
+}
+
+func TestCancel(t *testing.T) {
+	requireSh(t)
+	test.Parallel(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	// sleep inherits stdout, so Run returns only if whole process group is killed
+	var out strings.Builder
+	stdio := unix.NewStdio(strings.NewReader(""), &out, io.Discard)
+	start := time.Now()
+	err := Command("sh", "-c", "sleep 10 & wait").Run(ctx, stdio)
+	require.Error(t, err)
+	require.Less(t, time.Since(start), 5*time.Second)
+	require.Equal(t, 128+9, pipe.FromError(err).Code)
+}
+
+func TestFromPath(t *testing.T) {
+	requireSh(t)
+	test.Parallel(t)
+
+	filter, err := FromPath("sh", []string{"-c", "echo $0"})
+	require.NoError(t, err)
+	var out strings.Builder
+	err = filter.Run(context.Background(), unix.NewStdio(nil, &out, io.Discard))
+	require.NoError(t, err)
+	require.Equal(t, "sh\n", out.String())
+
+	_, err = FromPath("gonix-does-not-exist", nil)
+	require.Error(t, err)
+	require.Equal(t, pipe.NotFound, pipe.FromError(err).Code)
+}
@@ -4,17 +4,23 @@ go 1.20
 
 require (
 	github.com/benhoyt/goawk v1.21.0
+	github.com/cespare/xxhash/v2 v2.2.0
 	github.com/gomoni/gio v0.0.0-20230206214735-ff72054e35d2
+	github.com/itchyny/gojq v0.12.13
 	github.com/spf13/pflag v1.0.5
 	github.com/stretchr/testify v1.8.1
 	go.uber.org/goleak v1.1.12
 	golang.org/x/crypto v0.0.0-20191011191535-87dc89f01550
 	golang.org/x/sync v0.0.0-20220819030929-7fc1605a5dde
+	golang.org/x/text v0.9.0
+	lukechampine.com/blake3 v1.2.1
 )
 
 require (
 	github.com/davecgh/go-spew v1.1.1 // indirect
+	github.com/itchyny/timefmt-go v0.1.5 // indirect
+	github.com/klauspost/cpuid/v2 v2.0.9 // indirect
 	github.com/pmezard/go-difflib v1.0.0 // indirect
-	golang.org/x/sys v0.0.0-20210510120138-977fb7262007 // indirect
+	golang.org/x/sys v0.8.0 // indirect
 	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
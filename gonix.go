@@ -0,0 +1,146 @@
+// Copyright 2023 Michal Vyskocil. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+/*
+gonix is a registry of gonix commands. Each command package registers itself
+in its init function, so importing a package, like
+github.com/gomoni/gonix/cat makes the cat available in Default registry.
+
+	cat, err := gonix.New("cat", []string{"-n"})
+
+The busybox-like cmd/gonix, sh.Sh and other tools can then enumerate and
+construct commands the same way.
+*/
+package gonix
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/gomoni/gio/pipe"
+	"github.com/gomoni/gio/unix"
+	"github.com/gomoni/gonix/sh"
+)
+
+// Cmd describes a command
+type Cmd struct {
+	Name  string                                   // Name is the command name like cat
+	Usage string                                   // Usage is a one line description of the command
+	New   func(argv []string) (unix.Filter, error) // New builds a filter from argv except the command name
+}
+
+// Registry maps command names to Cmd, it is safe for a concurrent use
+type Registry struct {
+	mu   sync.RWMutex
+	cmds map[string]Cmd
+}
+
+func NewRegistry() *Registry {
+	return &Registry{cmds: make(map[string]Cmd)}
+}
+
+// Default is the registry used by command packages
+var Default = NewRegistry()
+
+// Register adds the command to registry. It panics if the name is empty, New is nil or if
+// the command is registered twice.
+func (r *Registry) Register(cmd Cmd) {
+	if cmd.Name == "" {
+		panic("gonix: Register with an empty name")
+	}
+	if cmd.New == nil {
+		panic(fmt.Sprintf("gonix: Register %q with a nil New", cmd.Name))
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, dup := r.cmds[cmd.Name]; dup {
+		panic(fmt.Sprintf("gonix: Register called twice for %q", cmd.Name))
+	}
+	r.cmds[cmd.Name] = cmd
+}
+
+// Lookup returns the command registered under the name
+func (r *Registry) Lookup(name string) (Cmd, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	cmd, ok := r.cmds[name]
+	return cmd, ok
+}
+
+// Names returns sorted names of all registered commands
+func (r *Registry) Names() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	ret := make([]string, 0, len(r.cmds))
+	for name := range r.cmds {
+		ret = append(ret, name)
+	}
+	sort.Strings(ret)
+	return ret
+}
+
+// Cmds returns all registered commands sorted by a name
+func (r *Registry) Cmds() []Cmd {
+	names := r.Names()
+	ret := make([]Cmd, 0, len(names))
+	for _, name := range names {
+		cmd, _ := r.Lookup(name)
+		ret = append(ret, cmd)
+	}
+	return ret
+}
+
+// New builds a filter from argv, unknown name returns pipe.NotFound error
+func (r *Registry) New(name string, argv []string) (unix.Filter, error) {
+	cmd, ok := r.Lookup(name)
+	if !ok {
+		return nil, pipe.NewErrorf(pipe.NotFound, "%s: command not found", name)
+	}
+	return cmd.New(argv)
+}
+
+// Builtins returns all registered commands in a format suitable for sh.New
+func (r *Registry) Builtins() sh.Builtins {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	ret := make(sh.Builtins, len(r.cmds))
+	for name, cmd := range r.cmds {
+		ret[name] = cmd.New
+	}
+	return ret
+}
+
+// Register adds the command to the Default registry
+func Register(cmd Cmd) {
+	Default.Register(cmd)
+}
+
+// Lookup returns the command from the Default registry
+func Lookup(name string) (Cmd, bool) {
+	return Default.Lookup(name)
+}
+
+// Names returns sorted names of the Default registry
+func Names() []string {
+	return Default.Names()
+}
+
+// New builds a filter from the Default registry
+func New(name string, argv []string) (unix.Filter, error) {
+	return Default.New(name, argv)
+}
+
+// FromArgs adapts a FromArgs method returning a concrete type to Cmd.New
+//
+//	gonix.FromArgs(cat.New().FromArgs)
+func FromArgs[F unix.Filter](fromArgs func([]string) (F, error)) func([]string) (unix.Filter, error) {
+	return func(argv []string) (unix.Filter, error) {
+		filter, err := fromArgs(argv)
+		if err != nil {
+			return nil, err
+		}
+		return filter, nil
+	}
+}
@@ -0,0 +1,71 @@
+// Copyright 2023 Michal Vyskocil. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package gonix_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/gomoni/gio/pipe"
+	"github.com/gomoni/gio/unix"
+	. "github.com/gomoni/gonix"
+	"github.com/gomoni/gonix/internal/test"
+	"github.com/gomoni/gonix/sh"
+	"github.com/stretchr/testify/require"
+
+	_ "github.com/gomoni/gonix/cat"
+	_ "github.com/gomoni/gonix/wc"
+)
+
+func TestRegistry(t *testing.T) {
+	test.Parallel(t)
+
+	r := NewRegistry()
+	r.Register(Cmd{Name: "b", Usage: "b usage", New: func([]string) (unix.Filter, error) { return nil, nil }})
+	r.Register(Cmd{Name: "a", Usage: "a usage", New: func([]string) (unix.Filter, error) { return nil, nil }})
+
+	require.Equal(t, []string{"a", "b"}, r.Names())
+	cmd, ok := r.Lookup("a")
+	require.True(t, ok)
+	require.Equal(t, "a usage", cmd.Usage)
+	_, ok = r.Lookup("c")
+	require.False(t, ok)
+	require.Len(t, r.Cmds(), 2)
+	require.Len(t, r.Builtins(), 2)
+
+	_, err := r.New("c", nil)
+	require.Error(t, err)
+	require.Equal(t, pipe.NotFound, pipe.FromError(err).Code)
+
+	require.Panics(t, func() {
+		r.Register(Cmd{Name: "a", New: func([]string) (unix.Filter, error) { return nil, nil }})
+	})
+	require.Panics(t, func() {
+		r.Register(Cmd{Name: "", New: func([]string) (unix.Filter, error) { return nil, nil }})
+	})
+	require.Panics(t, func() {
+		r.Register(Cmd{Name: "d"})
+	})
+}
+
+func TestDefault(t *testing.T) {
+	test.Parallel(t)
+
+	require.Subset(t, Names(), []string{"cat", "wc"})
+
+	_, err := New("wc", []string{"--unknown"})
+	require.Error(t, err)
+
+	var out strings.Builder
+	stdio := unix.NewStdio(
+		strings.NewReader("three\nsmall\npigs\n"),
+		&out,
+		&out,
+	)
+	err = sh.New(Default.Builtins(), nil).Run(context.Background(), stdio, "cat | wc -l")
+	require.NoError(t, err)
+	require.Equal(t, "3\n", out.String())
+}
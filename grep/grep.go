@@ -0,0 +1,454 @@
+// Copyright 2023 Michal Vyskocil. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+/*
+grep prints lines that match patterns
+
+	-E/--extended-regexp      PATTERNS are extended regular expressions (Go RE2 syntax)
+	-F/--fixed-strings        PATTERNS are strings
+	-G/--basic-regexp         PATTERNS are basic regular expressions (default)
+	-e/--regexp PATTERNS      use PATTERNS for matching
+	-i/--ignore-case          ignore case distinctions in patterns and data
+	-v/--invert-match         select non-matching lines
+	-w/--word-regexp          match only whole words
+	-x/--line-regexp          match only whole lines
+	-c/--count                print only a count of selected lines per FILE
+	-l/--files-with-matches   print only names of FILEs with selected lines
+	-q/--quiet                suppress all normal output
+	-n/--line-number          print line number with output lines
+	-o/--only-matching        show only nonempty parts of lines that match
+	-H/--with-filename        print file name with output lines
+	-h/--no-filename          suppress the file name prefix on output
+	-A/--after-context NUM    print NUM lines of trailing context
+	-B/--before-context NUM   print NUM lines of leading context
+	-C/--context NUM          print NUM lines of output context
+
+Many fixed strings are searched via Aho-Corasick algorithm in a single pass.
+
+Exit status is 0 if a line is selected, 1 if no lines were selected and 2 if an
+error occurred. The error 1 has no message.
+*/
+package grep
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"strings"
+
+	"github.com/gomoni/gio/pipe"
+	"github.com/gomoni/gio/unix"
+	"github.com/gomoni/gonix"
+	"github.com/gomoni/gonix/internal"
+	"github.com/gomoni/gonix/internal/dbg"
+	"github.com/spf13/pflag"
+)
+
+type syntax int
+
+const (
+	basic    syntax = 0
+	extended syntax = 1
+	fixed    syntax = 2
+)
+
+type Grep struct {
+	debug            bool
+	patterns         []string
+	syntax           syntax
+	ignoreCase       bool
+	invert           bool
+	wordRegexp       bool
+	lineRegexp       bool
+	count            bool
+	filesWithMatches bool
+	quiet            bool
+	lineNumber       bool
+	onlyMatching     bool
+	withFilename     bool
+	noFilename       bool
+	before           int
+	after            int
+	fsys             fs.FS
+	files            []string
+}
+
+func New(patterns ...string) Grep {
+	return Grep{patterns: patterns}
+}
+
+func init() {
+	gonix.Register(gonix.Cmd{
+		Name:  "grep",
+		Usage: "print lines that match patterns",
+		New:   gonix.FromArgs(New().FromArgs),
+	})
+}
+
+// FromArgs build a Grep from standard argv except the command name (os.Argv[1:])
+func (c Grep) FromArgs(argv []string) (Grep, error) {
+	flag := pflag.FlagSet{}
+
+	var ext, fix, bre bool
+	flag.BoolVarP(&ext, "extended-regexp", "E", false, "PATTERNS are extended regular expressions")
+	flag.BoolVarP(&fix, "fixed-strings", "F", false, "PATTERNS are strings")
+	flag.BoolVarP(&bre, "basic-regexp", "G", false, "PATTERNS are basic regular expressions")
+	regexps := flag.StringArrayP("regexp", "e", nil, "use PATTERNS for matching")
+	flag.BoolVarP(&c.ignoreCase, "ignore-case", "i", false, "ignore case distinctions in patterns and data")
+	flag.BoolVarP(&c.invert, "invert-match", "v", false, "select non-matching lines")
+	flag.BoolVarP(&c.wordRegexp, "word-regexp", "w", false, "match only whole words")
+	flag.BoolVarP(&c.lineRegexp, "line-regexp", "x", false, "match only whole lines")
+	flag.BoolVarP(&c.count, "count", "c", false, "print only a count of selected lines per FILE")
+	flag.BoolVarP(&c.filesWithMatches, "files-with-matches", "l", false, "print only names of FILEs with selected lines")
+	flag.BoolVarP(&c.quiet, "quiet", "q", false, "suppress all normal output")
+	flag.BoolVar(&c.quiet, "silent", false, "suppress all normal output")
+	flag.BoolVarP(&c.lineNumber, "line-number", "n", false, "print line number with output lines")
+	flag.BoolVarP(&c.onlyMatching, "only-matching", "o", false, "show only nonempty parts of lines that match")
+	flag.BoolVarP(&c.withFilename, "with-filename", "H", false, "print file name with output lines")
+	flag.BoolVarP(&c.noFilename, "no-filename", "h", false, "suppress the file name prefix on output")
+	flag.IntVarP(&c.after, "after-context", "A", 0, "print NUM lines of trailing context")
+	flag.IntVarP(&c.before, "before-context", "B", 0, "print NUM lines of leading context")
+	contextLines := flag.IntP("context", "C", 0, "print NUM lines of output context")
+
+	err := flag.Parse(argv)
+	if err != nil {
+		return Grep{}, pipe.NewErrorf(2, "grep: parsing failed: %w", err)
+	}
+
+	switch {
+	case fix:
+		c.syntax = fixed
+	case ext:
+		c.syntax = extended
+	default:
+		c.syntax = basic
+	}
+
+	if flag.Changed("context") {
+		if !flag.Changed("after-context") {
+			c.after = *contextLines
+		}
+		if !flag.Changed("before-context") {
+			c.before = *contextLines
+		}
+	}
+	if c.after < 0 || c.before < 0 {
+		return Grep{}, pipe.NewErrorf(2, "grep: invalid context length argument")
+	}
+
+	args := flag.Args()
+	patterns := *regexps
+	if !flag.Changed("regexp") {
+		if len(args) == 0 {
+			return Grep{}, pipe.NewErrorf(2, "grep: missing pattern")
+		}
+		patterns = args[:1]
+		args = args[1:]
+	}
+	c.patterns = nil
+	for _, p := range patterns {
+		c.patterns = append(c.patterns, strings.Split(p, "\n")...)
+	}
+
+	_, err = c.compile()
+	if err != nil {
+		return Grep{}, pipe.NewErrorf(2, "grep: %w", err)
+	}
+
+	if len(args) > 0 {
+		c.files = args
+	}
+	return c, nil
+}
+
+// Files are input files, where - denotes stdin
+func (c Grep) Files(f ...string) Grep {
+	c.files = append(c.files, f...)
+	return c
+}
+
+// FS sets a filesystem used to open files, nil means the OS filesystem
+func (c Grep) FS(fsys fs.FS) Grep {
+	c.fsys = fsys
+	return c
+}
+
+// Patterns adds patterns, line matches if any pattern matches
+func (c Grep) Patterns(p ...string) Grep {
+	c.patterns = append(c.patterns, p...)
+	return c
+}
+
+// Extended interprets patterns as Go RE2 regular expressions
+func (c Grep) Extended(b bool) Grep {
+	if b {
+		c.syntax = extended
+	} else {
+		c.syntax = basic
+	}
+	return c
+}
+
+// Fixed interprets patterns as fixed strings
+func (c Grep) Fixed(b bool) Grep {
+	if b {
+		c.syntax = fixed
+	} else {
+		c.syntax = basic
+	}
+	return c
+}
+
+func (c Grep) IgnoreCase(b bool) Grep {
+	c.ignoreCase = b
+	return c
+}
+
+// Invert selects non-matching lines
+func (c Grep) Invert(b bool) Grep {
+	c.invert = b
+	return c
+}
+
+// WordRegexp matches only whole words
+func (c Grep) WordRegexp(b bool) Grep {
+	c.wordRegexp = b
+	return c
+}
+
+// LineRegexp matches only whole lines
+func (c Grep) LineRegexp(b bool) Grep {
+	c.lineRegexp = b
+	return c
+}
+
+// Count prints only a count of selected lines
+func (c Grep) Count(b bool) Grep {
+	c.count = b
+	return c
+}
+
+// FilesWithMatches prints only names of files with selected lines
+func (c Grep) FilesWithMatches(b bool) Grep {
+	c.filesWithMatches = b
+	return c
+}
+
+// Quiet prints nothing, only exit status matters
+func (c Grep) Quiet(b bool) Grep {
+	c.quiet = b
+	return c
+}
+
+func (c Grep) LineNumber(b bool) Grep {
+	c.lineNumber = b
+	return c
+}
+
+// OnlyMatching prints only matched parts of lines
+func (c Grep) OnlyMatching(b bool) Grep {
+	c.onlyMatching = b
+	return c
+}
+
+// WithFilename prints file name even for a single file
+func (c Grep) WithFilename(b bool) Grep {
+	c.withFilename = b
+	return c
+}
+
+// NoFilename never prints file name
+func (c Grep) NoFilename(b bool) Grep {
+	c.noFilename = b
+	return c
+}
+
+// Context prints n lines before and after each selected line
+func (c Grep) Context(n int) Grep {
+	c.before = n
+	c.after = n
+	return c
+}
+
+func (c Grep) BeforeContext(n int) Grep {
+	c.before = n
+	return c
+}
+
+func (c Grep) AfterContext(n int) Grep {
+	c.after = n
+	return c
+}
+
+func (c Grep) SetDebug(debug bool) Grep {
+	c.debug = debug
+	return c
+}
+
+func (c Grep) Run(ctx context.Context, stdio unix.StandardIO) error {
+	debug := dbg.Logger(c.debug, "grep", stdio.Stderr())
+	debug.Printf("c=%+v", c)
+
+	m, err := c.compile()
+	if err != nil {
+		return pipe.NewErrorf(2, "grep: %w", err)
+	}
+
+	out := bufio.NewWriter(stdio.Stdout())
+	g := &grep{
+		c:            c,
+		m:            m,
+		out:          out,
+		withFilename: (len(c.files) > 1 || c.withFilename) && !c.noFilename,
+	}
+	if c.onlyMatching {
+		g.c.before, g.c.after = 0, 0
+	}
+
+	fun := func(ctx context.Context, stdio unix.StandardIO, _ int, name string) error {
+		if c.quiet && g.selected > 0 {
+			return nil
+		}
+		err := g.file(ctx, stdio.Stdin(), displayName(name))
+		if ferr := out.Flush(); err == nil {
+			err = ferr
+		}
+		if err != nil {
+			return internal.NewError(2, fmt.Errorf("grep: %s: %w", displayName(name), err))
+		}
+		return nil
+	}
+
+	err = internal.NewRunFiles(c.files, stdio, fun).FS(c.fsys).Do(ctx)
+	debug.Printf("selected=%d, err=%v", g.selected, err)
+	switch {
+	case err != nil && c.quiet && g.selected > 0:
+		return nil
+	case err != nil:
+		return internal.NewError(2, errors.Unwrap(err))
+	case g.selected == 0:
+		return pipe.NewError(1, nil)
+	}
+	return nil
+}
+
+// grep is a state of a single run
+type grep struct {
+	c            Grep
+	m            matcher
+	out          *bufio.Writer
+	withFilename bool
+	selected     int
+	printed      bool // any line was printed, so the group separator -- is needed
+	lastFile     string
+	lastLine     int64
+}
+
+type line struct {
+	no   int64
+	text []byte
+}
+
+func (g *grep) file(ctx context.Context, in io.Reader, name string) error {
+	c := g.c
+	r := bufio.NewReaderSize(in, 64*1024)
+	var no int64
+	var count int
+	var after int
+	var before []line
+
+	for {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		text, err := r.ReadBytes('\n')
+		if len(text) > 0 {
+			text = trimNewline(text)
+			no++
+
+			if g.m.match(text) != c.invert {
+				count++
+				g.selected++
+				switch {
+				case c.quiet:
+					return nil
+				case c.filesWithMatches:
+					fmt.Fprintf(g.out, "%s\n", name)
+					return nil
+				case c.count:
+				case c.onlyMatching:
+					if !c.invert {
+						for _, loc := range g.m.findAll(text) {
+							g.print(name, ':', no, text[loc[0]:loc[1]])
+						}
+					}
+				default:
+					for _, l := range before {
+						g.print(name, '-', l.no, l.text)
+					}
+					before = before[:0]
+					g.print(name, ':', no, text)
+					after = c.after
+				}
+			} else if after > 0 {
+				g.print(name, '-', no, text)
+				after--
+			} else if c.before > 0 {
+				if len(before) == c.before {
+					before = append(before[:0], before[1:]...)
+				}
+				before = append(before, line{no: no, text: text})
+			}
+		}
+		if errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return err
+		}
+	}
+
+	if c.count && !c.quiet && !c.filesWithMatches {
+		if g.withFilename {
+			fmt.Fprintf(g.out, "%s:", name)
+		}
+		fmt.Fprintf(g.out, "%d\n", count)
+	}
+	return nil
+}
+
+func (g *grep) print(name string, sep byte, no int64, text []byte) {
+	if (g.c.before > 0 || g.c.after > 0) && g.printed && (name != g.lastFile || no != g.lastLine+1) {
+		g.out.WriteString("--\n")
+	}
+	g.printed = true
+	g.lastFile = name
+	g.lastLine = no
+
+	if g.withFilename {
+		g.out.WriteString(name)
+		g.out.WriteByte(sep)
+	}
+	if g.c.lineNumber {
+		fmt.Fprintf(g.out, "%d%c", no, sep)
+	}
+	g.out.Write(text)
+	g.out.WriteByte('\n')
+}
+
+func trimNewline(text []byte) []byte {
+	if text[len(text)-1] == '\n' {
+		return text[:len(text)-1]
+	}
+	return text
+}
+
+func displayName(name string) string {
+	if name == "" || name == "-" {
+		return "(standard input)"
+	}
+	return name
+}
@@ -0,0 +1,270 @@
+// Copyright 2023 Michal Vyskocil. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package grep_test
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/gomoni/gio/pipe"
+	"github.com/gomoni/gio/unix"
+	. "github.com/gomoni/gonix/grep"
+	"github.com/gomoni/gonix/internal/test"
+	"github.com/stretchr/testify/require"
+)
+
+const pigs = "three\nsmall\npigs\n"
+
+func TestGrep(t *testing.T) {
+	test.Parallel(t)
+	testCases := []test.Case[Grep]{
+		{
+			Name:     "basic",
+			Filter:   New("s.a"),
+			FromArgs: fromArgs(t, []string{"s.a"}),
+			Input:    pigs,
+			Expected: "small\n",
+		},
+		{
+			Name:     "basic \\(\\|\\)",
+			Filter:   New(`\(thr\|pi\)`),
+			FromArgs: fromArgs(t, []string{`\(thr\|pi\)`}),
+			Input:    pigs,
+			Expected: "three\npigs\n",
+		},
+		{
+			Name:     "basic literal (|)",
+			Filter:   New(`(a|b)`),
+			FromArgs: fromArgs(t, []string{`(a|b)`}),
+			Input:    "a\n(a|b)\n",
+			Expected: "(a|b)\n",
+		},
+		{
+			Name:     "-E",
+			Filter:   New(`^(thr|pi)`).Extended(true),
+			FromArgs: fromArgs(t, []string{"-E", `^(thr|pi)`}),
+			Input:    pigs,
+			Expected: "three\npigs\n",
+		},
+		{
+			Name:     "-F",
+			Filter:   New(`.`).Fixed(true),
+			FromArgs: fromArgs(t, []string{"-F", "."}),
+			Input:    "a\n.\n",
+			Expected: ".\n",
+		},
+		{
+			Name:     "-F -e -e",
+			Filter:   New(`ee`, "ig").Fixed(true),
+			FromArgs: fromArgs(t, []string{"-F", "-e", "ee", "-e", "ig"}),
+			Input:    pigs,
+			Expected: "three\npigs\n",
+		},
+		{
+			Name:     "-F -o -i aho-corasick",
+			Filter:   New("A", "AB", "bc", "c").Fixed(true).OnlyMatching(true).IgnoreCase(true),
+			FromArgs: fromArgs(t, []string{"-Foi", "-e", "A", "-e", "AB", "-e", "bc", "-e", "c"}),
+			Input:    "xabcx\nxCx\nnothing\n",
+			Expected: "ab\nc\nC\n",
+		},
+		{
+			Name:     "-i",
+			Filter:   New("SMALL").IgnoreCase(true),
+			FromArgs: fromArgs(t, []string{"-i", "SMALL"}),
+			Input:    pigs,
+			Expected: "small\n",
+		},
+		{
+			Name:     "-v",
+			Filter:   New("small").Invert(true),
+			FromArgs: fromArgs(t, []string{"-v", "small"}),
+			Input:    pigs,
+			Expected: "three\npigs\n",
+		},
+		{
+			Name:     "-c",
+			Filter:   New("s").Count(true),
+			FromArgs: fromArgs(t, []string{"-c", "s"}),
+			Input:    pigs,
+			Expected: "2\n",
+		},
+		{
+			Name:     "-n",
+			Filter:   New("s").LineNumber(true),
+			FromArgs: fromArgs(t, []string{"-n", "s"}),
+			Input:    pigs,
+			Expected: "2:small\n3:pigs\n",
+		},
+		{
+			Name:     "-o -E",
+			Filter:   New("[aeiou]+").Extended(true).OnlyMatching(true),
+			FromArgs: fromArgs(t, []string{"-oE", "[aeiou]+"}),
+			Input:    pigs,
+			Expected: "ee\na\ni\n",
+		},
+		{
+			Name:     "-w",
+			Filter:   New("pig").WordRegexp(true),
+			FromArgs: fromArgs(t, []string{"-w", "pig"}),
+			Input:    "pigs\na pig\n",
+			Expected: "a pig\n",
+		},
+		{
+			Name:     "-x -F",
+			Filter:   New("pig").LineRegexp(true).Fixed(true),
+			FromArgs: fromArgs(t, []string{"-xF", "pig"}),
+			Input:    "pigs\npig\n",
+			Expected: "pig\n",
+		},
+		{
+			Name:     "-l",
+			Filter:   New("s").FilesWithMatches(true),
+			FromArgs: fromArgs(t, []string{"-l", "s"}),
+			Input:    pigs,
+			Expected: "(standard input)\n",
+		},
+		{
+			Name:     "-C 1 -n",
+			Filter:   New("^[0-9]5$").Extended(true).Context(1).LineNumber(true),
+			FromArgs: fromArgs(t, []string{"-E", "-C", "1", "-n", "^[0-9]5$"}),
+			Input:    "01\n05\n10\n15\n20\n25\n30\n",
+			Expected: "1-01\n2:05\n3-10\n4:15\n5-20\n6:25\n7-30\n",
+		},
+		{
+			Name:     "-A 1 -B 0",
+			Filter:   New("5").AfterContext(1),
+			FromArgs: fromArgs(t, []string{"-A", "1", "5"}),
+			Input:    "05\n10\n20\n25\n30\n",
+			Expected: "05\n10\n--\n25\n30\n",
+		},
+		{
+			Name:     "-B 2",
+			Filter:   New("pigs").BeforeContext(2),
+			FromArgs: fromArgs(t, []string{"-B2", "pigs"}),
+			Input:    "one\n" + pigs,
+			Expected: pigs,
+		},
+	}
+	test.RunAll(t, testCases)
+}
+
+func TestGrepFiles(t *testing.T) {
+	test.Parallel(t)
+	pigsFile := test.Testdata(t, "three-small-pigs")
+
+	testCases := []struct {
+		name     string
+		filter   Grep
+		expected string
+	}{
+		{
+			name:     "two files",
+			filter:   New("pigs").Files(pigsFile, "-"),
+			expected: pigsFile + ":pigs\n(standard input):pigs\n",
+		},
+		{
+			name:     "-h",
+			filter:   New("pigs").Files(pigsFile, "-").NoFilename(true),
+			expected: "pigs\npigs\n",
+		},
+		{
+			name:     "-H -n",
+			filter:   New("pigs").Files(pigsFile).WithFilename(true).LineNumber(true),
+			expected: pigsFile + ":3:pigs\n",
+		},
+		{
+			name:     "-c",
+			filter:   New("s").Files(pigsFile, "-").Count(true),
+			expected: pigsFile + ":2\n(standard input):1\n",
+		},
+		{
+			name:     "-l",
+			filter:   New("three").Files(pigsFile, "-").FilesWithMatches(true),
+			expected: pigsFile + "\n",
+		},
+	}
+
+	for _, tt := range testCases {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			test.Parallel(t)
+			var out strings.Builder
+			stdio := unix.NewStdio(
+				strings.NewReader("pigs\n"),
+				&out,
+				os.Stderr,
+			)
+			err := tt.filter.Run(context.Background(), stdio)
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, out.String())
+		})
+	}
+}
+
+func TestExitCode(t *testing.T) {
+	test.Parallel(t)
+	pigsFile := test.Testdata(t, "three-small-pigs")
+
+	testCases := []struct {
+		name     string
+		filter   Grep
+		code     int
+		expected string
+	}{
+		{
+			name:   "no match",
+			filter: New("wolf"),
+			code:   1,
+		},
+		{
+			name:   "-q",
+			filter: New("pigs").Quiet(true),
+		},
+		{
+			name:     "missing file",
+			filter:   New("pigs").Files("does-not-exist", pigsFile),
+			code:     2,
+			expected: pigsFile + ":pigs\n",
+		},
+		{
+			name:   "-q missing file",
+			filter: New("pigs").Quiet(true).Files(pigsFile, "does-not-exist"),
+		},
+	}
+
+	for _, tt := range testCases {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			test.Parallel(t)
+			var out strings.Builder
+			stdio := unix.NewStdio(strings.NewReader(pigs), &out, &strings.Builder{})
+			err := tt.filter.Run(context.Background(), stdio)
+			if tt.code == 0 {
+				require.NoError(t, err)
+			} else {
+				require.Error(t, err)
+				require.Equal(t, tt.code, pipe.FromError(err).Code)
+			}
+			require.Equal(t, tt.expected, out.String())
+		})
+	}
+
+	_, err := New().FromArgs([]string{"-E", "("})
+	require.Error(t, err)
+	require.Equal(t, 2, pipe.FromError(err).Code)
+	_, err = New().FromArgs([]string{})
+	require.Error(t, err)
+	require.Equal(t, 2, pipe.FromError(err).Code)
+}
+
+func fromArgs(t *testing.T, argv []string) Grep {
+	t.Helper()
+	n := New()
+	f, err := n.FromArgs(argv)
+	require.NoError(t, err)
+	return f
+}
@@ -0,0 +1,277 @@
+// Copyright 2023 Michal Vyskocil. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package grep
+
+import (
+	"bytes"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gomoni/gonix/internal"
+)
+
+// matcher finds patterns in a line
+type matcher interface {
+	// match reports if line contains any pattern
+	match(line []byte) bool
+	// findAll returns non-overlapping leftmost-longest matches in line
+	findAll(line []byte) [][]int
+}
+
+// always matches every line, but never finds anything, as an empty pattern does
+type always struct{}
+
+func (always) match([]byte) bool      { return true }
+func (always) findAll([]byte) [][]int { return nil }
+
+type regexpMatcher struct {
+	re *regexp.Regexp
+}
+
+func (m regexpMatcher) match(line []byte) bool {
+	return m.re.Match(line)
+}
+
+func (m regexpMatcher) findAll(line []byte) [][]int {
+	all := m.re.FindAllIndex(line, -1)
+	ret := all[:0]
+	for _, loc := range all {
+		if loc[0] != loc[1] {
+			ret = append(ret, loc)
+		}
+	}
+	return ret
+}
+
+// fixedMatcher finds one fixed string
+type fixedMatcher struct {
+	pattern []byte
+	fold    bool
+}
+
+func (m fixedMatcher) match(line []byte) bool {
+	if m.fold {
+		line = toLowerASCII(line)
+	}
+	return bytes.Contains(line, m.pattern)
+}
+
+func (m fixedMatcher) findAll(line []byte) [][]int {
+	if m.fold {
+		line = toLowerASCII(line)
+	}
+	var ret [][]int
+	for pos := 0; pos < len(line); {
+		idx := bytes.Index(line[pos:], m.pattern)
+		if idx == -1 {
+			break
+		}
+		ret = append(ret, []int{pos + idx, pos + idx + len(m.pattern)})
+		pos += idx + len(m.pattern)
+	}
+	return ret
+}
+
+// compile builds the best matcher for patterns
+func (c Grep) compile() (matcher, error) {
+	patterns := c.patterns
+	for _, p := range patterns {
+		if p == "" && !c.lineRegexp && !c.wordRegexp {
+			return always{}, nil
+		}
+	}
+
+	if c.syntax == fixed && !c.lineRegexp && !c.wordRegexp && (!c.ignoreCase || isASCII(patterns)) {
+		if c.ignoreCase {
+			lower := make([]string, len(patterns))
+			for idx, p := range patterns {
+				lower[idx] = string(toLowerASCII([]byte(p)))
+			}
+			patterns = lower
+		}
+		if len(patterns) == 1 {
+			return fixedMatcher{pattern: []byte(patterns[0]), fold: c.ignoreCase}, nil
+		}
+		return newAhoCorasick(patterns, c.ignoreCase), nil
+	}
+
+	var sb strings.Builder
+	if c.ignoreCase {
+		sb.WriteString("(?i)")
+	}
+	switch {
+	case c.lineRegexp:
+		sb.WriteString("^(?:")
+	case c.wordRegexp:
+		sb.WriteString(`\b(?:`)
+	default:
+		sb.WriteString("(?:")
+	}
+	for idx, p := range patterns {
+		if idx > 0 {
+			sb.WriteString("|")
+		}
+		sb.WriteString("(?:")
+		switch c.syntax {
+		case fixed:
+			sb.WriteString(regexp.QuoteMeta(p))
+		default:
+			sb.WriteString(internal.ToRE2(p, c.syntax == basic))
+		}
+		sb.WriteString(")")
+	}
+	switch {
+	case c.lineRegexp:
+		sb.WriteString(")$")
+	case c.wordRegexp:
+		sb.WriteString(`)\b`)
+	default:
+		sb.WriteString(")")
+	}
+
+	re, err := regexp.Compile(sb.String())
+	if err != nil {
+		return nil, err
+	}
+	re.Longest()
+	return regexpMatcher{re: re}, nil
+}
+
+func isASCII(patterns []string) bool {
+	for _, p := range patterns {
+		for i := 0; i < len(p); i++ {
+			if p[i] >= utf8.RuneSelf {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func toLowerASCII(s []byte) []byte {
+	ret := make([]byte, len(s))
+	for idx, b := range s {
+		if b >= 'A' && b <= 'Z' {
+			b += 'a' - 'A'
+		}
+		ret[idx] = b
+	}
+	return ret
+}
+
+// ahoCorasick finds many fixed strings in a single pass over the line
+type ahoCorasick struct {
+	nodes []acNode
+	fold  bool
+}
+
+type acNode struct {
+	children map[byte]int
+	fail     int
+	out      []int // lengths of patterns ending in this node
+}
+
+func newAhoCorasick(patterns []string, fold bool) *ahoCorasick {
+	ac := &ahoCorasick{
+		nodes: []acNode{{children: map[byte]int{}}},
+		fold:  fold,
+	}
+	for _, p := range patterns {
+		node := 0
+		for i := 0; i < len(p); i++ {
+			next, ok := ac.nodes[node].children[p[i]]
+			if !ok {
+				ac.nodes = append(ac.nodes, acNode{children: map[byte]int{}})
+				next = len(ac.nodes) - 1
+				ac.nodes[node].children[p[i]] = next
+			}
+			node = next
+		}
+		ac.nodes[node].out = append(ac.nodes[node].out, len(p))
+	}
+
+	// breadth first, so fail links of shorter prefixes are ready
+	queue := make([]int, 0, len(ac.nodes))
+	for _, child := range ac.nodes[0].children {
+		queue = append(queue, child)
+	}
+	for len(queue) > 0 {
+		node := queue[0]
+		queue = queue[1:]
+		for b, child := range ac.nodes[node].children {
+			fail := ac.nodes[node].fail
+			for fail != 0 && !ac.has(fail, b) {
+				fail = ac.nodes[fail].fail
+			}
+			if next, ok := ac.nodes[fail].children[b]; ok && next != child {
+				fail = next
+			}
+			ac.nodes[child].fail = fail
+			ac.nodes[child].out = append(ac.nodes[child].out, ac.nodes[fail].out...)
+			queue = append(queue, child)
+		}
+	}
+	return ac
+}
+
+func (ac *ahoCorasick) has(node int, b byte) bool {
+	_, ok := ac.nodes[node].children[b]
+	return ok
+}
+
+// scan calls fn for each match, stops if fn returns false
+func (ac *ahoCorasick) scan(line []byte, fn func(start, end int) bool) {
+	node := 0
+	for i, b := range line {
+		if ac.fold && b >= 'A' && b <= 'Z' {
+			b += 'a' - 'A'
+		}
+		for node != 0 && !ac.has(node, b) {
+			node = ac.nodes[node].fail
+		}
+		if next, ok := ac.nodes[node].children[b]; ok {
+			node = next
+		}
+		for _, length := range ac.nodes[node].out {
+			if !fn(i+1-length, i+1) {
+				return
+			}
+		}
+	}
+}
+
+func (ac *ahoCorasick) match(line []byte) bool {
+	var found bool
+	ac.scan(line, func(int, int) bool {
+		found = true
+		return false
+	})
+	return found
+}
+
+func (ac *ahoCorasick) findAll(line []byte) [][]int {
+	var all [][]int
+	ac.scan(line, func(start, end int) bool {
+		all = append(all, []int{start, end})
+		return true
+	})
+	sort.Slice(all, func(i, j int) bool {
+		if all[i][0] != all[j][0] {
+			return all[i][0] < all[j][0]
+		}
+		return all[i][1] > all[j][1]
+	})
+	var ret [][]int
+	pos := 0
+	for _, loc := range all {
+		if loc[0] >= pos {
+			ret = append(ret, loc)
+			pos = loc[1]
+		}
+	}
+	return ret
+}
@@ -2,34 +2,53 @@
 // Use of this source code is governed by a MIT
 // license that can be found in the LICENSE file.
 
+/*
+head prints the first part of files
+
+	-n/--lines N    print the first N lines, default 10
+	-n/--lines -N   print all but the last N lines
+	-c/--bytes N    print the first N bytes
+	-c/--bytes -N   print all but the last N bytes
+	-z              line delimiter is NUL, not newline
+	-q/-v           never or always print headers
+
+N may have a multiplier suffix like 1K or 1MiB, see internal.Byte.
+*/
 package head
 
 import (
+	"bufio"
 	"context"
+	"errors"
 	"fmt"
+	"io"
+	"io/fs"
 	"math"
-	"strconv"
 
 	"github.com/gomoni/gio/pipe"
 	"github.com/gomoni/gio/unix"
-	"github.com/gomoni/gonix/awk"
+	"github.com/gomoni/gonix"
 	"github.com/gomoni/gonix/internal"
 	"github.com/gomoni/gonix/internal/dbg"
 	"github.com/spf13/pflag"
-
-	_ "embed"
 )
 
-//go:embed head.awk
-var headAwk []byte
+type unit int
 
-//go:embed head_negative.awk
-var headNegative []byte
+const (
+	lines unit = 0
+	bytes unit = 1
+)
 
 type Head struct {
 	debug          bool
-	lines          int
+	unit           unit
+	count          int
 	zeroTerminated bool
+	quiet          bool
+	verbose        bool
+	files0From     string
+	fsys           fs.FS
 	files          []string
 }
 
@@ -37,6 +56,14 @@ func New() Head {
 	return Head{}
 }
 
+func init() {
+	gonix.Register(gonix.Cmd{
+		Name:  "head",
+		Usage: "output the first part of files",
+		New:   gonix.FromArgs(New().FromArgs),
+	})
+}
+
 func (c Head) FromArgs(argv []string) (Head, error) {
 	if len(argv) == 0 {
 		c = c.Lines(10)
@@ -45,21 +72,38 @@ func (c Head) FromArgs(argv []string) (Head, error) {
 
 	flag := pflag.FlagSet{}
 
-	var lines internal.Byte = internal.Byte(c.lines)
-	flag.VarP(&lines, "lines", "n", "print at least n lines, -n means everything except last n lines")
+	if c.count == 0 {
+		c.count = 10
+	}
+	var linesArg internal.Byte = internal.Byte(c.count)
+	flag.VarP(&linesArg, "lines", "n", "print at least n lines, -n means everything except last n lines")
+	var bytesArg internal.Byte
+	flag.VarP(&bytesArg, "bytes", "c", "print the first n bytes, -n means everything except last n bytes")
 
 	zeroTerminated := flag.BoolP("zero-terminated", "z", false, "line delimiter is NUL")
+	flag.BoolVarP(&c.quiet, "quiet", "q", false, "never print headers giving file names")
+	flag.BoolVar(&c.quiet, "silent", false, "same as --quiet")
+	flag.BoolVarP(&c.verbose, "verbose", "v", false, "always print headers giving file names")
+	flag.StringVar(&c.files0From, "files0-from", "", "read input from the files specified by NUL-terminated names in file F")
 
 	err := flag.Parse(argv)
 	if err != nil {
 		return Head{}, pipe.NewErrorf(1, "head: parsing failed: %w", err)
 	}
 	if len(flag.Args()) > 0 {
+		if c.files0From != "" {
+			return Head{}, pipe.NewErrorf(1, "head: %w", internal.ErrFiles0Operands(flag.Args()[0]))
+		}
 		c.files = flag.Args()
 	}
 
-	// TODO: deal with more than int64 lines
-	c.lines = int(math.Round(float64(lines)))
+	if flag.Changed("bytes") {
+		c.unit = bytes
+		c.count = count(bytesArg)
+	} else {
+		c.unit = lines
+		c.count = count(linesArg)
+	}
 	c.zeroTerminated = *zeroTerminated
 
 	return c, nil
@@ -71,8 +115,41 @@ func (c Head) Files(f ...string) Head {
 	return c
 }
 
-func (c Head) Lines(lines int) Head {
-	c.lines = lines
+// Files0From reads NUL terminated names of files from a file, - means stdin
+func (c Head) Files0From(name string) Head {
+	c.files0From = name
+	return c
+}
+
+// FS sets a filesystem used to open files, nil means the OS filesystem
+func (c Head) FS(fsys fs.FS) Head {
+	c.fsys = fsys
+	return c
+}
+
+// Lines prints the first n lines, negative n prints all but the last n lines
+func (c Head) Lines(n int) Head {
+	c.unit = lines
+	c.count = n
+	return c
+}
+
+// Bytes prints the first n bytes, negative n prints all but the last n bytes
+func (c Head) Bytes(n int) Head {
+	c.unit = bytes
+	c.count = n
+	return c
+}
+
+// Quiet never prints headers with file names
+func (c Head) Quiet(b bool) Head {
+	c.quiet = b
+	return c
+}
+
+// Verbose always prints headers with file names
+func (c Head) Verbose(b bool) Head {
+	c.verbose = b
 	return c
 }
 
@@ -87,60 +164,183 @@ func (c Head) SetDebug(debug bool) Head {
 }
 
 func (c Head) Run(ctx context.Context, stdio unix.StandardIO) error {
-	debug := dbg.Logger(c.debug, "cat", stdio.Stderr())
-	if c.lines == 0 {
+	debug := dbg.Logger(c.debug, "head", stdio.Stderr())
+	debug.Printf("head: unit=%d count=%d", c.unit, c.count)
+
+	var head func(context.Context, io.Reader, io.Writer) error
+	switch {
+	case c.count == 0:
+		head = func(context.Context, io.Reader, io.Writer) error { return nil }
+	case c.unit == bytes:
+		head = func(ctx context.Context, in io.Reader, out io.Writer) error {
+			if c.count >= 0 {
+				return firstBytes(in, out, int64(c.count))
+			}
+			return allButLastBytes(ctx, in, out, int64(-c.count))
+		}
+	default:
+		delim := byte('\n')
+		if c.zeroTerminated {
+			delim = 0
+		}
+		head = func(ctx context.Context, in io.Reader, out io.Writer) error {
+			if c.count > 0 {
+				return firstLines(ctx, in, out, c.count, delim)
+			}
+			return allButLastLines(ctx, in, out, -c.count, delim)
+		}
+	}
+
+	// a length of --files0-from list is not known in advance
+	headers := c.verbose || ((len(c.files) > 1 || c.files0From != "") && !c.quiet)
+	printed := false
+	runOne := func(ctx context.Context, stdio unix.StandardIO, _ int, name string) error {
+		if headers {
+			if printed {
+				fmt.Fprintln(stdio.Stdout())
+			}
+			fmt.Fprintf(stdio.Stdout(), "==> %s <==\n", displayName(name))
+		}
+		printed = true
+		err := head(ctx, stdio.Stdin(), stdio.Stdout())
+		if err != nil {
+			return internal.NewError(1, fmt.Errorf("head: fail to run: %w", err))
+		}
 		return nil
 	}
-	var src []byte
-	var lines int
-	if c.lines > 0 {
-		lines = c.lines
-		src = headAwk
-	} else {
-		lines = -1 * c.lines
-		src = headNegative
+
+	runFiles := internal.NewRunFiles(
+		c.files,
+		stdio,
+		runOne,
+	).FS(c.fsys).Files0From(c.files0From)
+	err := runFiles.Do(ctx)
+	if c.readsStdin() {
+		// head has all it needs, so stop the writer like SIGPIPE does
+		internal.CloseStdin(stdio.Stdin())
 	}
+	return err
+}
 
-	debug.Printf("head: src=`%s`", src)
-	debug.Printf("head: lines=%d", lines)
-	debug.Printf("head: zero-terminated=%t", c.zeroTerminated)
+func (c Head) readsStdin() bool {
+	if c.files0From != "" {
+		return c.files0From == "-"
+	}
+	files := c.files
+	if len(files) == 0 {
+		return true
+	}
+	for _, name := range files {
+		if name == "" || name == "-" {
+			return true
+		}
+	}
+	return false
+}
 
-	config := awk.NewConfig()
-	if c.zeroTerminated {
-		config.Vars = append(config.Vars, []string{"RS", "\x00"}...)
+// firstLines prints the first n lines and stops reading the input. Lines
+// are always terminated by a newline, like awk print does.
+func firstLines(ctx context.Context, in io.Reader, out io.Writer, n int, delim byte) error {
+	w := bufio.NewWriter(out)
+	var printed int
+	err := internal.Lines(ctx, in, delim, func(line []byte) (bool, error) {
+		w.Write(line)
+		err := w.WriteByte('\n')
+		printed++
+		return printed < n, err
+	})
+	if err != nil {
+		return err
 	}
-	config.Vars = append(config.Vars, []string{"lines", strconv.Itoa(lines)}...)
+	return w.Flush()
+}
 
-	prog, err := awk.Compile([]byte(src), config)
+// allButLastLines prints everything except the last n lines, which are kept
+// in a ring buffer
+func allButLastLines(ctx context.Context, in io.Reader, out io.Writer, n int, delim byte) error {
+	if n <= 0 {
+		// -count overflowed, all lines are among the last n
+		return nil
+	}
+	w := bufio.NewWriter(out)
+	ring := make([][]byte, 0, 64)
+	head := 0
+	err := internal.Lines(ctx, in, delim, func(line []byte) (bool, error) {
+		if len(ring) < n {
+			ring = append(ring, append([]byte(nil), line...))
+			return true, nil
+		}
+		w.Write(ring[head])
+		err := w.WriteByte('\n')
+		ring[head] = append(ring[head][:0], line...)
+		head++
+		if head == n {
+			head = 0
+		}
+		return true, err
+	})
 	if err != nil {
 		return err
 	}
+	return w.Flush()
+}
 
-	var head func(context.Context, unix.StandardIO, int, string) error
-	if len(c.files) <= 1 {
-		head = func(ctx context.Context, stdio unix.StandardIO, _ int, _ string) error {
-			err := prog.Run(ctx, stdio)
-			if err != nil {
-				return pipe.NewError(1, fmt.Errorf("head: fail to run: %w", err))
+// firstBytes prints the first n bytes
+func firstBytes(in io.Reader, out io.Writer, n int64) error {
+	_, err := io.CopyN(out, in, n)
+	if errors.Is(err, io.EOF) {
+		return nil
+	}
+	return err
+}
+
+// allButLastBytes prints everything except the last n bytes, which are kept
+// in memory until the end of input
+func allButLastBytes(ctx context.Context, in io.Reader, out io.Writer, n int64) error {
+	var buf []byte
+	var chunk [32 * 1024]byte
+	for {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		nr, err := in.Read(chunk[:])
+		buf = append(buf, chunk[:nr]...)
+		if int64(len(buf))-n > n {
+			keep := int64(len(buf)) - n
+			if _, err := out.Write(buf[:keep]); err != nil {
+				return err
 			}
-			return nil
+			buf = append(buf[:0], buf[keep:]...)
 		}
-	} else {
-		head = func(ctx context.Context, stdio unix.StandardIO, _ int, name string) error {
-			fmt.Fprintf(stdio.Stdout(), "==> %s <==\n", name)
-			err := prog.Run(ctx, stdio)
-			if err != nil {
-				return pipe.NewError(1, fmt.Errorf("head: fail to run: %w", err))
+		if errors.Is(err, io.EOF) {
+			// the input may have been shorter than 2n
+			if keep := int64(len(buf)) - n; keep > 0 {
+				_, err := out.Write(buf[:keep])
+				return err
 			}
-			fmt.Fprintln(stdio.Stdout())
 			return nil
+		} else if err != nil {
+			return err
 		}
 	}
+}
 
-	runFiles := internal.NewRunFiles(
-		c.files,
-		stdio,
-		head,
-	)
-	return runFiles.Do(ctx)
+// count rounds x to int, counts out of range are clamped, so a huge count
+// means everything and does not overflow
+func count(x internal.Byte) int {
+	f := math.Round(float64(x))
+	switch {
+	case f >= math.MaxInt:
+		return math.MaxInt
+	case f <= -math.MaxInt:
+		return -math.MaxInt
+	}
+	return int(f)
+}
+
+func displayName(name string) string {
+	if name == "" || name == "-" {
+		return "standard input"
+	}
+	return name
 }
@@ -5,10 +5,18 @@
 package head_test
 
 import (
+	"context"
+	"io"
+	"math"
+	"strings"
 	"testing"
+	"testing/fstest"
 
+	"github.com/gomoni/gio/unix"
+	"github.com/gomoni/gonix/cat"
 	. "github.com/gomoni/gonix/head"
 	"github.com/gomoni/gonix/internal/test"
+	"github.com/gomoni/gonix/pipeline"
 	"github.com/stretchr/testify/require"
 )
 
@@ -35,6 +43,19 @@ func TestHead(t *testing.T) {
 			Input:    "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12\n",
 			Expected: "1\n2\n",
 		},
+		{
+			Name:     "--lines -99999999999999999999",
+			Filter:   New().Lines(-math.MaxInt),
+			FromArgs: fromArgs(t, []string{"-n", "-99999999999999999999"}),
+			Input:    "a\nb\n",
+			Expected: "",
+		},
+		{
+			Name:     "Lines(math.MinInt)",
+			Filter:   New().Lines(math.MinInt),
+			Input:    "a\nb\n",
+			Expected: "",
+		},
 		{
 			Name:     "--lines 2 --zero-terminated",
 			Filter:   New().Lines(2).ZeroTerminated(true),
@@ -42,10 +63,118 @@ func TestHead(t *testing.T) {
 			Input:    "1\x002\x003\x004\x00",
 			Expected: "1\n2\n",
 		},
+		{
+			Name:     "--bytes 8",
+			Filter:   New().Bytes(8),
+			FromArgs: fromArgs(t, []string{"-c", "8"}),
+			Input:    "three\nsmall\npigs\n",
+			Expected: "three\nsm",
+		},
+		{
+			Name:     "--bytes -5",
+			Filter:   New().Bytes(-5),
+			FromArgs: fromArgs(t, []string{"--bytes", "-5"}),
+			Input:    "three\nsmall\npigs\n",
+			Expected: "three\nsmall\n",
+		},
+		{
+			Name:     "--bytes -5 shorter than 2n",
+			Filter:   New().Bytes(-5),
+			FromArgs: fromArgs(t, []string{"--bytes", "-5"}),
+			Input:    "abcdefgh",
+			Expected: "abc",
+		},
+		{
+			Name:     "--bytes -5 shorter than n",
+			Filter:   New().Bytes(-5),
+			FromArgs: fromArgs(t, []string{"--bytes", "-5"}),
+			Input:    "abc",
+			Expected: "",
+		},
+		{
+			Name:     "--bytes -5E",
+			Filter:   New().Bytes(-5 << 60),
+			FromArgs: fromArgs(t, []string{"--bytes", "-5E"}),
+			Input:    "abc",
+			Expected: "",
+		},
+		{
+			Name:     "--bytes 1K",
+			Filter:   New().Bytes(1024),
+			FromArgs: fromArgs(t, []string{"--bytes", "1K"}),
+			Input:    "three\x00small\x00pigs\x00",
+			Expected: "three\x00small\x00pigs\x00",
+		},
+		{
+			Name:     "--verbose",
+			Filter:   New().Lines(1).Verbose(true),
+			FromArgs: fromArgs(t, []string{"-v", "-n", "1"}),
+			Input:    "three\nsmall\npigs\n",
+			Expected: "==> standard input <==\nthree\n",
+		},
 	}
 	test.RunAll(t, testCases)
 }
 
+// endless never returns io.EOF
+type endless struct{}
+
+func (endless) Read(p []byte) (int, error) {
+	for i := range p {
+		p[i] = '\n'
+	}
+	return len(p), nil
+}
+
+func TestEarlyStop(t *testing.T) {
+	test.Parallel(t)
+	for _, head := range []Head{New().Lines(1), New().Bytes(1)} {
+		var out strings.Builder
+		err := head.Run(context.Background(), unix.NewStdio(endless{}, &out, io.Discard))
+		require.NoError(t, err)
+		require.Equal(t, "\n", out.String())
+	}
+}
+
+func TestBrokenPipe(t *testing.T) {
+	test.Parallel(t)
+	// endless | cat | head -n 1 finishes and the pipeline succeeds
+	var out strings.Builder
+	stdio := unix.NewStdio(endless{}, &out, io.Discard)
+	res := pipeline.NewLine().Pipefail(true).Run(context.Background(), stdio, cat.New(), New().Lines(1))
+	require.NoError(t, res.Err())
+	require.Equal(t, []int{pipeline.BrokenPipe, 0}, res.PipeStatus)
+	require.Equal(t, "\n", out.String())
+}
+
+func TestHeaders(t *testing.T) {
+	test.Parallel(t)
+	fsys := fstest.MapFS{
+		"a": {Data: []byte("a\nb\n")},
+		"b": {Data: []byte("c\n")},
+	}
+	testCases := []struct {
+		name     string
+		head     Head
+		expected string
+	}{
+		{"default", New().Lines(1), "==> a <==\na\n\n==> b <==\nc\n"},
+		{"quiet", New().Lines(1).Quiet(true), "a\nc\n"},
+		{"bytes", New().Bytes(1), "==> a <==\na\n==> b <==\nc"},
+		{"zero", New().Lines(0), "==> a <==\n\n==> b <==\n"},
+	}
+	for _, tt := range testCases {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			test.Parallel(t)
+			var out strings.Builder
+			err := tt.head.FS(fsys).Files("a", "b").Run(context.Background(), unix.NewStdio(nil, &out, io.Discard))
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, out.String())
+		})
+	}
+}
+
 func fromArgs(t *testing.T, argv []string) Head {
 	t.Helper()
 	n := New()
@@ -0,0 +1,31 @@
+// Copyright 2023 Michal Vyskocil. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package internal
+
+import (
+	"errors"
+	"io"
+	"syscall"
+)
+
+// ErrBrokenPipe is returned to a writer when a reader does not need more
+// input, like head after it printed N lines. It is syscall.EPIPE, so it is
+// the same error a write to a real closed pipe returns.
+var ErrBrokenPipe error = syscall.EPIPE
+
+// IsBrokenPipe is true if err was caused by a write to a closed pipe. It is
+// not a failure of a writer, but a signal to stop, like SIGPIPE in a shell.
+func IsBrokenPipe(err error) bool {
+	return errors.Is(err, syscall.EPIPE) || errors.Is(err, io.ErrClosedPipe)
+}
+
+// CloseStdin closes a pipe reader with ErrBrokenPipe, so the writer at the
+// other end of the pipe stops instead of producing output nobody reads.
+// Readers which are not pipes are left untouched.
+func CloseStdin(stdin io.Reader) {
+	if r, ok := stdin.(interface{ CloseWithError(error) error }); ok {
+		r.CloseWithError(ErrBrokenPipe)
+	}
+}
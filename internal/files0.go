@@ -0,0 +1,84 @@
+// Copyright 2023 Michal Vyskocil. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package internal
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+)
+
+// Files0 reads NUL terminated file names one by one, like --files0-from=F
+// does, so an endless list like find -print0 output can be processed.
+type Files0 struct {
+	from   string
+	r      *bufio.Reader
+	closer io.Closer
+	line   int
+}
+
+// InvalidNameError is an empty name or - when the list is read from stdin,
+// it is reported and the caller is expected to continue with the next name
+type InvalidNameError struct {
+	msg string
+}
+
+func (e *InvalidNameError) Error() string {
+	return e.msg
+}
+
+// OpenFiles0 opens the list from, where - means stdin
+func OpenFiles0(from string, fsys fs.FS, stdin io.Reader) (*Files0, error) {
+	f := &Files0{from: from}
+	if from == "-" {
+		f.r = bufio.NewReader(stdin)
+		return f, nil
+	}
+	file, err := Open(fsys, from)
+	if err != nil {
+		return nil, fmt.Errorf("cannot open %q for reading: %w", from, err)
+	}
+	f.r = bufio.NewReader(file)
+	f.closer = file
+	return f, nil
+}
+
+// Next returns the next name or io.EOF at the end of the list. Invalid names
+// return *InvalidNameError, other errors are failures to read the list.
+func (f *Files0) Next() (string, error) {
+	name, err := f.r.ReadString(0)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return "", fmt.Errorf("%s: read error: %w", f.from, err)
+	}
+	if len(name) > 0 && name[len(name)-1] == 0 {
+		name = name[:len(name)-1]
+	} else if name == "" {
+		return "", io.EOF
+	}
+	f.line++
+
+	switch {
+	case name == "":
+		return "", &InvalidNameError{msg: fmt.Sprintf("%s:%d: invalid zero-length file name", f.from, f.line)}
+	case name == "-" && f.from == "-":
+		return "", &InvalidNameError{msg: "when reading file names from stdin, no file name of '-' allowed"}
+	}
+	return name, nil
+}
+
+// Close closes the list unless it is stdin
+func (f *Files0) Close() error {
+	if f.closer == nil {
+		return nil
+	}
+	return f.closer.Close()
+}
+
+// ErrFiles0Operands is an error of file operand combined with --files0-from
+func ErrFiles0Operands(operand string) error {
+	return fmt.Errorf("extra operand %q\nfile operands cannot be combined with --files0-from", operand)
+}
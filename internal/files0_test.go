@@ -0,0 +1,127 @@
+// Copyright 2023 Michal Vyskocil. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+package internal_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/gomoni/gio/pipe"
+	"github.com/gomoni/gio/unix"
+	. "github.com/gomoni/gonix/internal"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFiles0(t *testing.T) {
+	t.Parallel()
+	fsys := fstest.MapFS{
+		"list": {Data: []byte("a\x00b c\x00-\x00")},
+	}
+
+	testCases := []struct {
+		name     string
+		from     string
+		stdin    string
+		expected []string
+		invalid  []string
+	}{
+		{"file", "list", "", []string{"a", "b c", "-"}, nil},
+		{"stdin", "-", "a\x00b", []string{"a", "b"}, nil},
+		{"empty stdin", "-", "", nil, nil},
+		{"invalid", "-", "a\x00\x00-\x00b\x00", []string{"a", "b"},
+			[]string{"-:2: invalid zero-length file name", "when reading file names from stdin, no file name of '-' allowed"}},
+	}
+
+	for _, tt := range testCases {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			list, err := OpenFiles0(tt.from, fsys, strings.NewReader(tt.stdin))
+			require.NoError(t, err)
+			defer list.Close()
+			var names, invalid []string
+			for {
+				name, err := list.Next()
+				if errors.Is(err, io.EOF) {
+					break
+				}
+				var e *InvalidNameError
+				if errors.As(err, &e) {
+					invalid = append(invalid, e.Error())
+					continue
+				}
+				require.NoError(t, err)
+				names = append(names, name)
+			}
+			require.Equal(t, tt.expected, names)
+			require.Equal(t, tt.invalid, invalid)
+		})
+	}
+
+	_, err := OpenFiles0("missing", fsys, nil)
+	require.Error(t, err)
+}
+
+func TestRunFilesFiles0From(t *testing.T) {
+	t.Parallel()
+	fsys := fstest.MapFS{
+		"a": {Data: []byte("a\n")},
+		"b": {Data: []byte("b\n")},
+	}
+	cat := func(_ context.Context, stdio unix.StandardIO, _ int, _ string) error {
+		_, err := io.Copy(stdio.Stdout(), stdio.Stdin())
+		return err
+	}
+
+	for _, threads := range []uint{1, 2} {
+		var stdout, stderr strings.Builder
+		stdio := unix.NewStdio(strings.NewReader("a\x00\x00b\x00"), &stdout, &stderr)
+		err := NewRunFiles(nil, stdio, cat).FS(fsys).Files0From("-").DoThreads(context.Background(), threads)
+		require.Error(t, err)
+		require.EqualValues(t, 1, pipe.FromError(err).Code)
+		require.True(t, IsReported(err))
+		require.Equal(t, "a\nb\n", stdout.String())
+		require.Equal(t, "-:2: invalid zero-length file name\n", stderr.String())
+	}
+
+	stdio := unix.NewStdio(nil, io.Discard, io.Discard)
+	err := NewRunFiles([]string{"x"}, stdio, cat).Files0From("-").Do(context.Background())
+	require.EqualError(t, pipe.FromError(err).Err, "extra operand \"x\"\nfile operands cannot be combined with --files0-from")
+	err = NewRunFiles(nil, stdio, cat).FS(fsys).Files0From("missing").Do(context.Background())
+	require.Error(t, err)
+}
+
+// TestRunFilesFiles0Stream checks names are processed while the list is read
+func TestRunFilesFiles0Stream(t *testing.T) {
+	t.Parallel()
+	fsys := fstest.MapFS{
+		"a": {Data: []byte("a\n")},
+	}
+	r, w := io.Pipe()
+	seen := make(chan struct{})
+	cat := func(_ context.Context, stdio unix.StandardIO, _ int, _ string) error {
+		_, err := io.Copy(stdio.Stdout(), stdio.Stdin())
+		seen <- struct{}{}
+		return err
+	}
+
+	errc := make(chan error, 1)
+	go func() {
+		stdio := unix.NewStdio(r, io.Discard, io.Discard)
+		errc <- NewRunFiles(nil, stdio, cat).FS(fsys).Files0From("-").Do(context.Background())
+	}()
+
+	// the list never ends until the writer is closed
+	for i := 0; i < 3; i++ {
+		_, err := w.Write([]byte("a\x00"))
+		require.NoError(t, err)
+		<-seen
+	}
+	w.Close()
+	require.NoError(t, <-errc)
+}
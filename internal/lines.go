@@ -0,0 +1,62 @@
+// Copyright 2023 Michal Vyskocil. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package internal
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+)
+
+// linesCtxCheck is how often Lines checks the context
+const linesCtxCheck = 4096
+
+// Lines reads records terminated by delim from in and calls fn for each of
+// them, like awk does for RS. A record excludes the delimiter and is valid
+// only until fn returns. The last record without a delimiter is passed too.
+// Lines stops when fn returns false or an error. When delim is a newline, a
+// trailing \r is dropped like bufio.ScanLines does.
+//
+// Only a line longer than the read buffer is copied, so memory is bounded by
+// the longest line.
+func Lines(ctx context.Context, in io.Reader, delim byte, fn func(line []byte) (bool, error)) error {
+	r := bufio.NewReaderSize(in, 64*1024)
+	var long []byte
+	for n := 0; ; n++ {
+		if n%linesCtxCheck == 0 && ctx.Err() != nil {
+			return ctx.Err()
+		}
+		line, err := r.ReadSlice(delim)
+		if errors.Is(err, bufio.ErrBufferFull) {
+			long = append(long, line...)
+			continue
+		}
+		if len(long) > 0 {
+			line = append(long, line...)
+			long = long[:0]
+		}
+		if err != nil && !errors.Is(err, io.EOF) {
+			return err
+		}
+		eof := err != nil
+		if eof && len(line) == 0 {
+			return nil
+		}
+		if !eof {
+			line = line[:len(line)-1]
+		}
+		if delim == '\n' && len(line) > 0 && line[len(line)-1] == '\r' {
+			line = line[:len(line)-1]
+		}
+		more, ferr := fn(line)
+		if ferr != nil {
+			return ferr
+		}
+		if !more || eof {
+			return nil
+		}
+	}
+}
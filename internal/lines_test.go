@@ -0,0 +1,47 @@
+// Copyright 2023 Michal Vyskocil. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+package internal_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	. "github.com/gomoni/gonix/internal"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLines(t *testing.T) {
+	t.Parallel()
+	long := strings.Repeat("x", 100*1024)
+	testCases := []struct {
+		name     string
+		input    string
+		delim    byte
+		stop     int
+		expected []string
+	}{
+		{"empty", "", '\n', 0, nil},
+		{"lines", "a\n\nb\n", '\n', 0, []string{"a", "", "b"}},
+		{"no trailing newline", "a\nb", '\n', 0, []string{"a", "b"}},
+		{"crlf", "a\r\nb\r", '\n', 0, []string{"a", "b"}},
+		{"nul", "a\r\x00b\n\x00", 0, 0, []string{"a\r", "b\n"}},
+		{"long", "a\n" + long + "\nb\n", '\n', 0, []string{"a", long, "b"}},
+		{"stop", "a\nb\nc\n", '\n', 2, []string{"a", "b"}},
+	}
+
+	for _, tt := range testCases {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			var lines []string
+			err := Lines(context.Background(), strings.NewReader(tt.input), tt.delim, func(line []byte) (bool, error) {
+				lines = append(lines, string(line))
+				return tt.stop == 0 || len(lines) < tt.stop, nil
+			})
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, lines)
+		})
+	}
+}
@@ -0,0 +1,36 @@
+// Copyright 2023 Michal Vyskocil. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package internal
+
+import "strings"
+
+// ToRE2 converts POSIX regular expression to Go syntax. In a basic regular expression
+// (, ), {, }, |, + and ? are special only when escaped. GNU word boundaries \< and \>
+// are converted to \b.
+func ToRE2(s string, basic bool) string {
+	var sb strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c == '\\' && i+1 < len(s) {
+			i++
+			n := s[i]
+			switch {
+			case n == '<' || n == '>':
+				sb.WriteString(`\b`)
+			case basic && strings.IndexByte("(){}|+?", n) != -1:
+				sb.WriteByte(n)
+			default:
+				sb.WriteByte('\\')
+				sb.WriteByte(n)
+			}
+			continue
+		}
+		if basic && strings.IndexByte("(){}|+?", c) != -1 {
+			sb.WriteByte('\\')
+		}
+		sb.WriteByte(c)
+	}
+	return sb.String()
+}
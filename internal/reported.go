@@ -0,0 +1,35 @@
+// Copyright 2023 Michal Vyskocil. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package internal
+
+import "errors"
+
+// ReportedError is an error, which was already printed to stderr, so the
+// caller like cmd/gonix must not print it again
+type ReportedError struct {
+	Err error
+}
+
+// Reported marks err as printed to stderr, nil stays nil
+func Reported(err error) error {
+	if err == nil {
+		return nil
+	}
+	return ReportedError{Err: err}
+}
+
+func (e ReportedError) Error() string {
+	return e.Err.Error()
+}
+
+func (e ReportedError) Unwrap() error {
+	return e.Err
+}
+
+// IsReported is true if err was already printed to stderr
+func IsReported(err error) bool {
+	var e ReportedError
+	return errors.As(err, &e)
+}
@@ -6,8 +6,13 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"io/fs"
 	"os"
+	"path"
+	"path/filepath"
 	"runtime"
+	"strings"
+	"sync"
 
 	"github.com/gomoni/gio/pipe"
 	"github.com/gomoni/gio/unix"
@@ -15,12 +20,15 @@ import (
 
 // RunFiles is a helper run gonix commands with inputs from more files
 // failure in file opening does not break the loop, but returns exit code 1
-// "" or "-" are treated as stdin
+// "" or "-" are treated as stdin. A broken pipe stops the loop and it is
+// returned, so it gets BrokenPipe status, see Status.
 type RunFiles struct {
-	files []string
-	errs  error
-	stdio unix.StandardIO
-	fun   func(context.Context, unix.StandardIO, int, string) error
+	fsys       fs.FS
+	files      []string
+	files0From string
+	errs       error
+	stdio      unix.StandardIO
+	fun        func(context.Context, unix.StandardIO, int, string) error
 }
 
 func NewRunFiles(files []string, stdio unix.StandardIO, fun func(context.Context, unix.StandardIO, int, string) error) RunFiles {
@@ -32,13 +40,73 @@ func NewRunFiles(files []string, stdio unix.StandardIO, fun func(context.Context
 	}
 }
 
+// FS makes RunFiles to open files from fsys instead of the OS filesystem, nil means OS
+func (l RunFiles) FS(fsys fs.FS) RunFiles {
+	l.fsys = fsys
+	return l
+}
+
+// Files0From reads NUL terminated names of files from a file, - means stdin,
+// empty name means files are used. Names are read while files are processed
+// and invalid names are reported and skipped, see Files0. An empty list
+// processes nothing. File operands can't be combined with the list.
+func (l RunFiles) Files0From(from string) RunFiles {
+	l.files0From = from
+	return l
+}
+
+// stdinOnly is true if there are no files, so stdin is the only input
+func (l RunFiles) stdinOnly() bool {
+	return l.files0From == "" && len(l.files) == 0
+}
+
+// names returns an iterator over input names, which returns io.EOF at the end
+func (l RunFiles) names() (next func() (string, error), done func() error, err error) {
+	if l.files0From == "" {
+		idx := 0
+		next = func() (string, error) {
+			if idx == len(l.files) {
+				return "", io.EOF
+			}
+			idx++
+			return l.files[idx-1], nil
+		}
+		return next, func() error { return nil }, nil
+	}
+	if len(l.files) > 0 {
+		return nil, nil, pipe.NewError(1, ErrFiles0Operands(l.files[0]))
+	}
+	list, err := OpenFiles0(l.files0From, l.fsys, l.stdio.Stdin())
+	if err != nil {
+		return nil, nil, pipe.NewError(1, err)
+	}
+	return list.Next, list.Close, nil
+}
+
 func (l RunFiles) Do(ctx context.Context) error {
 	errs := make([]error, 0, len(l.files))
-	if len(l.files) == 0 {
+	if l.stdinOnly() {
 		return l.doOne(ctx, 0, "", l.stdio.Stdout(), l.stdio.Stderr(), &errs)
 	}
-	for idx, name := range l.files {
-		err := l.doOne(ctx, idx, name, l.stdio.Stdout(), l.stdio.Stderr(), &errs)
+	next, done, err := l.names()
+	if err != nil {
+		return err
+	}
+	defer done()
+	for idx := 0; ; {
+		name, err := next()
+		if errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			fmt.Fprintf(l.stdio.Stderr(), "%s\n", err)
+			errs = append(errs, err)
+			if isInvalidName(err) {
+				continue
+			}
+			break
+		}
+		err = l.doOne(ctx, idx, name, l.stdio.Stdout(), l.stdio.Stderr(), &errs)
+		idx++
 		if err != nil {
 			return err
 		}
@@ -49,6 +117,7 @@ func (l RunFiles) Do(ctx context.Context) error {
 type in struct {
 	idx  int
 	name string
+	err  error
 }
 
 type out struct {
@@ -58,58 +127,103 @@ type out struct {
 
 // DoThreads runs individual tasks concurrently via PMap. Each command writes to the memory buffer
 // first, so probably best to be used for a compute intensive operations like cksum is. As it uses
-// PMap, outputs are in the same order as inputs.
+// PMap, outputs are in the same order as inputs. Names are processed in batches of a few names per
+// thread, so the memory does not grow with a number of names.
 func (l RunFiles) DoThreads(ctx context.Context, threads uint) error {
 	if threads == 0 {
 		threads = uint(runtime.GOMAXPROCS(0))
 	}
-	if threads == 1 || len(l.files) == 0 {
+	if threads == 1 || l.stdinOnly() {
 		return l.Do(ctx)
 	}
 
+	var mu sync.Mutex
 	errs := make([]error, 0, len(l.files))
 	one := func(ctx context.Context, in in) (out, error) {
 		out := out{
 			stdout: bytes.NewBuffer(nil),
 			stderr: bytes.NewBuffer(nil),
 		}
-		err := l.doOne(ctx, in.idx, in.name, out.stdout, out.stderr, &errs)
+		var oneErrs []error
+		err := in.err
+		if err == nil {
+			err = l.doOne(ctx, in.idx, in.name, out.stdout, out.stderr, &oneErrs)
+		}
 		if err != nil {
-			errs = append(errs, err)
+			fmt.Fprintf(out.stderr, "%s\n", err)
+			oneErrs = append(oneErrs, err)
+		}
+		mu.Lock()
+		errs = append(errs, oneErrs...)
+		mu.Unlock()
+		if in.err != nil {
+			return out, nil
 		}
 		return out, err
 	}
 
-	inputs := make([]in, len(l.files))
-	for idx, f := range l.files {
-		inputs[idx] = in{idx: idx, name: f}
-	}
-
-	outputs, err := PMap(ctx, threads, inputs, one)
+	next, done, err := l.names()
 	if err != nil {
 		return err
 	}
-	for _, out := range outputs {
-		_, err = io.Copy(l.stdio.Stderr(), out.stderr)
-		if err != nil {
-			errs = append(errs, err)
+	defer done()
+
+	batch := make([]in, 0, 4*threads)
+	for idx, eof := 0, false; !eof; {
+		batch = batch[:0]
+		for len(batch) < cap(batch) {
+			name, err := next()
+			if errors.Is(err, io.EOF) {
+				eof = true
+				break
+			} else if err != nil {
+				// reported in order by one
+				batch = append(batch, in{err: err})
+				if isInvalidName(err) {
+					continue
+				}
+				eof = true
+				break
+			}
+			batch = append(batch, in{idx: idx, name: name})
+			idx++
 		}
-		_, err = io.Copy(l.stdio.Stdout(), out.stdout)
+
+		outputs, err := PMap(ctx, threads, batch, one)
 		if err != nil {
-			errs = append(errs, err)
+			return err
+		}
+		for _, out := range outputs {
+			_, err = io.Copy(l.stdio.Stderr(), out.stderr)
+			if err != nil {
+				fmt.Fprintf(l.stdio.Stderr(), "%s\n", err)
+				errs = append(errs, err)
+			}
+			_, err = io.Copy(l.stdio.Stdout(), out.stdout)
+			if IsBrokenPipe(err) {
+				return NewError(1, err)
+			} else if err != nil {
+				fmt.Fprintf(l.stdio.Stderr(), "%s\n", err)
+				errs = append(errs, err)
+			}
 		}
 	}
 	return asPipeError(errs)
 }
 
+func isInvalidName(err error) bool {
+	var invalid *InvalidNameError
+	return errors.As(err, &invalid)
+}
+
 func (l RunFiles) doOne(ctx context.Context, idx int, name string, stdout, stderr io.Writer, errsp *[]error) error {
 	var in io.Reader
 	if name == "" || name == "-" {
 		in = l.stdio.Stdin()
 	} else {
-		f, err := os.Open(name)
+		f, err := Open(l.fsys, name)
 		if err != nil {
-			fmt.Fprintf(l.stdio.Stderr(), "%s\n", err)
+			fmt.Fprintf(stderr, "%s\n", err)
 			*errsp = append(*errsp, err)
 			return nil
 		}
@@ -125,10 +239,60 @@ func (l RunFiles) doOne(ctx context.Context, idx int, name string, stdout, stder
 	)
 }
 
+// Open opens the named file for reading from fsys or from the OS filesystem
+// if fsys is nil. Names are converted to fs.ValidPath, so an absolute path
+// or ./name refers to fsys root, names outside of fsys are invalid.
+func Open(fsys fs.FS, name string) (fs.File, error) {
+	if fsys == nil {
+		return os.Open(name)
+	}
+	p, err := fsPath(name)
+	if err != nil {
+		return nil, err
+	}
+	return fsys.Open(p)
+}
+
+// Stat returns a fs.FileInfo from fsys or from the OS filesystem if fsys is nil
+func Stat(fsys fs.FS, name string) (fs.FileInfo, error) {
+	if fsys == nil {
+		return os.Stat(name)
+	}
+	p, err := fsPath(name)
+	if err != nil {
+		return nil, err
+	}
+	return fs.Stat(fsys, p)
+}
+
+// ReadFile reads the named file from fsys or from the OS filesystem if fsys is nil
+func ReadFile(fsys fs.FS, name string) ([]byte, error) {
+	if fsys == nil {
+		return os.ReadFile(name)
+	}
+	p, err := fsPath(name)
+	if err != nil {
+		return nil, err
+	}
+	return fs.ReadFile(fsys, p)
+}
+
+func fsPath(name string) (string, error) {
+	p := strings.TrimLeft(path.Clean(filepath.ToSlash(name)), "/")
+	if p == "" {
+		p = "."
+	}
+	if !fs.ValidPath(p) {
+		return "", &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+	return p, nil
+}
+
 func asPipeError(errs []error) error {
 	if len(errs) == 0 {
 		return nil
 	}
-	err := pipe.NewError(1, errors.Join(errs...))
+	// errors were printed by doOne
+	err := pipe.NewError(1, Reported(errors.Join(errs...)))
 	return err
 }
@@ -0,0 +1,87 @@
+// Copyright 2023 Michal Vyskocil. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+package internal_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/gomoni/gio/unix"
+	. "github.com/gomoni/gonix/internal"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOpenFS(t *testing.T) {
+	t.Parallel()
+	fsys := fstest.MapFS{
+		"a.txt":     {Data: []byte("a\n")},
+		"dir/b.txt": {Data: []byte("b\n")},
+	}
+
+	for _, name := range []string{"a.txt", "/a.txt", "./a.txt", "dir/../a.txt"} {
+		b, err := ReadFile(fsys, name)
+		require.NoError(t, err, name)
+		require.Equal(t, "a\n", string(b), name)
+	}
+
+	info, err := Stat(fsys, "dir/b.txt")
+	require.NoError(t, err)
+	require.Equal(t, int64(2), info.Size())
+
+	_, err = Open(fsys, "../a.txt")
+	require.Error(t, err)
+	require.True(t, errors.Is(err, fs.ErrInvalid))
+
+	_, err = Open(fsys, "missing.txt")
+	require.Error(t, err)
+	require.True(t, errors.Is(err, fs.ErrNotExist))
+}
+
+func TestRunFilesFS(t *testing.T) {
+	t.Parallel()
+	fsys := fstest.MapFS{
+		"a.txt": {Data: []byte("a\n")},
+		"b.txt": {Data: []byte("b\n")},
+	}
+	cat := func(_ context.Context, stdio unix.StandardIO, _ int, _ string) error {
+		_, err := io.Copy(stdio.Stdout(), stdio.Stdin())
+		return err
+	}
+
+	var stdout, stderr strings.Builder
+	stdio := unix.NewStdio(strings.NewReader("stdin\n"), &stdout, &stderr)
+	err := NewRunFiles([]string{"a.txt", "-", "missing.txt", "b.txt"}, stdio, cat).FS(fsys).Do(context.Background())
+	require.Error(t, err)
+	require.Equal(t, "a\nstdin\nb\n", stdout.String())
+	require.Contains(t, stderr.String(), "missing.txt")
+}
+
+func TestRunFilesBrokenPipe(t *testing.T) {
+	t.Parallel()
+	fsys := fstest.MapFS{
+		"a": {Data: []byte("a\n")},
+		"b": {Data: []byte("b\n")},
+	}
+	r, w := io.Pipe()
+	CloseStdin(r)
+
+	var names []string
+	fun := func(_ context.Context, stdio unix.StandardIO, _ int, name string) error {
+		names = append(names, name)
+		_, err := io.Copy(stdio.Stdout(), stdio.Stdin())
+		return err
+	}
+	err := NewRunFiles([]string{"a", "b"}, unix.NewStdio(nil, w, io.Discard), fun).FS(fsys).Do(context.Background())
+	require.Equal(t, BrokenPipe, Status(err))
+	require.Equal(t, []string{"a"}, names)
+
+	_, err = w.Write([]byte("x"))
+	require.True(t, IsBrokenPipe(err))
+	require.False(t, IsBrokenPipe(io.EOF))
+}
@@ -0,0 +1,51 @@
+// Copyright 2023 Michal Vyskocil. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package internal
+
+import (
+	"context"
+	"errors"
+
+	"github.com/gomoni/gio/pipe"
+)
+
+// Exit statuses of filters stopped from the outside, see pipeline.Code
+const (
+	// Signaled is added to a signal number for commands interrupted by a signal
+	Signaled = 128
+	// Timeout is a code of timeout(1) for commands out of time
+	Timeout = 124
+	// Interrupted is 128+SIGINT
+	Interrupted = Signaled + 2
+	// BrokenPipe is 128+SIGPIPE
+	BrokenPipe = Signaled + 13
+)
+
+// Status returns Timeout, Interrupted or BrokenPipe if err was caused by
+// a context deadline, a cancelation or a write to a closed reader, zero
+// otherwise.
+func Status(err error) int {
+	switch {
+	case err == nil:
+		return 0
+	case errors.Is(err, context.DeadlineExceeded):
+		return Timeout
+	case errors.Is(err, context.Canceled):
+		return Interrupted
+	case IsBrokenPipe(err):
+		return BrokenPipe
+	}
+	return 0
+}
+
+// NewError is pipe.NewError, which uses Status of err instead of code if
+// there is any, so a filter stopped from the outside does not look like
+// a failed one.
+func NewError(code int, err error) pipe.Error {
+	if status := Status(err); status != 0 {
+		code = status
+	}
+	return pipe.NewError(code, err)
+}
@@ -12,6 +12,7 @@ import (
 	"fmt"
 	"math"
 	"strconv"
+	"time"
 )
 
 // Unit represents the units with multiplier suffixes. Is defined as float64 to support
@@ -274,3 +275,53 @@ func (b *Byte) Set(value string) error {
 	*b = x
 	return nil
 }
+
+// Duration is a time interval parsed like GNU sleep or timeout do
+type Duration time.Duration
+
+// DurationSuffixes are in seconds, a number without a suffix is in seconds too
+var DurationSuffixes = map[string]float64{
+	"s": 1,
+	"m": 60,
+	"h": 60 * 60,
+	"d": 24 * 60 * 60,
+}
+
+// ParseDuration parses a non negative floating point number of seconds with
+// an optional suffix s, m, h or d, such as "10", "1.5m" or "2d". Durations
+// longer than time.Duration can hold are truncated to the maximum, nonzero
+// durations shorter than a nanosecond are rounded up to it.
+func ParseDuration(s string) (Duration, error) {
+	u, err := parseUnit(DurationSuffixes, s)
+	if err != nil || u < 0 {
+		// errors of parseUnit talk about sizes
+		return 0, fmt.Errorf("invalid time interval %q", s)
+	}
+	ns := float64(u) * float64(time.Second)
+	switch {
+	case ns >= math.MaxInt64:
+		return Duration(math.MaxInt64), nil
+	case ns > 0 && ns < 1:
+		// zero disables the time limit
+		return Duration(1), nil
+	}
+	return Duration(ns), nil
+}
+
+// https://pkg.go.dev/github.com/spf13/pflag#Value
+func (d Duration) String() string {
+	return time.Duration(d).String()
+}
+
+func (d Duration) Type() string {
+	return "Duration"
+}
+
+func (d *Duration) Set(value string) error {
+	x, err := ParseDuration(value)
+	if err != nil {
+		return err
+	}
+	*d = x
+	return nil
+}
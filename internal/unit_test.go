@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"math"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 )
@@ -162,3 +163,40 @@ func TestSPF13Value(t *testing.T) {
 
 	require.Equal(t, "Byte", b2.Type())
 }
+
+func TestParseDuration(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		input    string
+		expected time.Duration
+	}{
+		{"0", 0},
+		{"10", 10 * time.Second},
+		{"0.5", 500 * time.Millisecond},
+		{"1.5s", 1500 * time.Millisecond},
+		{"2m", 2 * time.Minute},
+		{"1h", time.Hour},
+		{"1d", 24 * time.Hour},
+		{"100000000000d", math.MaxInt64},
+		{"0.0000000001", time.Nanosecond},
+		{"0.0000000015", time.Nanosecond},
+	}
+
+	for _, tt := range testCases {
+		tt := tt
+		t.Run(tt.input, func(t *testing.T) {
+			t.Parallel()
+			d, err := ParseDuration(tt.input)
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, time.Duration(d))
+		})
+	}
+
+	_, err := ParseDuration("-1s")
+	require.EqualError(t, err, `invalid time interval "-1s"`)
+	_, err = ParseDuration("1w")
+	require.EqualError(t, err, `invalid time interval "1w"`)
+	_, err = ParseDuration("2x")
+	require.EqualError(t, err, `invalid time interval "2x"`)
+}
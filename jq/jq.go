@@ -0,0 +1,454 @@
+// Copyright 2023 Michal Vyskocil. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+/*
+jq is a thin wrapper on top of github.com/itchyny/gojq providing a compatible
+[unix.Filter] interface for gojq.
+
+	jq [-rcsn] [--arg name value]... [--argjson name json]... filter [file...]
+
+Configuration from NewConfig is sandboxed, a filter can't read environment
+variables via $ENV or env, nor import modules from the file system.
+
+Exit status is 2 for invalid arguments and input, 3 for a filter which can't
+be compiled and 5 if the filter failed on any input. The halt_error sets its
+own exit code.
+*/
+package jq
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"strings"
+
+	"github.com/gomoni/gio/pipe"
+	"github.com/gomoni/gio/unix"
+	"github.com/gomoni/gonix"
+	"github.com/gomoni/gonix/internal"
+	"github.com/itchyny/gojq"
+	"github.com/spf13/pflag"
+)
+
+// Var is a variable available to filter as $Name
+type Var struct {
+	Name  string
+	Value any
+}
+
+type Config struct {
+	Raw       bool                  // Raw prints strings without quotes
+	Compact   bool                  // Compact prints each value on a single line
+	Slurp     bool                  // Slurp reads all inputs into an array and runs the filter once
+	NullInput bool                  // NullInput runs filter with null input, inputs are available via input and inputs
+	Indent    int                   // Indent is a number of spaces used for pretty printing
+	Vars      []Var                 // Vars are passed to filter, named ones are in $ARGS.named too
+	Environ   func() []string       // Environ provides $ENV, nil means an empty environment
+	Options   []gojq.CompilerOption // Options are additional options like gojq.WithFunction
+}
+
+// NewConfig returns a sandboxed configuration
+func NewConfig() *Config {
+	return &Config{
+		Indent: 2,
+	}
+}
+
+// JQ is a thin wrapper on top of github.com/itchyny/gojq
+type JQ struct {
+	query  *gojq.Query
+	code   *gojq.Code
+	config *Config
+	fsys   fs.FS
+	files  []string
+}
+
+func New(query *gojq.Query, config *Config) JQ {
+	return JQ{
+		query:  query,
+		config: config,
+	}
+}
+
+func init() {
+	gonix.Register(gonix.Cmd{
+		Name:  "jq",
+		Usage: "command-line JSON processor",
+		New:   gonix.FromArgs(JQ{}.FromArgs),
+	})
+}
+
+// Compile parses and compiles the filter once, so JQ can be run many times. Only
+// filters calling input or inputs are compiled again in each Run, as the input
+// differs.
+func Compile(src []byte, config *Config) (JQ, error) {
+	if config == nil {
+		return JQ{}, fmt.Errorf("nil config")
+	}
+	query, err := gojq.Parse(string(src))
+	if err != nil {
+		return JQ{}, err
+	}
+	c := New(query, config)
+	code, err := gojq.Compile(query, c.options(nil)...)
+	if err != nil {
+		_, err2 := gojq.Compile(query, c.options(gojq.NewIter())...)
+		if err2 != nil {
+			return JQ{}, err
+		}
+		code = nil
+	}
+	c.code = code
+	return c, nil
+}
+
+// Files are input files, where - denotes stdin
+func (c JQ) Files(f ...string) JQ {
+	c.files = append(c.files, f...)
+	return c
+}
+
+// FS sets a filesystem used to open files, nil means the OS filesystem
+func (c JQ) FS(fsys fs.FS) JQ {
+	c.fsys = fsys
+	return c
+}
+
+// FromArgs builds a JQ from standard argv except the command name (os.Argv[1:])
+//
+//	jq [-rcsn] [--arg name value]... [--argjson name json]... filter [file...]
+//
+// Configuration starts from NewConfig.
+func (c JQ) FromArgs(argv []string) (JQ, error) {
+	config := NewConfig()
+	if c.config != nil {
+		cp := *c.config
+		config = &cp
+	}
+
+	// --arg and --argjson have two values, which pflag does not support
+	var rest []string
+	for idx := 0; idx < len(argv); idx++ {
+		arg := argv[idx]
+		if arg == "--" {
+			rest = append(rest, argv[idx:]...)
+			break
+		}
+		if arg != "--arg" && arg != "--argjson" {
+			rest = append(rest, arg)
+			continue
+		}
+		if idx+2 >= len(argv) {
+			return JQ{}, pipe.NewErrorf(2, "jq: %s takes two parameters (e.g. %s varname value)", arg, arg)
+		}
+		name, value := argv[idx+1], argv[idx+2]
+		idx += 2
+		if arg == "--arg" {
+			config.Vars = append(config.Vars, Var{Name: name, Value: value})
+			continue
+		}
+		var v any
+		dec := json.NewDecoder(strings.NewReader(value))
+		dec.UseNumber()
+		if err := dec.Decode(&v); err != nil {
+			return JQ{}, pipe.NewErrorf(2, "jq: invalid JSON text passed to --argjson: %w", err)
+		}
+		config.Vars = append(config.Vars, Var{Name: name, Value: v})
+	}
+
+	flag := pflag.FlagSet{}
+	// jq accepts options after the filter and files
+	flag.SetInterspersed(true)
+	flag.BoolVarP(&config.Raw, "raw-output", "r", config.Raw, "output raw strings, not JSON texts")
+	flag.BoolVarP(&config.Compact, "compact-output", "c", config.Compact, "compact instead of pretty-printed output")
+	flag.BoolVarP(&config.Slurp, "slurp", "s", config.Slurp, "read all inputs into an array")
+	flag.BoolVarP(&config.NullInput, "null-input", "n", config.NullInput, "use null as the single input value")
+	flag.IntVar(&config.Indent, "indent", config.Indent, "use the given number of spaces for indentation")
+
+	err := flag.Parse(rest)
+	if err != nil {
+		return JQ{}, pipe.NewErrorf(2, "jq: parsing failed: %w", err)
+	}
+	if config.Indent < 0 || config.Indent > 7 {
+		return JQ{}, pipe.NewErrorf(2, "jq: cannot indent more than 7 characters")
+	}
+
+	args := flag.Args()
+	if len(args) == 0 {
+		return JQ{}, pipe.NewErrorf(2, "jq: missing filter")
+	}
+	jq, err := Compile([]byte(args[0]), config)
+	if err != nil {
+		return JQ{}, pipe.NewErrorf(3, "jq: compile error: %w", err)
+	}
+	if len(args) > 1 {
+		jq.files = args[1:]
+	}
+	return jq, nil
+}
+
+func (c JQ) options(inputs gojq.Iter) []gojq.CompilerOption {
+	names := make([]string, 0, len(c.config.Vars)+1)
+	names = append(names, "$ARGS")
+	for _, v := range c.config.Vars {
+		names = append(names, "$"+v.Name)
+	}
+	opts := []gojq.CompilerOption{gojq.WithVariables(names)}
+	if c.config.Environ != nil {
+		opts = append(opts, gojq.WithEnvironLoader(c.config.Environ))
+	}
+	if inputs != nil {
+		opts = append(opts, gojq.WithInputIter(inputs))
+	}
+	return append(opts, c.config.Options...)
+}
+
+func (c JQ) values() []any {
+	named := make(map[string]any, len(c.config.Vars))
+	values := make([]any, 0, len(c.config.Vars)+1)
+	values = append(values, map[string]any{"positional": []any{}, "named": named})
+	for _, v := range c.config.Vars {
+		named[v.Name] = v.Value
+		values = append(values, v.Value)
+	}
+	return values
+}
+
+func (c JQ) Run(ctx context.Context, stdio unix.StandardIO) error {
+	if c.config == nil {
+		return fmt.Errorf("nil config")
+	}
+	if c.query == nil {
+		return fmt.Errorf("nil query")
+	}
+
+	stdin, closeFn, openErr := c.open(stdio)
+	defer closeFn()
+	inputs := newInputs(stdin, c.config.Slurp)
+
+	code := c.code
+	if code == nil {
+		var err error
+		code, err = gojq.Compile(c.query, c.options(inputs)...)
+		if err != nil {
+			return pipe.NewErrorf(3, "jq: compile error: %w", err)
+		}
+	}
+
+	out := bufio.NewWriter(stdio.Stdout())
+	defer out.Flush()
+	p := printer{config: c.config, out: out, stderr: stdio.Stderr()}
+
+	if c.config.NullInput {
+		err := p.run(ctx, code.RunWithContext(ctx, nil, c.values()...))
+		if err != nil {
+			return err
+		}
+	} else {
+		for {
+			v, ok := inputs.Next()
+			if !ok {
+				break
+			}
+			if _, ok := v.(error); ok {
+				break
+			}
+			err := p.run(ctx, code.RunWithContext(ctx, v, c.values()...))
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := out.Flush(); err != nil {
+		return internal.NewError(1, fmt.Errorf("jq: fail to run: %w", err))
+	}
+	switch {
+	case inputs.err != nil:
+		return pipe.NewErrorf(2, "jq: error: %w", inputs.err)
+	case openErr != nil:
+		return openErr
+	case p.failed:
+		return pipe.NewError(5, nil)
+	}
+	return nil
+}
+
+// open returns a reader reading all files one after another
+func (c JQ) open(stdio unix.StandardIO) (io.Reader, func(), error) {
+	if len(c.files) == 0 {
+		return stdio.Stdin(), func() {}, nil
+	}
+	var readers []io.Reader
+	var closers []io.Closer
+	var errs []error
+	for _, name := range c.files {
+		if name == "-" {
+			readers = append(readers, stdio.Stdin())
+			continue
+		}
+		f, err := internal.Open(c.fsys, name)
+		if err != nil {
+			cause := err
+			var pathErr *fs.PathError
+			if errors.As(err, &pathErr) {
+				cause = pathErr.Err
+			}
+			fmt.Fprintf(stdio.Stderr(), "jq: error: Could not open %s: %s\n", name, cause)
+			errs = append(errs, err)
+			continue
+		}
+		readers = append(readers, f)
+		closers = append(closers, f)
+	}
+	closeFn := func() {
+		for _, c := range closers {
+			c.Close()
+		}
+	}
+	var err error
+	if len(errs) > 0 {
+		err = pipe.NewError(2, internal.Reported(errors.Join(errs...)))
+	}
+	return io.MultiReader(readers...), closeFn, err
+}
+
+// inputs is an iterator over JSON values in the input
+type inputs struct {
+	dec   *json.Decoder
+	slurp bool
+	done  bool
+	err   error
+}
+
+func newInputs(r io.Reader, slurp bool) *inputs {
+	dec := json.NewDecoder(r)
+	dec.UseNumber()
+	return &inputs{dec: dec, slurp: slurp}
+}
+
+func (i *inputs) Next() (any, bool) {
+	if i.done || i.err != nil {
+		return nil, false
+	}
+	if !i.slurp {
+		return i.next()
+	}
+	i.done = true
+	values := []any{}
+	for {
+		v, ok := i.next()
+		if !ok {
+			break
+		}
+		if err, ok := v.(error); ok {
+			return err, true
+		}
+		values = append(values, v)
+	}
+	return values, true
+}
+
+func (i *inputs) next() (any, bool) {
+	var v any
+	err := i.dec.Decode(&v)
+	if errors.Is(err, io.EOF) {
+		return nil, false
+	} else if err != nil {
+		i.err = err
+		return err, true
+	}
+	return v, true
+}
+
+type printer struct {
+	config *Config
+	out    *bufio.Writer
+	stderr io.Writer
+	failed bool
+}
+
+type haltError interface {
+	error
+	Value() any
+	ExitCode() int
+	IsHaltError() bool
+}
+
+// run prints all results of the filter, errors are reported to stderr
+func (p *printer) run(ctx context.Context, iter gojq.Iter) error {
+	for {
+		v, ok := iter.Next()
+		if !ok {
+			return nil
+		}
+		if err, ok := v.(error); ok {
+			var halt haltError
+			if errors.As(err, &halt) && halt.IsHaltError() {
+				return p.halt(halt)
+			}
+			if ctx.Err() != nil {
+				return internal.NewError(1, fmt.Errorf("jq: %w", ctx.Err()))
+			}
+			if err := p.out.Flush(); err != nil {
+				return internal.NewError(1, fmt.Errorf("jq: fail to run: %w", err))
+			}
+			msg := err.Error()
+			if msg == "break" {
+				// gojq reports exhausted input as an uncaught break
+				msg = "No more inputs"
+			}
+			fmt.Fprintf(p.stderr, "jq: error: %s\n", msg)
+			p.failed = true
+			return nil
+		}
+		if err := p.print(v); err != nil {
+			return internal.NewError(1, fmt.Errorf("jq: fail to run: %w", err))
+		}
+	}
+}
+
+func (p *printer) halt(halt haltError) error {
+	if err := p.out.Flush(); err != nil {
+		return internal.NewError(1, fmt.Errorf("jq: fail to run: %w", err))
+	}
+	if value := halt.Value(); value != nil {
+		if s, ok := value.(string); ok {
+			fmt.Fprint(p.stderr, s)
+		} else {
+			b, _ := gojq.Marshal(value)
+			fmt.Fprintf(p.stderr, "%s\n", b)
+		}
+	}
+	if halt.ExitCode() == 0 {
+		return nil
+	}
+	return pipe.NewError(halt.ExitCode(), nil)
+}
+
+func (p *printer) print(v any) error {
+	if s, ok := v.(string); ok && p.config.Raw {
+		_, err := p.out.WriteString(s + "\n")
+		return err
+	}
+	b, err := gojq.Marshal(v)
+	if err != nil {
+		return err
+	}
+	if !p.config.Compact {
+		var buf bytes.Buffer
+		err = json.Indent(&buf, b, "", strings.Repeat(" ", p.config.Indent))
+		if err != nil {
+			return err
+		}
+		b = buf.Bytes()
+	}
+	_, err = p.out.Write(append(b, '\n'))
+	return err
+}
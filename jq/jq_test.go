@@ -0,0 +1,158 @@
+// Copyright 2023 Michal Vyskocil. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package jq_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/gomoni/gio/pipe"
+	"github.com/gomoni/gio/unix"
+	"github.com/gomoni/gonix/internal/test"
+	. "github.com/gomoni/gonix/jq"
+	"github.com/stretchr/testify/require"
+)
+
+const input = `{"name": "three", "n": 1}
+{"name": "small", "n": 2}
+{"name": "pigs", "n": 3}
+`
+
+func TestJQ(t *testing.T) {
+	test.Parallel(t)
+	testCases := []test.Case[JQ]{
+		{
+			Name:     "identity",
+			Filter:   compile(t, NewConfig(), `.`),
+			Input:    `{"a":[1,2]}`,
+			Expected: "{\n  \"a\": [\n    1,\n    2\n  ]\n}\n",
+		},
+		{
+			Name:     "-c",
+			Filter:   fromArgs(t, []string{"-c", "."}),
+			Input:    `{"a": [1, 2]}`,
+			Expected: "{\"a\":[1,2]}\n",
+		},
+		{
+			Name:     "field",
+			Filter:   fromArgs(t, []string{".name"}),
+			Input:    input,
+			Expected: "\"three\"\n\"small\"\n\"pigs\"\n",
+		},
+		{
+			Name:     "-r",
+			Filter:   fromArgs(t, []string{"-r", ".name"}),
+			Input:    input,
+			Expected: "three\nsmall\npigs\n",
+		},
+		{
+			Name:     "-s",
+			Filter:   fromArgs(t, []string{"-s", "map(.n) | add"}),
+			Input:    input,
+			Expected: "6\n",
+		},
+		{
+			Name:     "-n",
+			Filter:   fromArgs(t, []string{"-n", "[1, 2] | length"}),
+			Input:    input,
+			Expected: "2\n",
+		},
+		{
+			Name:     "-n inputs",
+			Filter:   fromArgs(t, []string{"-n", "-c", "[inputs.n]"}),
+			Input:    input,
+			Expected: "[1,2,3]\n",
+		},
+		{
+			Name:     "options after filter",
+			Filter:   fromArgs(t, []string{"-n", "[inputs.n]", "-c"}),
+			Input:    input,
+			Expected: "[1,2,3]\n",
+		},
+		{
+			Name:     "--arg --argjson",
+			Filter:   fromArgs(t, []string{"-n", "-c", "--arg", "a", "1", "--argjson", "b", `{"c":1}`, "[$a, $b, $ARGS.named.a]"}),
+			Expected: "[\"1\",{\"c\":1},\"1\"]\n",
+		},
+		{
+			Name:     "big numbers",
+			Filter:   fromArgs(t, []string{"."}),
+			Input:    "100000000000000000000000000001",
+			Expected: "100000000000000000000000000001\n",
+		},
+	}
+	test.RunAll(t, testCases)
+}
+
+func TestRunMany(t *testing.T) {
+	test.Parallel(t)
+	jq := compile(t, NewConfig(), `[.n, input.n]`)
+	for i := 0; i < 2; i++ {
+		var out strings.Builder
+		stdio := unix.NewStdio(strings.NewReader(input), &out, &out)
+		err := jq.Run(context.Background(), stdio)
+		require.Error(t, err)
+		require.Equal(t, 5, pipe.FromError(err).Code)
+		require.Equal(t, "[\n  1,\n  2\n]\njq: error: No more inputs\n", out.String())
+	}
+}
+
+func TestSandbox(t *testing.T) {
+	t.Setenv("GONIX_JQ_TEST", "secret")
+	var out strings.Builder
+	stdio := unix.NewStdio(strings.NewReader(""), &out, &out)
+	err := fromArgs(t, []string{"-n", "$ENV.GONIX_JQ_TEST"}).Run(context.Background(), stdio)
+	require.NoError(t, err)
+	require.Equal(t, "null\n", out.String())
+}
+
+func TestErrors(t *testing.T) {
+	test.Parallel(t)
+	_, err := New(nil, nil).FromArgs([]string{".["})
+	require.Error(t, err)
+	require.Equal(t, 3, pipe.FromError(err).Code)
+
+	_, err = New(nil, nil).FromArgs([]string{"--argjson", "a", "{", "."})
+	require.Error(t, err)
+	require.Equal(t, 2, pipe.FromError(err).Code)
+
+	var out strings.Builder
+	stdio := unix.NewStdio(strings.NewReader(`{"a":1} nope`), &out, &out)
+	err = fromArgs(t, []string{"-c", "."}).Run(context.Background(), stdio)
+	require.Error(t, err)
+	require.Equal(t, 2, pipe.FromError(err).Code)
+	require.Equal(t, "{\"a\":1}\n", out.String())
+
+	out.Reset()
+	stdio = unix.NewStdio(strings.NewReader(""), &out, &out)
+	err = fromArgs(t, []string{"-n", `"bye\n" | halt_error(4)`}).Run(context.Background(), stdio)
+	require.Error(t, err)
+	require.Equal(t, 4, pipe.FromError(err).Code)
+	require.Equal(t, "bye\n", out.String())
+
+	out.Reset()
+	stdio = unix.NewStdio(strings.NewReader(""), &out, &out)
+	err = fromArgs(t, []string{".", "missing"}).FS(fstest.MapFS{}).Run(context.Background(), stdio)
+	require.Error(t, err)
+	require.Equal(t, 2, pipe.FromError(err).Code)
+	require.Equal(t, "jq: error: Could not open missing: file does not exist\n", out.String())
+}
+
+func compile(t *testing.T, config *Config, src string) JQ {
+	t.Helper()
+	jq, err := Compile([]byte(src), config)
+	require.NoError(t, err)
+	return jq
+}
+
+func fromArgs(t *testing.T, argv []string) JQ {
+	t.Helper()
+	n := New(nil, nil)
+	f, err := n.FromArgs(argv)
+	require.NoError(t, err)
+	return f
+}
@@ -0,0 +1,136 @@
+// Copyright 2023 Michal Vyskocil. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+/*
+pipeline runs filters connected by pipes and reports an exit status of each
+of them, like bash does via PIPESTATUS.
+
+Errors are mapped to exit statuses by Code, so callers can tell the causes
+apart
+
+	0       success
+	1       a general error of a filter
+	124     context.DeadlineExceeded, like timeout(1)
+	126     a command is not executable
+	127     a command not found
+	128+N   a command killed by a signal N, see exec
+	130     context.Canceled, like 128+SIGINT
+	141     write to a closed reader, like 128+SIGPIPE
+
+Unlike unix.NewLine, a failed filter does not cancel the others. Readers
+after it get io.EOF and writers before it get a broken pipe error, like in
+a shell.
+*/
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+
+	"github.com/gomoni/gio/pipe"
+	"github.com/gomoni/gio/unix"
+	"github.com/gomoni/gonix/internal"
+)
+
+type Line struct {
+	pipefail bool
+}
+
+// NewLine returns Line, which returns the exit status of the last filter
+func NewLine() Line {
+	return Line{}
+}
+
+// Pipefail returns the exit status of the last filter, which failed, like
+// set -o pipefail does. The pipeline succeeds only if all filters succeed.
+// A filter stopped by a broken pipe did not fail, the reader did not need
+// more input, so yes | head -n 1 succeeds.
+func (l Line) Pipefail(b bool) Line {
+	l.pipefail = b
+	return l
+}
+
+// Result is a result of a pipeline run
+type Result struct {
+	// Status is the exit status of the pipeline, see Line.Pipefail
+	Status int
+	// PipeStatus are exit statuses of each filter like bash's PIPESTATUS
+	PipeStatus []int
+	// Errs are errors returned by each filter, nil on success
+	Errs []error
+}
+
+// Err returns nil if Status is zero, otherwise pipe.Error with Status as the
+// Code. The errors of all failed filters are joined, so pipe.Errors can be
+// used to get them.
+func (r Result) Err() error {
+	if r.Status == 0 {
+		return nil
+	}
+	var errs []error
+	for _, err := range r.Errs {
+		if err != nil {
+			errs = append(errs, pipe.FromError(err).Err)
+		}
+	}
+	if len(errs) == 1 {
+		return pipe.NewError(r.Status, errs[0])
+	}
+	return pipe.NewError(r.Status, errors.Join(errs...))
+}
+
+// Run runs filters connected by pipes concurrently and waits for all of them.
+func (l Line) Run(ctx context.Context, stdio unix.StandardIO, filters ...unix.Filter) Result {
+	res := Result{
+		PipeStatus: make([]int, len(filters)),
+		Errs:       make([]error, len(filters)),
+	}
+	if len(filters) == 0 {
+		return res
+	}
+
+	var wg sync.WaitGroup
+	stdin := stdio.Stdin()
+	for idx, filter := range filters {
+		var stdout io.Writer = stdio.Stdout()
+		var next *io.PipeReader
+		var pw *io.PipeWriter
+		if idx < len(filters)-1 {
+			next, pw = io.Pipe()
+			stdout = pw
+		}
+
+		wg.Add(1)
+		go func(idx int, filter unix.Filter, stdin io.Reader, stdout io.Writer, pw *io.PipeWriter) {
+			defer wg.Done()
+			err := filter.Run(ctx, unix.NewStdio(stdin, stdout, stdio.Stderr()))
+			if idx > 0 {
+				// the filter does not read more, writer gets a broken pipe
+				internal.CloseStdin(stdin)
+			}
+			if pw != nil {
+				pw.Close()
+			}
+			res.Errs[idx] = err
+			res.PipeStatus[idx] = Code(err)
+		}(idx, filter, stdin, stdout, pw)
+
+		if next != nil {
+			stdin = next
+		}
+	}
+	wg.Wait()
+
+	res.Status = res.PipeStatus[len(res.PipeStatus)-1]
+	if l.pipefail {
+		for _, status := range res.PipeStatus {
+			if status != 0 && status != BrokenPipe {
+				res.Status = status
+			}
+		}
+	}
+	return res
+}
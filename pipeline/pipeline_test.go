@@ -0,0 +1,222 @@
+// Copyright 2023 Michal Vyskocil. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package pipeline_test
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+	"syscall"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/gomoni/gonix/cat"
+	"github.com/gomoni/gonix/head"
+	"github.com/gomoni/gonix/internal/test"
+	. "github.com/gomoni/gonix/pipeline"
+	"github.com/gomoni/gonix/tail"
+	"github.com/gomoni/gonix/wc"
+
+	"github.com/gomoni/gio/pipe"
+	"github.com/gomoni/gio/unix"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/goleak"
+)
+
+func TestMain(m *testing.M) {
+	goleak.VerifyTestMain(m)
+}
+
+func TestCode(t *testing.T) {
+	test.Parallel(t)
+	testCases := []struct {
+		name     string
+		err      error
+		expected int
+	}{
+		{"nil", nil, 0},
+		{"pipe.Error", pipe.NewErrorf(2, "syntax error"), 2},
+		{"unknown", fmt.Errorf("unknown"), pipe.UnknownError},
+		{"not found", fmt.Errorf("x: %w", exec.ErrNotFound), pipe.NotFound},
+		{"deadline", fmt.Errorf("cat: %w", context.DeadlineExceeded), Timeout},
+		{"canceled", fmt.Errorf("cat: %w", context.Canceled), Interrupted},
+		{"EPIPE", fmt.Errorf("awk: %w", syscall.EPIPE), BrokenPipe},
+		{"closed pipe", io.ErrClosedPipe, BrokenPipe},
+		{"explicit code", pipe.NewErrorf(3, "cat: %w", context.Canceled), 3},
+		{"zero code", pipe.NewError(0, context.DeadlineExceeded), Timeout},
+	}
+	for _, tt := range testCases {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			test.Parallel(t)
+			require.Equal(t, tt.expected, Code(tt.err))
+			if tt.err == nil {
+				require.NoError(t, Error(tt.err))
+			} else {
+				require.Equal(t, tt.expected, pipe.FromError(Error(tt.err)).Code)
+			}
+		})
+	}
+	require.Equal(t, 124, Timeout)
+	require.Equal(t, 130, Interrupted)
+	require.Equal(t, 141, BrokenPipe)
+}
+
+func TestLine(t *testing.T) {
+	test.Parallel(t)
+	testCases := []struct {
+		name       string
+		filters    []unix.Filter
+		expected   string
+		pipeStatus []int
+		status     int
+		pipefail   int
+	}{
+		{
+			name:       "cat | wc -l",
+			filters:    []unix.Filter{cat.New(), wc.New().Lines(true)},
+			expected:   "3\n",
+			pipeStatus: []int{0, 0},
+		},
+		{
+			name:       "false | cat | wc -l",
+			filters:    []unix.Filter{fail{code: 3}, cat.New(), wc.New().Lines(true)},
+			expected:   "0\n",
+			pipeStatus: []int{3, 0, 0},
+			status:     0,
+			pipefail:   3,
+		},
+		{
+			name:       "cat | false",
+			filters:    []unix.Filter{cat.New(), fail{code: 1}},
+			pipeStatus: []int{BrokenPipe, 1},
+			status:     1,
+			pipefail:   1,
+		},
+		{
+			name:       "yes | head -n 1",
+			filters:    []unix.Filter{yes{}, head.New().Lines(1)},
+			expected:   "y\n",
+			pipeStatus: []int{BrokenPipe, 0},
+			status:     0,
+			pipefail:   0,
+		},
+		{
+			name:       "false | yes | head -n 1",
+			filters:    []unix.Filter{fail{code: 2}, yes{}, head.New().Lines(1)},
+			expected:   "y\n",
+			pipeStatus: []int{2, BrokenPipe, 0},
+			status:     0,
+			pipefail:   2,
+		},
+	}
+
+	for _, tt := range testCases {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			test.Parallel(t)
+			for _, pipefail := range []bool{false, true} {
+				var stdout strings.Builder
+				stdio := unix.NewStdio(strings.NewReader("three\nsmall\npigs\n"), &stdout, io.Discard)
+				res := NewLine().Pipefail(pipefail).Run(context.Background(), stdio, tt.filters...)
+				require.Equal(t, tt.expected, stdout.String())
+				require.Equal(t, tt.pipeStatus, res.PipeStatus)
+				status := tt.status
+				if pipefail {
+					status = tt.pipefail
+				}
+				require.Equal(t, status, res.Status)
+				if status == 0 {
+					require.NoError(t, res.Err())
+				} else {
+					require.Equal(t, status, pipe.FromError(res.Err()).Code)
+				}
+			}
+		})
+	}
+}
+
+func TestLineContext(t *testing.T) {
+	test.Parallel(t)
+	stdio := unix.NewStdio(strings.NewReader(""), io.Discard, io.Discard)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	res := NewLine().Run(ctx, stdio, wait{}, wait{})
+	require.Equal(t, []int{Interrupted, Interrupted}, res.PipeStatus)
+	require.Equal(t, Interrupted, res.Status)
+
+	ctx, cancel = context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	res = NewLine().Run(ctx, stdio, wait{})
+	require.Equal(t, []int{Timeout}, res.PipeStatus)
+	require.ErrorIs(t, res.Err(), context.DeadlineExceeded)
+}
+
+// TestLineFollow cancels tail -f, which runs until the context is done
+func TestLineFollow(t *testing.T) {
+	test.Parallel(t)
+	fsys := fstest.MapFS{
+		"log": {Data: []byte("1\n2\n3\n")},
+	}
+	var stdout strings.Builder
+	stdio := unix.NewStdio(strings.NewReader(""), &stdout, io.Discard)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	follow := tail.New().Lines(1).Files("log").FS(fsys).Follow(true).SleepInterval(time.Millisecond)
+	res := NewLine().Run(ctx, stdio, follow, cat.New())
+	require.Equal(t, "3\n", stdout.String())
+	require.Equal(t, Timeout, res.PipeStatus[0])
+	require.ErrorIs(t, res.Errs[0], context.DeadlineExceeded)
+
+	ctx, cancel = context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+	res = NewLine().Run(ctx, stdio, follow, cat.New())
+	require.Equal(t, Interrupted, res.PipeStatus[0])
+	require.ErrorIs(t, res.Errs[0], context.Canceled)
+}
+
+func TestResultErr(t *testing.T) {
+	test.Parallel(t)
+	stdio := unix.NewStdio(strings.NewReader(""), io.Discard, io.Discard)
+	res := NewLine().Pipefail(true).Run(context.Background(), stdio, fail{code: 2}, fail{code: 3})
+	require.Equal(t, []int{2, 3}, res.PipeStatus)
+	err := res.Err()
+	require.Equal(t, 3, pipe.FromError(err).Code)
+	require.Len(t, pipe.Errors(err), 2)
+}
+
+// fail fails with a code
+type fail struct {
+	code int
+}
+
+func (f fail) Run(context.Context, unix.StandardIO) error {
+	return pipe.NewErrorf(f.code, "fail: %d", f.code)
+}
+
+// yes writes y until the write fails
+type yes struct{}
+
+func (yes) Run(ctx context.Context, stdio unix.StandardIO) error {
+	for {
+		_, err := io.WriteString(stdio.Stdout(), "y\n")
+		if err != nil {
+			return fmt.Errorf("yes: %w", err)
+		}
+	}
+}
+
+// wait waits until the context is done
+type wait struct{}
+
+func (wait) Run(ctx context.Context, _ unix.StandardIO) error {
+	<-ctx.Done()
+	return ctx.Err()
+}
@@ -0,0 +1,51 @@
+// Copyright 2023 Michal Vyskocil. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package pipeline
+
+import (
+	"errors"
+
+	"github.com/gomoni/gio/pipe"
+	"github.com/gomoni/gonix/internal"
+)
+
+const (
+	// Signaled is added to a signal number for commands interrupted by a signal
+	Signaled = internal.Signaled
+	// Timeout is a code of timeout(1) for commands out of time, used for
+	// context.DeadlineExceeded
+	Timeout = internal.Timeout
+	// Interrupted is 128+SIGINT, used for context.Canceled
+	Interrupted = internal.Interrupted
+	// BrokenPipe is 128+SIGPIPE, used for writes to a closed reader
+	BrokenPipe = internal.BrokenPipe
+)
+
+// Code maps an error to the exit status. nil is 0 and an explicit code of
+// pipe.Error wins. Otherwise a deadline is Timeout, a cancelation is
+// Interrupted and a write to a closed reader is BrokenPipe. Other errors get
+// the code from pipe.FromError. Filters of gonix return these statuses as
+// explicit codes.
+func Code(err error) int {
+	var e pipe.Error
+	if errors.As(err, &e) && e.Code != 0 {
+		return e.Code
+	}
+	if status := internal.Status(err); status != 0 || err == nil {
+		return status
+	}
+	return pipe.FromError(err).Code
+}
+
+// Error returns pipe.Error with a Code and unwrapped error, so callers using
+// pipe.FromError can tell the causes apart. It returns nil for nil.
+func Error(err error) error {
+	if err == nil {
+		return nil
+	}
+	e := pipe.FromError(err)
+	e.Code = Code(err)
+	return e
+}
@@ -0,0 +1,22 @@
+// Copyright 2023 Michal Vyskocil. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+/*
+sbase runs gonix applets and their suckless sbase counterparts side by side
+and reports divergences in stdout, stderr and exit code.
+
+The reference implementation is the testdata/sbase git submodule, which is
+copied to a temporary directory and built there by the local C compiler, so
+the checkout stays clean. The tests are skipped if the submodule is not
+checked out or there is no cc or make in PATH.
+
+	git submodule update --init sbase/testdata/sbase
+	go test ./sbase/ -v
+
+Error messages differ between implementations, so only a presence of an
+output on stderr is compared and texts are logged. Known differences, like
+wc column widths, are listed in knownDiffs and the output is normalized
+before the comparison.
+*/
+package sbase
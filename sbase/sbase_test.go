@@ -0,0 +1,320 @@
+// Copyright 2023 Michal Vyskocil. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package sbase_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"math/rand"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/gomoni/gio/pipe"
+	"github.com/gomoni/gio/unix"
+	"github.com/gomoni/gonix"
+	"github.com/gomoni/gonix/internal/test"
+	"github.com/stretchr/testify/require"
+
+	_ "github.com/gomoni/gonix/cat"
+	_ "github.com/gomoni/gonix/cksum"
+	_ "github.com/gomoni/gonix/head"
+	_ "github.com/gomoni/gonix/tr"
+	_ "github.com/gomoni/gonix/wc"
+)
+
+// tools are built from sbase and compared with gonix applets
+var tools = []string{"cat", "cksum", "head", "tr", "wc"}
+
+// argvs lists arguments tested for each tool
+var argvs = map[string][][]string{
+	"cat":   {{}, {"-u"}},
+	"cksum": {{}},
+	"head":  {{}, {"-n", "1"}, {"-n", "2"}, {"-n", "100"}},
+	"tr": {
+		{"a-z", "A-Z"},
+		{"[:lower:]", "[:upper:]"},
+		{"-d", "aeiou"},
+		{"-d", "[:space:]"},
+		{"-s", " "},
+		{"-c", "-d", "a-z\\n"},
+		{"-c", "-s", "a-z", "\\n"},
+		{"-d", "-s", "a", "b"},
+	},
+	"wc": {{}, {"-l"}, {"-w"}, {"-c"}, {"-m"}, {"-lw"}},
+}
+
+// knownDiffs are tools with known differences to sbase, their stdout is
+// normalized before the comparison
+var knownDiffs = map[string]func(string) string{
+	// sbase pads every column by %7zu, gonix uses GNU column widths
+	"wc": squeezeSpaces,
+}
+
+// squeezeSpaces trims lines and replaces runs of white spaces by a single space
+func squeezeSpaces(s string) string {
+	lines := strings.SplitAfter(s, "\n")
+	for idx, line := range lines {
+		nl := strings.HasSuffix(line, "\n")
+		lines[idx] = strings.Join(strings.Fields(line), " ")
+		if nl {
+			lines[idx] += "\n"
+		}
+	}
+	return strings.Join(lines, "")
+}
+
+// fixed are inputs with known corner cases
+var fixed = []string{
+	"",
+	"\n",
+	"three\nsmall\npigs\n",
+	"no newline",
+	"\n\n\nempty lines\n\n",
+	"tab\tand  spaces \t \naaabbbccc   ddd\n",
+	"žluťoučký kůň úpěl ďábelské ódy\n",
+	"\x00binary\x01\xff\xfe\n",
+}
+
+var (
+	buildOnce sync.Once
+	buildDir  string
+	binDir    string
+	skip      string
+	buildErr  error
+)
+
+func TestMain(m *testing.M) {
+	code := m.Run()
+	if buildDir != "" {
+		os.RemoveAll(buildDir)
+	}
+	os.Exit(code)
+}
+
+// build copies the submodule to a temporary directory and builds sbase tools
+// there, so the checkout stays clean
+func build(t *testing.T) string {
+	t.Helper()
+	buildOnce.Do(func() {
+		src, err := filepath.Abs(filepath.Join("testdata", "sbase"))
+		if err != nil {
+			buildErr = err
+			return
+		}
+		if _, err := os.Stat(filepath.Join(src, "Makefile")); err != nil {
+			skip = "sbase submodule is not checked out, run git submodule update --init"
+			return
+		}
+		cc, err := exec.LookPath("cc")
+		if err != nil {
+			skip = "cc not found"
+			return
+		}
+		makeBin, err := exec.LookPath("make")
+		if err != nil {
+			skip = "make not found"
+			return
+		}
+		buildDir, err = os.MkdirTemp("", "gonix-sbase-")
+		if err != nil {
+			buildErr = err
+			return
+		}
+		dir := filepath.Join(buildDir, "sbase")
+		if err := copyTree(src, dir); err != nil {
+			buildErr = fmt.Errorf("copying sbase: %w", err)
+			return
+		}
+		cmd := exec.Command(makeBin, append([]string{"-C", dir, "CC=" + cc}, tools...)...)
+		out, err := cmd.CombinedOutput()
+		if err != nil {
+			buildErr = fmt.Errorf("building sbase: %w\n%s", err, out)
+			return
+		}
+		binDir = dir
+	})
+	if skip != "" {
+		t.Skip(skip)
+	}
+	require.NoError(t, buildErr)
+	return binDir
+}
+
+// copyTree copies files and directories from src to dst except .git
+func copyTree(src, dst string) error {
+	return filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.Name() == ".git" {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		switch {
+		case d.IsDir():
+			return os.MkdirAll(target, 0o755)
+		case d.Type()&fs.ModeSymlink != 0:
+			link, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			return os.Symlink(link, target)
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		return os.WriteFile(target, data, info.Mode().Perm())
+	})
+}
+
+// generated returns pseudo random inputs, which are the same for each run
+func generated() []string {
+	words := []string{"three", "small", "pigs", "wolf", "Brick", "HOUSE", "ďábel", "kůň", "日本", "a", "\t", " ", "  "}
+	rnd := rand.New(rand.NewSource(42))
+	ret := make([]string, 0, 4)
+	for _, lines := range []int{1, 10, 100, 1000} {
+		var sb strings.Builder
+		for i := 0; i < lines; i++ {
+			n := rnd.Intn(20)
+			for j := 0; j < n; j++ {
+				if j > 0 {
+					sb.WriteByte(' ')
+				}
+				sb.WriteString(words[rnd.Intn(len(words))])
+			}
+			sb.WriteByte('\n')
+		}
+		ret = append(ret, sb.String())
+	}
+	return ret
+}
+
+type result struct {
+	stdout string
+	stderr string
+	code   int
+}
+
+func runSbase(t *testing.T, dir string, argv []string, input string) result {
+	t.Helper()
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command(filepath.Join(dir, argv[0]), argv[1:]...)
+	cmd.Stdin = strings.NewReader(input)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	err := cmd.Run()
+	var exitErr *exec.ExitError
+	if err != nil && !errors.As(err, &exitErr) {
+		require.NoError(t, err)
+	}
+	return result{stdout: stdout.String(), stderr: stderr.String(), code: cmd.ProcessState.ExitCode()}
+}
+
+func runGonix(argv []string, input string) result {
+	var stdout, stderr strings.Builder
+	code := func() int {
+		filter, err := gonix.New(argv[0], argv[1:])
+		if err == nil {
+			stdio := unix.NewStdio(strings.NewReader(input), &stdout, &stderr)
+			err = filter.Run(context.Background(), stdio)
+		}
+		if err == nil {
+			return 0
+		}
+		e := pipe.FromError(err)
+		if e.Err != nil {
+			fmt.Fprintf(&stderr, "%s\n", e.Err)
+		}
+		return e.Code
+	}()
+	return result{stdout: stdout.String(), stderr: stderr.String(), code: code}
+}
+
+func compare(t *testing.T, dir string, argv []string, input string) {
+	t.Helper()
+	expected := runSbase(t, dir, argv, input)
+	actual := runGonix(argv, input)
+	t.Logf("sbase stderr=%q, gonix stderr=%q", expected.stderr, actual.stderr)
+	if normalize, ok := knownDiffs[argv[0]]; ok {
+		expected.stdout = normalize(expected.stdout)
+		actual.stdout = normalize(actual.stdout)
+	}
+	require.Equal(t, expected.stdout, actual.stdout, "stdout")
+	require.Equal(t, expected.code, actual.code, "exit code")
+	require.Equal(t, expected.stderr != "", actual.stderr != "", "stderr")
+}
+
+func TestConformance(t *testing.T) {
+	test.Parallel(t)
+	dir := build(t)
+
+	inputs := append(append([]string{}, fixed...), generated()...)
+	for _, tool := range tools {
+		for _, args := range argvs[tool] {
+			argv := append([]string{tool}, args...)
+			for idx, input := range inputs {
+				argv, input := argv, input
+				t.Run(fmt.Sprintf("%s/%d", strings.Join(argv, " "), idx), func(t *testing.T) {
+					test.Parallel(t)
+					compare(t, dir, argv, input)
+				})
+			}
+		}
+	}
+}
+
+func TestConformanceFiles(t *testing.T) {
+	test.Parallel(t)
+	dir := build(t)
+
+	tmp := t.TempDir()
+	var files []string
+	for idx, input := range fixed[1:4] {
+		name := filepath.Join(tmp, fmt.Sprintf("file%d", idx))
+		require.NoError(t, os.WriteFile(name, []byte(input), 0600))
+		files = append(files, name)
+	}
+	missing := filepath.Join(tmp, "does-not-exist")
+
+	for _, tool := range []string{"cat", "cksum", "head", "wc"} {
+		for _, names := range [][]string{
+			files[:1],
+			files,
+			{files[0], "-", files[1]},
+			{files[0], missing, files[1]},
+		} {
+			argv := append([]string{tool}, names...)
+			t.Run(strings.Join(argv, " "), func(t *testing.T) {
+				test.Parallel(t)
+				compare(t, dir, argv, "stdin\n")
+			})
+		}
+	}
+}
+
+func TestSqueezeSpaces(t *testing.T) {
+	test.Parallel(t)
+	require.Equal(t, "3 3 17\n1 2 3 -\n", squeezeSpaces("      3       3      17\n1 2\t 3 -\n"))
+	require.Equal(t, "", squeezeSpaces(""))
+}
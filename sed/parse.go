@@ -0,0 +1,516 @@
+// Copyright 2023 Michal Vyskocil. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package sed
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/gomoni/gonix/internal"
+)
+
+type addrKind int
+
+const (
+	addrLine     addrKind = iota // line number
+	addrLast                     // $
+	addrRegexp                   // /re/
+	addrRelative                 // addr,+N
+)
+
+type address struct {
+	kind addrKind
+	line int
+	re   *regexp.Regexp // nil for an empty regexp, which is the last regexp used
+}
+
+// replacement is a part of s/// replacement, either a literal text or a group
+// reference, where & is a group 0
+type replacement struct {
+	text  string
+	group int
+}
+
+type command struct {
+	addr1  *address
+	addr2  *address
+	negate bool
+	name   byte
+
+	text  string // a, i, c
+	label string // b, t, T, :
+	jump  int    // resolved label or the end of block for {
+
+	// s
+	re      *regexp.Regexp
+	repl    []replacement
+	global  bool
+	nth     int
+	print   bool
+	code    int           // q, Q exit code
+	mapping map[rune]rune // y
+}
+
+type parser struct {
+	script   string
+	pos      int
+	extended bool
+	cmds     []command
+}
+
+// parse compiles a sed script
+func parse(script string, extended bool) ([]command, error) {
+	p := &parser{script: script, extended: extended}
+	err := p.parse()
+	if err != nil {
+		return nil, err
+	}
+	return p.cmds, nil
+}
+
+func (p *parser) eof() bool {
+	return p.pos >= len(p.script)
+}
+
+func (p *parser) peek() byte {
+	if p.eof() {
+		return 0
+	}
+	return p.script[p.pos]
+}
+
+func (p *parser) skipSpaces() {
+	for !p.eof() && (p.peek() == ' ' || p.peek() == '\t') {
+		p.pos++
+	}
+}
+
+func (p *parser) errorf(format string, args ...any) error {
+	return fmt.Errorf("char %d: %s", p.pos, fmt.Sprintf(format, args...))
+}
+
+func (p *parser) parse() error {
+	var blocks []int
+	for {
+		for !p.eof() && strings.IndexByte(" \t\n;", p.peek()) != -1 {
+			p.pos++
+		}
+		if p.eof() {
+			break
+		}
+		if p.peek() == '#' {
+			p.skipLine()
+			continue
+		}
+
+		var cmd command
+		var err error
+		cmd.addr1, err = p.address()
+		if err != nil {
+			return err
+		}
+		if cmd.addr1 != nil && p.peek() == ',' {
+			p.pos++
+			p.skipSpaces()
+			if p.peek() == '+' {
+				p.pos++
+				n, ok := p.number()
+				if !ok {
+					return p.errorf("expected number after +")
+				}
+				cmd.addr2 = &address{kind: addrRelative, line: n}
+			} else {
+				cmd.addr2, err = p.address()
+				if err != nil {
+					return err
+				}
+				if cmd.addr2 == nil {
+					return p.errorf("unexpected `,'")
+				}
+			}
+		}
+		if cmd.addr1 != nil && cmd.addr1.kind == addrLine && cmd.addr1.line == 0 {
+			if cmd.addr2 == nil || cmd.addr2.kind != addrRegexp {
+				return p.errorf("invalid usage of line address 0")
+			}
+		}
+
+		p.skipSpaces()
+		for p.peek() == '!' {
+			cmd.negate = true
+			p.pos++
+			p.skipSpaces()
+		}
+		if p.eof() {
+			return p.errorf("missing command")
+		}
+		cmd.name = p.peek()
+		p.pos++
+
+		switch cmd.name {
+		case '{':
+			blocks = append(blocks, len(p.cmds))
+		case '}':
+			if cmd.addr1 != nil || cmd.negate {
+				return p.errorf("} doesn't want any addresses")
+			}
+			if len(blocks) == 0 {
+				return p.errorf("unexpected `}'")
+			}
+			p.cmds[blocks[len(blocks)-1]].jump = len(p.cmds) + 1
+			blocks = blocks[:len(blocks)-1]
+			err = p.end()
+		case '=', 'd', 'D', 'g', 'G', 'h', 'H', 'n', 'N', 'p', 'P', 'x':
+			err = p.end()
+		case 'a', 'i', 'c':
+			cmd.text = p.text()
+		case ':':
+			if cmd.addr1 != nil {
+				return p.errorf(": doesn't want any addresses")
+			}
+			cmd.label = p.label()
+			if cmd.label == "" {
+				return p.errorf("\":\" lacks a label")
+			}
+		case 'b', 't', 'T':
+			cmd.label = p.label()
+		case 'q', 'Q':
+			if cmd.addr2 != nil {
+				return p.errorf("command only uses one address")
+			}
+			p.skipSpaces()
+			cmd.code, _ = p.number()
+			err = p.end()
+		case 's':
+			err = p.substitute(&cmd)
+		case 'y':
+			err = p.transliterate(&cmd)
+		default:
+			return p.errorf("unknown command: `%c'", cmd.name)
+		}
+		if err != nil {
+			return err
+		}
+		p.cmds = append(p.cmds, cmd)
+	}
+
+	if len(blocks) > 0 {
+		return p.errorf("unmatched `{'")
+	}
+	return p.resolveLabels()
+}
+
+func (p *parser) resolveLabels() error {
+	labels := make(map[string]int)
+	for idx, cmd := range p.cmds {
+		if cmd.name == ':' {
+			if _, dup := labels[cmd.label]; dup {
+				return fmt.Errorf("duplicate label `%s'", cmd.label)
+			}
+			labels[cmd.label] = idx
+		}
+	}
+	for idx, cmd := range p.cmds {
+		switch cmd.name {
+		case 'b', 't', 'T':
+			if cmd.label == "" {
+				p.cmds[idx].jump = len(p.cmds)
+				continue
+			}
+			jump, ok := labels[cmd.label]
+			if !ok {
+				return fmt.Errorf("can't find label for jump to `%s'", cmd.label)
+			}
+			p.cmds[idx].jump = jump
+		}
+	}
+	return nil
+}
+
+// end checks there is nothing after a command
+func (p *parser) end() error {
+	p.skipSpaces()
+	switch p.peek() {
+	case 0, '\n', ';':
+		return nil
+	case '}', '#':
+		return nil
+	}
+	return p.errorf("extra characters after command")
+}
+
+func (p *parser) skipLine() {
+	for !p.eof() && p.peek() != '\n' {
+		p.pos++
+	}
+}
+
+func (p *parser) number() (int, bool) {
+	start := p.pos
+	for !p.eof() && p.peek() >= '0' && p.peek() <= '9' {
+		p.pos++
+	}
+	if start == p.pos {
+		return 0, false
+	}
+	n, err := strconv.Atoi(p.script[start:p.pos])
+	return n, err == nil
+}
+
+func (p *parser) address() (*address, error) {
+	switch c := p.peek(); {
+	case c >= '0' && c <= '9':
+		n, _ := p.number()
+		return &address{kind: addrLine, line: n}, nil
+	case c == '$':
+		p.pos++
+		return &address{kind: addrLast}, nil
+	case c == '/' || c == '\\':
+		p.pos++
+		delim := byte('/')
+		if c == '\\' {
+			if p.eof() {
+				return nil, p.errorf("unexpected end of expression")
+			}
+			delim = p.peek()
+			p.pos++
+		}
+		pattern, err := p.delimited(delim, true, "address regex")
+		if err != nil {
+			return nil, err
+		}
+		var flags string
+		for p.peek() == 'I' {
+			flags = "(?i)"
+			p.pos++
+		}
+		re, err := p.compile(pattern, flags)
+		if err != nil {
+			return nil, err
+		}
+		return &address{kind: addrRegexp, re: re}, nil
+	}
+	return nil, nil
+}
+
+// delimited reads text until an unescaped delimiter, \delim is unescaped, other escapes
+// are kept for the regexp or replacement parser
+func (p *parser) delimited(delim byte, isRegexp bool, what string) (string, error) {
+	var sb strings.Builder
+	for !p.eof() {
+		c := p.peek()
+		p.pos++
+		switch {
+		case c == delim:
+			return sb.String(), nil
+		case c == '\\' && !p.eof():
+			n := p.peek()
+			p.pos++
+			switch {
+			case n == delim:
+				sb.WriteByte(n)
+			case n == '\n':
+				if isRegexp {
+					sb.WriteString(`\n`)
+				} else {
+					sb.WriteString("\\\n")
+				}
+			default:
+				sb.WriteByte('\\')
+				sb.WriteByte(n)
+			}
+		case c == '\n' && isRegexp:
+			return "", p.errorf("unterminated %s", what)
+		default:
+			sb.WriteByte(c)
+		}
+	}
+	return "", p.errorf("unterminated %s", what)
+}
+
+// compile compiles the regular expression, an empty pattern returns nil
+func (p *parser) compile(pattern string, flags string) (*regexp.Regexp, error) {
+	if pattern == "" {
+		return nil, nil
+	}
+	re, err := regexp.Compile(flags + internal.ToRE2(pattern, !p.extended))
+	if err != nil {
+		return nil, p.errorf("%s", err)
+	}
+	return re, nil
+}
+
+// text reads the text argument of a, i and c commands in both one-liner
+// GNU form a text and the POSIX form a\<newline>text
+func (p *parser) text() string {
+	p.skipSpaces()
+	if p.peek() == '\\' {
+		p.pos++
+		if p.peek() == '\n' {
+			p.pos++
+		}
+	}
+
+	var sb strings.Builder
+	for !p.eof() {
+		c := p.peek()
+		p.pos++
+		if c == '\n' {
+			break
+		}
+		if c == '\\' && !p.eof() {
+			c = p.peek()
+			p.pos++
+		}
+		sb.WriteByte(c)
+	}
+	return sb.String()
+}
+
+func (p *parser) label() string {
+	p.skipSpaces()
+	start := p.pos
+	for !p.eof() && p.peek() != '\n' && p.peek() != ';' {
+		p.pos++
+	}
+	return strings.TrimRight(p.script[start:p.pos], " \t")
+}
+
+func (p *parser) substitute(cmd *command) error {
+	if p.eof() || p.peek() == '\n' || p.peek() == '\\' {
+		return p.errorf("unterminated `s' command")
+	}
+	delim := p.peek()
+	p.pos++
+	pattern, err := p.delimited(delim, true, "`s' command")
+	if err != nil {
+		return err
+	}
+	repl, err := p.delimited(delim, false, "`s' command")
+	if err != nil {
+		return err
+	}
+	cmd.repl = parseReplacement(repl)
+
+	var flags string
+loop:
+	for !p.eof() {
+		switch c := p.peek(); {
+		case c == 'g':
+			cmd.global = true
+		case c == 'p':
+			cmd.print = true
+		case c == 'I' || c == 'i':
+			flags = "(?i)"
+		case c >= '1' && c <= '9':
+			if cmd.nth != 0 {
+				return p.errorf("multiple number options to `s' command")
+			}
+			cmd.nth, _ = p.number()
+			continue
+		case c == 'w' || c == 'e':
+			return p.errorf("unsupported `s' flag: `%c'", c)
+		default:
+			break loop
+		}
+		p.pos++
+	}
+	if cmd.nth == 0 {
+		cmd.nth = 1
+	}
+	cmd.re, err = p.compile(pattern, flags)
+	if err != nil {
+		return err
+	}
+	if pattern == "" && flags != "" {
+		return p.errorf("no previous regular expression")
+	}
+	return p.end()
+}
+
+func parseReplacement(s string) []replacement {
+	var ret []replacement
+	var sb strings.Builder
+	flush := func() {
+		if sb.Len() > 0 {
+			ret = append(ret, replacement{text: sb.String(), group: -1})
+			sb.Reset()
+		}
+	}
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '&':
+			flush()
+			ret = append(ret, replacement{group: 0})
+		case c == '\\' && i+1 < len(s):
+			i++
+			n := s[i]
+			switch {
+			case n >= '0' && n <= '9':
+				flush()
+				ret = append(ret, replacement{group: int(n - '0')})
+			case n == 'n':
+				sb.WriteByte('\n')
+			case n == 't':
+				sb.WriteByte('\t')
+			default:
+				sb.WriteByte(n)
+			}
+		default:
+			sb.WriteByte(c)
+		}
+	}
+	flush()
+	return ret
+}
+
+func (p *parser) transliterate(cmd *command) error {
+	if p.eof() || p.peek() == '\n' || p.peek() == '\\' {
+		return p.errorf("unterminated `y' command")
+	}
+	delim := p.peek()
+	p.pos++
+	src, err := p.delimited(delim, false, "`y' command")
+	if err != nil {
+		return err
+	}
+	dst, err := p.delimited(delim, false, "`y' command")
+	if err != nil {
+		return err
+	}
+	from := []rune(unescapeY(src))
+	to := []rune(unescapeY(dst))
+	if len(from) != len(to) {
+		return p.errorf("strings for `y' command are different lengths")
+	}
+	cmd.mapping = make(map[rune]rune, len(from))
+	for idx, r := range from {
+		cmd.mapping[r] = to[idx]
+	}
+	return p.end()
+}
+
+func unescapeY(s string) string {
+	var sb strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+1 < len(s) {
+			i++
+			switch s[i] {
+			case 'n':
+				sb.WriteByte('\n')
+			case 't':
+				sb.WriteByte('\t')
+			default:
+				sb.WriteByte(s[i])
+			}
+			continue
+		}
+		sb.WriteByte(s[i])
+	}
+	return sb.String()
+}
@@ -0,0 +1,642 @@
+// Copyright 2023 Michal Vyskocil. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+/*
+sed is a stream editor for filtering and transforming text
+
+	sed [-nEz] [-e script]... [-f script-file]... [script] [file]...
+
+	-n/--quiet             suppress automatic printing of pattern space
+	-e/--expression        add the script to the commands to be executed
+	-f/--file              add the contents of script-file to the commands
+	-E/-r/--regexp-extended  use extended regular expressions (Go RE2 syntax)
+	-z/--null-data         separate lines by NUL characters
+
+Supported addresses are a line number, $, /regexp/, \cregexpc with an
+optional I flag, ranges addr1,addr2, 0,/regexp/ and addr1,+N. An address can
+be negated by !.
+
+Supported commands are {}, =, a, b, c, d, D, g, G, h, H, i, n, N, p, P, q, Q,
+s, t, T, x, y, : and #. The s command supports flags g, p, N and I, and the
+replacement can refer to the match by & and to groups by \1 to \9.
+
+Regular expressions are basic POSIX ones translated to Go RE2, so back
+references in patterns are not supported.
+*/
+package sed
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/gomoni/gio/pipe"
+	"github.com/gomoni/gio/unix"
+	"github.com/gomoni/gonix"
+	"github.com/gomoni/gonix/internal"
+	"github.com/gomoni/gonix/internal/dbg"
+	"github.com/spf13/pflag"
+)
+
+type Sed struct {
+	debug          bool
+	scripts        []string
+	quiet          bool
+	extended       bool
+	zeroTerminated bool
+	fsys           fs.FS
+	files          []string
+}
+
+func New(scripts ...string) Sed {
+	return Sed{scripts: scripts}
+}
+
+func init() {
+	gonix.Register(gonix.Cmd{
+		Name:  "sed",
+		Usage: "stream editor for filtering and transforming text",
+		New:   gonix.FromArgs(New().FromArgs),
+	})
+}
+
+// FromArgs build a Sed from standard argv except the command name (os.Argv[1:])
+func (c Sed) FromArgs(argv []string) (Sed, error) {
+	flag := pflag.FlagSet{}
+
+	flag.BoolVarP(&c.quiet, "quiet", "n", false, "suppress automatic printing of pattern space")
+	flag.BoolVar(&c.quiet, "silent", false, "suppress automatic printing of pattern space")
+	c.scripts = nil
+	flag.VarP(scriptValue{scripts: &c.scripts}, "expression", "e", "add the script to the commands to be executed")
+	flag.VarP(scriptValue{scripts: &c.scripts, file: true}, "file", "f", "add the contents of script-file to the commands to be executed")
+	flag.BoolVarP(&c.extended, "regexp-extended", "E", false, "use extended regular expressions in the script")
+	flag.BoolVarP(&c.extended, "extended", "r", false, "use extended regular expressions in the script")
+	flag.BoolVarP(&c.zeroTerminated, "null-data", "z", false, "separate lines by NUL characters")
+
+	err := flag.Parse(argv)
+	if err != nil {
+		return Sed{}, pipe.NewErrorf(1, "sed: parsing failed: %w", err)
+	}
+
+	args := flag.Args()
+	if len(c.scripts) == 0 {
+		if len(args) == 0 {
+			return Sed{}, pipe.NewErrorf(1, "sed: no script specified")
+		}
+		c.scripts = args[:1]
+		args = args[1:]
+	}
+
+	_, err = parse(strings.Join(c.scripts, "\n"), c.extended)
+	if err != nil {
+		return Sed{}, pipe.NewErrorf(1, "sed: %w", err)
+	}
+
+	if len(args) > 0 {
+		c.files = args
+	}
+	return c, nil
+}
+
+// scriptValue collects -e and -f in the order given
+type scriptValue struct {
+	scripts *[]string
+	file    bool
+}
+
+func (v scriptValue) String() string {
+	return ""
+}
+
+func (v scriptValue) Type() string {
+	if v.file {
+		return "script-file"
+	}
+	return "script"
+}
+
+func (v scriptValue) Set(value string) error {
+	if v.file {
+		b, err := os.ReadFile(value)
+		if err != nil {
+			return err
+		}
+		value = strings.TrimSuffix(string(b), "\n")
+	}
+	*v.scripts = append(*v.scripts, value)
+	return nil
+}
+
+// Files are input files, where - denotes stdin
+func (c Sed) Files(f ...string) Sed {
+	c.files = append(c.files, f...)
+	return c
+}
+
+// FS sets a filesystem used to open files, nil means the OS filesystem
+func (c Sed) FS(fsys fs.FS) Sed {
+	c.fsys = fsys
+	return c
+}
+
+// Scripts adds scripts to the commands to be executed
+func (c Sed) Scripts(scripts ...string) Sed {
+	c.scripts = append(c.scripts, scripts...)
+	return c
+}
+
+// Quiet suppresses automatic printing of pattern space
+func (c Sed) Quiet(b bool) Sed {
+	c.quiet = b
+	return c
+}
+
+// Extended uses Go RE2 syntax instead of POSIX basic regular expressions
+func (c Sed) Extended(b bool) Sed {
+	c.extended = b
+	return c
+}
+
+func (c Sed) ZeroTerminated(zeroTerminated bool) Sed {
+	c.zeroTerminated = zeroTerminated
+	return c
+}
+
+func (c Sed) SetDebug(debug bool) Sed {
+	c.debug = debug
+	return c
+}
+
+func (c Sed) Run(ctx context.Context, stdio unix.StandardIO) error {
+	debug := dbg.Logger(c.debug, "sed", stdio.Stderr())
+	debug.Printf("c=%+v", c)
+
+	cmds, err := parse(strings.Join(c.scripts, "\n"), c.extended)
+	if err != nil {
+		return pipe.NewErrorf(1, "sed: %w", err)
+	}
+
+	delim := byte('\n')
+	if c.zeroTerminated {
+		delim = 0
+	}
+	out := &output{w: bufio.NewWriter(stdio.Stdout()), delim: delim}
+	in := &input{
+		fsys:   c.fsys,
+		files:  c.files,
+		stdin:  stdio.Stdin(),
+		stderr: stdio.Stderr(),
+		delim:  delim,
+	}
+	if len(in.files) == 0 {
+		in.files = []string{"-"}
+	}
+	defer in.close()
+
+	s := &sed{
+		cmds:   cmds,
+		quiet:  c.quiet,
+		in:     in,
+		out:    out,
+		delim:  delim,
+		ranges: make([]rangeState, len(cmds)),
+	}
+	code, err := s.run(ctx)
+	if ferr := out.w.Flush(); err == nil {
+		err = ferr
+	}
+	if err != nil {
+		return internal.NewError(1, fmt.Errorf("sed: fail to run: %w", err))
+	}
+	if len(in.errs) > 0 {
+		return pipe.NewError(2, errors.Join(in.errs...))
+	}
+	if code != 0 {
+		return pipe.NewErrorf(code, "sed: exit status %d", code)
+	}
+	return nil
+}
+
+// line is a line of input, last line of a file may not have the delimiter
+type line struct {
+	text  []byte
+	delim bool
+}
+
+// input reads lines from all files as one stream, it reads a line ahead, so the
+// last line $ is known
+type input struct {
+	fsys   fs.FS
+	files  []string
+	stdin  io.Reader
+	stderr io.Writer
+	delim  byte
+	errs   []error
+
+	r      *bufio.Reader
+	closer io.Closer
+	next   *line
+	err    error
+}
+
+func (in *input) close() {
+	if in.closer != nil {
+		in.closer.Close()
+		in.closer = nil
+	}
+}
+
+// fill reads the next line, opens next file when needed
+func (in *input) fill() {
+	for in.next == nil && in.err == nil {
+		if in.r == nil {
+			if len(in.files) == 0 {
+				return
+			}
+			name := in.files[0]
+			in.files = in.files[1:]
+			if name == "" || name == "-" {
+				in.r = bufio.NewReader(in.stdin)
+			} else {
+				f, err := internal.Open(in.fsys, name)
+				if err != nil {
+					fmt.Fprintf(in.stderr, "sed: %s\n", err)
+					in.errs = append(in.errs, err)
+					continue
+				}
+				in.r = bufio.NewReader(f)
+				in.closer = f
+			}
+		}
+		text, err := in.r.ReadBytes(in.delim)
+		if len(text) > 0 {
+			l := line{text: text, delim: text[len(text)-1] == in.delim}
+			if l.delim {
+				l.text = text[:len(text)-1]
+			}
+			in.next = &l
+		}
+		if errors.Is(err, io.EOF) {
+			in.close()
+			in.r = nil
+		} else if err != nil {
+			in.err = err
+		}
+	}
+}
+
+// read returns the next line, false if there is none
+func (in *input) read() (line, bool, error) {
+	in.fill()
+	if in.next == nil {
+		return line{}, false, in.err
+	}
+	l := *in.next
+	in.next = nil
+	return l, true, nil
+}
+
+// last reports if there is no next line
+func (in *input) last() bool {
+	in.fill()
+	return in.next == nil
+}
+
+// output writes lines and adds the missing delimiter when anything follows
+// the last line without one
+type output struct {
+	w       *bufio.Writer
+	delim   byte
+	missing bool
+}
+
+func (o *output) write(text []byte, delim bool) {
+	if o.missing {
+		o.w.WriteByte(o.delim)
+	}
+	o.w.Write(text)
+	if delim {
+		o.w.WriteByte(o.delim)
+	}
+	o.missing = !delim
+}
+
+type rangeState struct {
+	active bool
+	end    int  // the last line of addr1,+N or addr1,N ranges
+	done   bool // the range 0,/re/ is over and must not start again
+}
+
+// sed is a state of the interpreter
+type sed struct {
+	cmds   []command
+	quiet  bool
+	in     *input
+	out    *output
+	delim  byte
+	ranges []rangeState
+
+	lineNo   int
+	ps       []byte // pattern space
+	psDelim  bool   // pattern space ended with a delimiter
+	hold     []byte
+	appended [][]byte // text of a commands
+	replaced bool     // for t command
+	lastRe   *regexp.Regexp
+	restart  bool // D restarts the cycle without reading a new line
+}
+
+// run runs the script over all input lines and returns the exit code of q or Q
+func (s *sed) run(ctx context.Context) (int, error) {
+	for {
+		if ctx.Err() != nil {
+			return 0, ctx.Err()
+		}
+		if s.restart {
+			s.restart = false
+		} else {
+			l, ok, err := s.in.read()
+			if err != nil {
+				return 0, err
+			}
+			if !ok {
+				return 0, nil
+			}
+			s.lineNo++
+			s.ps = l.text
+			s.psDelim = l.delim
+			s.replaced = false
+		}
+
+		code, quit, err := s.cycle()
+		if err != nil || quit {
+			return code, err
+		}
+	}
+}
+
+// cycle runs commands over the pattern space
+func (s *sed) cycle() (code int, quit bool, err error) {
+	autoprint := !s.quiet
+	for pc := 0; pc < len(s.cmds); {
+		cmd := &s.cmds[pc]
+		if !s.selected(pc) {
+			if cmd.name == '{' {
+				pc = cmd.jump
+			} else {
+				pc++
+			}
+			continue
+		}
+
+		switch cmd.name {
+		case '{', '}', ':':
+		case '=':
+			s.out.write([]byte(fmt.Sprintf("%d", s.lineNo)), true)
+		case 'a':
+			s.appended = append(s.appended, []byte(cmd.text))
+		case 'i':
+			s.out.write([]byte(cmd.text), true)
+		case 'c':
+			// with a range print the text at the end of it
+			if cmd.addr2 == nil || cmd.negate || !s.ranges[pc].active {
+				s.out.write([]byte(cmd.text), true)
+			}
+			s.endCycle(false)
+			return 0, false, nil
+		case 'b':
+			pc = cmd.jump
+			continue
+		case 't', 'T':
+			if s.replaced == (cmd.name == 't') {
+				s.replaced = false
+				pc = cmd.jump
+				continue
+			}
+			s.replaced = false
+		case 'd':
+			s.endCycle(false)
+			return 0, false, nil
+		case 'D':
+			idx := bytes.IndexByte(s.ps, s.delim)
+			if idx == -1 {
+				s.endCycle(false)
+				return 0, false, nil
+			}
+			s.ps = append([]byte(nil), s.ps[idx+1:]...)
+			s.restart = true
+			s.endCycle(false)
+			return 0, false, nil
+		case 'g':
+			s.ps = append([]byte(nil), s.hold...)
+		case 'G':
+			s.ps = append(append(s.ps, s.delim), s.hold...)
+		case 'h':
+			s.hold = append([]byte(nil), s.ps...)
+		case 'H':
+			s.hold = append(append(s.hold, s.delim), s.ps...)
+		case 'x':
+			s.ps, s.hold = s.hold, s.ps
+		case 'n':
+			if s.in.last() {
+				s.endCycle(autoprint)
+				return 0, true, nil
+			}
+			if autoprint {
+				s.out.write(s.ps, s.psDelim)
+			}
+			s.flushAppended()
+			l, _, err := s.in.read()
+			if err != nil {
+				return 0, true, err
+			}
+			s.lineNo++
+			s.ps = l.text
+			s.psDelim = l.delim
+		case 'N':
+			if s.in.last() {
+				s.endCycle(autoprint)
+				return 0, true, nil
+			}
+			s.flushAppended()
+			l, _, err := s.in.read()
+			if err != nil {
+				return 0, true, err
+			}
+			s.lineNo++
+			s.ps = append(append(s.ps, s.delim), l.text...)
+			s.psDelim = l.delim
+		case 'p':
+			s.out.write(s.ps, true)
+		case 'P':
+			idx := bytes.IndexByte(s.ps, s.delim)
+			if idx == -1 {
+				idx = len(s.ps)
+			}
+			s.out.write(s.ps[:idx], true)
+		case 'q':
+			s.endCycle(autoprint)
+			return cmd.code, true, nil
+		case 'Q':
+			return cmd.code, true, nil
+		case 's':
+			re, err := s.lastRegexp(cmd.re)
+			if err != nil {
+				return 0, true, err
+			}
+			if s.substitute(re, cmd) && cmd.print {
+				s.out.write(s.ps, true)
+			}
+		case 'y':
+			var sb strings.Builder
+			for _, r := range string(s.ps) {
+				if to, ok := cmd.mapping[r]; ok {
+					r = to
+				}
+				sb.WriteRune(r)
+			}
+			s.ps = []byte(sb.String())
+		}
+		pc++
+	}
+	s.endCycle(autoprint)
+	return 0, false, nil
+}
+
+// endCycle prints the pattern space and appended text
+func (s *sed) endCycle(print bool) {
+	if print {
+		s.out.write(s.ps, s.psDelim)
+	}
+	s.flushAppended()
+}
+
+func (s *sed) flushAppended() {
+	for _, text := range s.appended {
+		s.out.write(text, true)
+	}
+	s.appended = s.appended[:0]
+}
+
+// lastRegexp returns re or the last used regexp for an empty one
+func (s *sed) lastRegexp(re *regexp.Regexp) (*regexp.Regexp, error) {
+	if re == nil {
+		if s.lastRe == nil {
+			return nil, errors.New("no previous regular expression")
+		}
+		return s.lastRe, nil
+	}
+	s.lastRe = re
+	return re, nil
+}
+
+func (s *sed) matchAddr(a *address) bool {
+	switch a.kind {
+	case addrLine:
+		return s.lineNo == a.line
+	case addrLast:
+		return s.in.last()
+	case addrRegexp:
+		re, err := s.lastRegexp(a.re)
+		if err != nil {
+			return false
+		}
+		return re.Match(s.ps)
+	}
+	return false
+}
+
+// selected reports if the command at pc applies to the current line
+func (s *sed) selected(pc int) bool {
+	cmd := &s.cmds[pc]
+	if cmd.addr1 == nil {
+		return true
+	}
+	if cmd.addr2 == nil {
+		return s.matchAddr(cmd.addr1) != cmd.negate
+	}
+
+	r := &s.ranges[pc]
+	zero := cmd.addr1.kind == addrLine && cmd.addr1.line == 0
+	var ret bool
+	switch {
+	case r.active:
+		ret = true
+		switch cmd.addr2.kind {
+		case addrLine, addrRelative:
+			if s.lineNo >= r.end {
+				r.active = false
+			}
+		default:
+			if s.matchAddr(cmd.addr2) {
+				r.active = false
+			}
+		}
+	case zero && !r.done || !zero && s.matchAddr(cmd.addr1):
+		ret = true
+		r.active = true
+		switch cmd.addr2.kind {
+		case addrLine:
+			r.end = cmd.addr2.line
+			if r.end <= s.lineNo {
+				r.active = false
+			}
+		case addrRelative:
+			r.end = s.lineNo + cmd.addr2.line
+			if r.end <= s.lineNo {
+				r.active = false
+			}
+		case addrLast:
+			if s.in.last() {
+				r.active = false
+			}
+		case addrRegexp:
+			// 0,/re/ can end the range on the first line
+			if zero && s.matchAddr(cmd.addr2) {
+				r.active = false
+			}
+		}
+		r.done = zero
+	}
+	return ret != cmd.negate
+}
+
+// substitute runs s command, reports if any replacement was made
+func (s *sed) substitute(re *regexp.Regexp, cmd *command) bool {
+	matches := re.FindAllSubmatchIndex(s.ps, -1)
+	if len(matches) < cmd.nth {
+		return false
+	}
+
+	var buf []byte
+	prev := 0
+	for idx, m := range matches {
+		n := idx + 1
+		if n < cmd.nth || (n > cmd.nth && !cmd.global) {
+			continue
+		}
+		buf = append(buf, s.ps[prev:m[0]]...)
+		for _, r := range cmd.repl {
+			if r.group < 0 {
+				buf = append(buf, r.text...)
+				continue
+			}
+			if 2*r.group+1 < len(m) && m[2*r.group] >= 0 {
+				buf = append(buf, s.ps[m[2*r.group]:m[2*r.group+1]]...)
+			}
+		}
+		prev = m[1]
+	}
+	buf = append(buf, s.ps[prev:]...)
+	s.ps = buf
+	s.replaced = true
+	return true
+}
@@ -0,0 +1,276 @@
+// Copyright 2023 Michal Vyskocil. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package sed_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gomoni/gio/pipe"
+	"github.com/gomoni/gio/unix"
+	"github.com/gomoni/gonix/internal/test"
+	. "github.com/gomoni/gonix/sed"
+	"github.com/stretchr/testify/require"
+)
+
+const pigs = "three\nsmall\npigs\n"
+
+func TestSed(t *testing.T) {
+	test.Parallel(t)
+	testCases := []test.Case[Sed]{
+		{
+			Name:     "s///",
+			Filter:   New("s/s/S/"),
+			FromArgs: fromArgs(t, []string{"s/s/S/"}),
+			Input:    "sss\n",
+			Expected: "Sss\n",
+		},
+		{
+			Name:     "s///g",
+			Filter:   New("s/s/S/g"),
+			FromArgs: fromArgs(t, []string{"-e", "s/s/S/g"}),
+			Input:    "sss\n",
+			Expected: "SSS\n",
+		},
+		{
+			Name:     "s///2",
+			Filter:   New("s/s/S/2"),
+			FromArgs: fromArgs(t, []string{"s/s/S/2"}),
+			Input:    "sss\n",
+			Expected: "sSs\n",
+		},
+		{
+			Name:     "s///2g",
+			Filter:   New("s/s/S/2g"),
+			FromArgs: fromArgs(t, []string{"s/s/S/2g"}),
+			Input:    "sss\n",
+			Expected: "sSS\n",
+		},
+		{
+			Name:     "-n s///pI",
+			Filter:   New("s/SMALL/big/pI").Quiet(true),
+			FromArgs: fromArgs(t, []string{"-n", "s/SMALL/big/pI"}),
+			Input:    pigs,
+			Expected: "big\n",
+		},
+		{
+			Name:     "s basic groups",
+			Filter:   New(`s/\(.\)\(.*\)/\2\1 [&]/`),
+			FromArgs: fromArgs(t, []string{`s/\(.\)\(.*\)/\2\1 [&]/`}),
+			Input:    "pigs\n",
+			Expected: "igsp [pigs]\n",
+		},
+		{
+			Name:     "-E s extended groups",
+			Filter:   New(`s|(a+)(b+)|\2\1\n|`).Extended(true),
+			FromArgs: fromArgs(t, []string{"-E", `s|(a+)(b+)|\2\1\n|`}),
+			Input:    "aabbb\n",
+			Expected: "bbbaa\n\n",
+		},
+		{
+			Name:     "line address",
+			Filter:   New("2d"),
+			FromArgs: fromArgs(t, []string{"2d"}),
+			Input:    pigs,
+			Expected: "three\npigs\n",
+		},
+		{
+			Name:     "last line",
+			Filter:   New("$!d"),
+			FromArgs: fromArgs(t, []string{"$!d"}),
+			Input:    pigs,
+			Expected: "pigs\n",
+		},
+		{
+			Name:     "regexp range",
+			Filter:   New("/^t/,/^s/d"),
+			FromArgs: fromArgs(t, []string{"/^t/,/^s/d"}),
+			Input:    "one\n" + pigs,
+			Expected: "one\npigs\n",
+		},
+		{
+			Name:     "0,/re/",
+			Filter:   New("0,/s/s//S/"),
+			FromArgs: fromArgs(t, []string{"0,/s/s//S/"}),
+			Input:    "s\ns\n",
+			Expected: "S\ns\n",
+		},
+		{
+			Name:     "addr,+N",
+			Filter:   New("/one/,+1d"),
+			FromArgs: fromArgs(t, []string{"/one/,+1d"}),
+			Input:    "one\ntwo\nthree\none\nfour\nfive\n",
+			Expected: "three\nfive\n",
+		},
+		{
+			Name:     "2,1p",
+			Filter:   New("2,1p").Quiet(true),
+			FromArgs: fromArgs(t, []string{"-n", "2,1p"}),
+			Input:    pigs,
+			Expected: "small\n",
+		},
+		{
+			Name:     "block",
+			Filter:   New("/s/{s/s/S/;s/a/A/}"),
+			FromArgs: fromArgs(t, []string{"/s/{s/s/S/;s/a/A/}"}),
+			Input:    pigs,
+			Expected: "three\nSmAll\npigS\n",
+		},
+		{
+			Name:     "n and p",
+			Filter:   New("n;d"),
+			FromArgs: fromArgs(t, []string{"n;d"}),
+			Input:    "1\n2\n3\n4\n5\n",
+			Expected: "1\n3\n5\n",
+		},
+		{
+			Name:     "N joins lines",
+			Filter:   New("$!N;s/\\n/ /"),
+			FromArgs: fromArgs(t, []string{"$!N;s/\\n/ /"}),
+			Input:    "1\n2\n3\n",
+			Expected: "1 2\n3\n",
+		},
+		{
+			Name:     "N P D",
+			Filter:   New("$!N;P;D"),
+			FromArgs: fromArgs(t, []string{"$!N;P;D"}),
+			Input:    pigs,
+			Expected: pigs,
+		},
+		{
+			Name:     "tac via hold space",
+			Filter:   New("1!G;h;$!d"),
+			FromArgs: fromArgs(t, []string{"1!G;h;$!d"}),
+			Input:    pigs,
+			Expected: "pigs\nsmall\nthree\n",
+		},
+		{
+			Name:     "x and H",
+			Filter:   New("H;$!d;x"),
+			FromArgs: fromArgs(t, []string{"H;$!d;x"}),
+			Input:    "1\n2\n",
+			Expected: "\n1\n2\n",
+		},
+		{
+			Name:     "y",
+			Filter:   New("y/abc/xyz/"),
+			FromArgs: fromArgs(t, []string{"y/abc/xyz/"}),
+			Input:    "aabbcc\n",
+			Expected: "xxyyzz\n",
+		},
+		{
+			Name:     "a i c",
+			Filter:   New("1i\\\nbefore", "2a after", "3c\\", "changed"),
+			FromArgs: fromArgs(t, []string{"-e", "1i\\\nbefore", "-e", "2a after", "-e", "3c\\", "-e", "changed"}),
+			Input:    pigs,
+			Expected: "before\nthree\nsmall\nafter\nchanged\n",
+		},
+		{
+			Name:     "c range",
+			Filter:   New("1,2c gone"),
+			FromArgs: fromArgs(t, []string{"1,2c gone"}),
+			Input:    pigs,
+			Expected: "gone\npigs\n",
+		},
+		{
+			Name:     "q",
+			Filter:   New("2q"),
+			FromArgs: fromArgs(t, []string{"2q"}),
+			Input:    pigs,
+			Expected: "three\nsmall\n",
+		},
+		{
+			Name:     "labels and t",
+			Filter:   New(":a;s/^.\\{1,4\\}$/ &/;ta"),
+			FromArgs: fromArgs(t, []string{":a;s/^.\\{1,4\\}$/ &/;ta"}),
+			Input:    "1\n22\n",
+			Expected: "    1\n   22\n",
+		},
+		{
+			Name:     "b to end",
+			Filter:   New("/small/b;s/.*/X/"),
+			FromArgs: fromArgs(t, []string{"/small/b;s/.*/X/"}),
+			Input:    pigs,
+			Expected: "X\nsmall\nX\n",
+		},
+		{
+			Name:     "= and #",
+			Filter:   New("# line numbers\n$=").Quiet(true),
+			FromArgs: fromArgs(t, []string{"-n", "# line numbers\n$="}),
+			Input:    pigs,
+			Expected: "3\n",
+		},
+		{
+			Name:     "-z",
+			Filter:   New("s/^/>/").ZeroTerminated(true),
+			FromArgs: fromArgs(t, []string{"-z", "s/^/>/"}),
+			Input:    "a\nb\x00c\x00",
+			Expected: ">a\nb\x00>c\x00",
+		},
+		{
+			Name:     "missing newline",
+			Filter:   New("p"),
+			FromArgs: fromArgs(t, []string{"p"}),
+			Input:    "a\nb",
+			Expected: "a\na\nb\nb",
+		},
+	}
+	test.RunAll(t, testCases)
+}
+
+func TestFiles(t *testing.T) {
+	test.Parallel(t)
+	pigsFile := test.Testdata(t, "three-small-pigs")
+	script := filepath.Join(t.TempDir(), "script.sed")
+	err := os.WriteFile(script, []byte("1d\n"), 0600)
+	require.NoError(t, err)
+
+	sed := fromArgs(t, []string{"-f", script, "-e", "$d", pigsFile, "-"})
+	var out strings.Builder
+	stdio := unix.NewStdio(strings.NewReader("stdin1\nstdin2\n"), &out, os.Stderr)
+	err = sed.Run(context.Background(), stdio)
+	require.NoError(t, err)
+	require.Equal(t, "small\npigs\nstdin1\n", out.String())
+}
+
+func TestErrors(t *testing.T) {
+	test.Parallel(t)
+	for _, script := range []string{
+		"k",
+		"s/a/b",
+		"s/a/b/x",
+		"y/ab/c/",
+		"b nowhere",
+		"{p",
+		"p}",
+		"0p",
+		"1,p",
+	} {
+		_, err := New().FromArgs([]string{script})
+		require.Error(t, err, script)
+	}
+
+	var out strings.Builder
+	stdio := unix.NewStdio(strings.NewReader(pigs), &out, &out)
+	err := New("5q").Files("does-not-exist").Run(context.Background(), stdio)
+	require.Error(t, err)
+	require.Equal(t, 2, pipe.FromError(err).Code)
+
+	stdio = unix.NewStdio(strings.NewReader(pigs), &out, &out)
+	err = New("q42").Run(context.Background(), stdio)
+	require.Error(t, err)
+	require.Equal(t, 42, pipe.FromError(err).Code)
+}
+
+func fromArgs(t *testing.T, argv []string) Sed {
+	t.Helper()
+	n := New()
+	f, err := n.FromArgs(argv)
+	require.NoError(t, err)
+	return f
+}
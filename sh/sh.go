@@ -0,0 +1,171 @@
+// Copyright 2023 Michal Vyskocil. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+/*
+sh runs shell-like pipelines like `cat | wc -l` as native Go filters.
+
+It is not a shell. A command line is split into words by a pluggable split
+function and the words are separated to commands by a standalone `|`. Each
+command name is looked up in a map of builtins, so caller controls what
+code is going to be executed. Names not found are passed to NotFoundFunc,
+which fails with pipe.NotFound by default.
+
+Commands run via pipeline.Line with pipefail on, so the pipeline fails if
+any command fails. A command stopped by a broken pipe, like yes in
+`yes | head -n 1`, does not fail the pipeline. The exit status of the pipeline is a Code of returned
+pipe.Error, see pipeline.Code.
+*/
+package sh
+
+import (
+	"context"
+	"strings"
+
+	"github.com/gomoni/gio/pipe"
+	"github.com/gomoni/gio/unix"
+	"github.com/gomoni/gonix/pipeline"
+)
+
+// Builtins maps command names to FromArgs like constructors
+type Builtins map[string]func([]string) (unix.Filter, error)
+
+// SplitFunc splits the command line to words, github.com/desertbit/go-shlex is
+// probably the best option for a real sh syntax
+type SplitFunc func(string) ([]string, error)
+
+// NotFoundFunc is called for names not present in builtins
+type NotFoundFunc func(name string, args []string) (unix.Filter, error)
+
+type Sh struct {
+	builtins Builtins
+	split    SplitFunc
+	notFound NotFoundFunc
+	pipefail bool
+}
+
+// New returns Sh mapping builtins with a split function, nil split means Fields
+func New(builtins Builtins, split SplitFunc) Sh {
+	if split == nil {
+		split = Fields
+	}
+	return Sh{
+		builtins: builtins,
+		split:    split,
+		notFound: NotFound,
+		pipefail: true,
+	}
+}
+
+// NotFoundFunc sets the function to call when command is not in builtins
+func (s Sh) NotFoundFunc(fn NotFoundFunc) Sh {
+	if fn == nil {
+		fn = NotFound
+	}
+	s.notFound = fn
+	return s
+}
+
+// Pipefail controls the exit status of a pipeline, which is the status of the
+// last failed command if on (default) or the status of the last command if off
+func (s Sh) Pipefail(b bool) Sh {
+	s.pipefail = b
+	return s
+}
+
+// Parse splits the command line and converts each command to unix.Filter
+func (s Sh) Parse(cmdline string) ([]unix.Filter, error) {
+	words, err := s.split(cmdline)
+	if err != nil {
+		return nil, pipe.NewErrorf(2, "sh: split failed: %w", err)
+	}
+	if len(words) == 0 {
+		return nil, nil
+	}
+
+	var filters []unix.Filter
+	var argv []string
+	flush := func() error {
+		if len(argv) == 0 {
+			return pipe.NewErrorf(2, "sh: syntax error near unexpected token `|'")
+		}
+		filter, err := s.filter(argv[0], argv[1:])
+		if err != nil {
+			return err
+		}
+		filters = append(filters, filter)
+		argv = nil
+		return nil
+	}
+
+	for _, word := range words {
+		if word == "|" {
+			if err := flush(); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		argv = append(argv, word)
+	}
+	if err := flush(); err != nil {
+		return nil, err
+	}
+	return filters, nil
+}
+
+// Run parses the command line and runs it via pipeline.NewLine()
+func (s Sh) Run(ctx context.Context, stdio unix.StandardIO, cmdline string) error {
+	res, err := s.Exec(ctx, stdio, cmdline)
+	if err != nil {
+		return err
+	}
+	return res.Err()
+}
+
+// Exec parses the command line and runs it, the result has the exit status of
+// each command. The error is returned for a command line, which can't be
+// parsed.
+func (s Sh) Exec(ctx context.Context, stdio unix.StandardIO, cmdline string) (pipeline.Result, error) {
+	filters, err := s.Parse(cmdline)
+	if err != nil {
+		return pipeline.Result{}, err
+	}
+	return pipeline.NewLine().Pipefail(s.pipefail).Run(ctx, stdio, filters...), nil
+}
+
+func (s Sh) filter(name string, args []string) (unix.Filter, error) {
+	fromArgs, ok := s.builtins[name]
+	if !ok {
+		return s.notFound(name, args)
+	}
+	filter, err := fromArgs(args)
+	if err != nil {
+		return nil, err
+	}
+	return filter, nil
+}
+
+// NotFound is a default NotFoundFunc, returns error with pipe.NotFound code
+func NotFound(name string, _ []string) (unix.Filter, error) {
+	return nil, pipe.NewErrorf(pipe.NotFound, "sh: %s: command not found", name)
+}
+
+// Fields is a default SplitFunc, which splits on white spaces and a pipe
+// character. It does not support any quoting.
+func Fields(cmdline string) ([]string, error) {
+	var ret []string
+	for _, field := range strings.Fields(cmdline) {
+		for {
+			before, after, found := strings.Cut(field, "|")
+			if before != "" {
+				ret = append(ret, before)
+			}
+			if !found {
+				break
+			}
+			ret = append(ret, "|")
+			field = after
+		}
+	}
+	return ret, nil
+}
@@ -0,0 +1,172 @@
+// Copyright 2023 Michal Vyskocil. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package sh_test
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/gomoni/gio/pipe"
+	"github.com/gomoni/gio/unix"
+	"github.com/gomoni/gonix/cat"
+	"github.com/gomoni/gonix/head"
+	"github.com/gomoni/gonix/internal/test"
+	"github.com/gomoni/gonix/pipeline"
+	. "github.com/gomoni/gonix/sh"
+	"github.com/gomoni/gonix/wc"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/goleak"
+)
+
+func TestMain(m *testing.M) {
+	goleak.VerifyTestMain(m)
+}
+
+var builtins = Builtins{
+	"cat":  func(a []string) (unix.Filter, error) { return cat.New().FromArgs(a) },
+	"head": func(a []string) (unix.Filter, error) { return head.New().FromArgs(a) },
+	"wc":   func(a []string) (unix.Filter, error) { return wc.New().FromArgs(a) },
+	"yes":  func([]string) (unix.Filter, error) { return yes{}, nil },
+}
+
+// yes writes y until the write fails
+type yes struct{}
+
+func (yes) Run(_ context.Context, stdio unix.StandardIO) error {
+	for {
+		_, err := io.WriteString(stdio.Stdout(), "y\n")
+		if err != nil {
+			return fmt.Errorf("yes: %w", err)
+		}
+	}
+}
+
+func TestSh(t *testing.T) {
+	test.Parallel(t)
+
+	testCases := []struct {
+		name     string
+		sh       Sh
+		cmdline  string
+		expected string
+	}{
+		{
+			name:     "empty",
+			sh:       New(builtins, nil),
+			cmdline:  "  ",
+			expected: "",
+		},
+		{
+			name:     "wc -l",
+			sh:       New(builtins, nil),
+			cmdline:  "wc -l",
+			expected: "3\n",
+		},
+		{
+			name:     "cat | head -n 2 | wc -l",
+			sh:       New(builtins, nil),
+			cmdline:  "cat | head -n 2 | wc -l",
+			expected: "2\n",
+		},
+		{
+			name:     "cat|wc -l",
+			sh:       New(builtins, nil),
+			cmdline:  "cat|wc -l",
+			expected: "3\n",
+		},
+		{
+			name: "custom split",
+			sh: New(builtins, func(string) ([]string, error) {
+				return []string{"head", "-n", "1"}, nil
+			}),
+			cmdline:  "ignored",
+			expected: "three\n",
+		},
+		{
+			name: "custom not found",
+			sh: New(builtins, nil).NotFoundFunc(func(name string, args []string) (unix.Filter, error) {
+				require.Equal(t, "lines", name)
+				return wc.New().FromArgs([]string{"-l"})
+			}),
+			cmdline:  "cat | lines --count",
+			expected: "3\n",
+		},
+	}
+
+	for _, tt := range testCases {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			test.Parallel(t)
+			var out strings.Builder
+			stdio := unix.NewStdio(
+				strings.NewReader("three\nsmall\npigs\n"),
+				&out,
+				&out,
+			)
+			err := tt.sh.Run(context.Background(), stdio, tt.cmdline)
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, out.String())
+		})
+	}
+}
+
+func TestShError(t *testing.T) {
+	test.Parallel(t)
+
+	testCases := []struct {
+		name    string
+		cmdline string
+		code    int
+	}{
+		{"not found", "cat | grep x", pipe.NotFound},
+		{"syntax error", "cat | | wc", 2},
+		{"trailing pipe", "cat |", 2},
+		{"wrong argument", "wc --unknown", 1},
+	}
+
+	for _, tt := range testCases {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			test.Parallel(t)
+			_, err := New(builtins, nil).Parse(tt.cmdline)
+			require.Error(t, err)
+			require.Equal(t, tt.code, pipe.FromError(err).Code)
+		})
+	}
+}
+
+func TestExec(t *testing.T) {
+	test.Parallel(t)
+	const cmdline = "cat does-not-exist | wc -l"
+	stdio := unix.NewStdio(strings.NewReader(""), io.Discard, io.Discard)
+
+	res, err := New(builtins, nil).Exec(context.Background(), stdio, cmdline)
+	require.NoError(t, err)
+	require.Equal(t, []int{1, 0}, res.PipeStatus)
+	require.Equal(t, 1, res.Status)
+
+	err = New(builtins, nil).Run(context.Background(), stdio, cmdline)
+	require.Error(t, err)
+	require.Equal(t, 1, pipe.FromError(err).Code)
+
+	err = New(builtins, nil).Pipefail(false).Run(context.Background(), stdio, cmdline)
+	require.NoError(t, err)
+
+	// a broken pipe is not a failure even with pipefail
+	res, err = New(builtins, nil).Exec(context.Background(), stdio, "yes | head -n 1")
+	require.NoError(t, err)
+	require.Equal(t, []int{pipeline.BrokenPipe, 0}, res.PipeStatus)
+	require.NoError(t, res.Err())
+}
+
+func TestFields(t *testing.T) {
+	test.Parallel(t)
+	words, err := Fields(" go version|wc  -l |cat ")
+	require.NoError(t, err)
+	require.Equal(t, []string{"go", "version", "|", "wc", "-l", "|", "cat"}, words)
+}
@@ -0,0 +1,336 @@
+// Copyright 2023 Michal Vyskocil. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package sort
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/gomoni/gonix/internal"
+)
+
+// Key is a sort key definition as -k POS1[,POS2] does, fields and characters are counted
+// from 1. A zero EndField means the end of line and zero EndChar the end of the field.
+type Key struct {
+	StartField int
+	StartChar  int
+	EndField   int
+	EndChar    int
+	Options    KeyOptions
+}
+
+// KeyOptions are ordering options, each key without its own options inherits the global ones
+type KeyOptions struct {
+	IgnoreLeadingBlanks bool // b
+	IgnoreCase          bool // f
+	Numeric             bool // n
+	Human               bool // h
+	Version             bool // V
+	Reverse             bool // r
+}
+
+func (o KeyOptions) zero() bool {
+	return o == KeyOptions{}
+}
+
+// ParseKey parses the -k argument like 2,2 or 1.3n,1.5
+func ParseKey(s string) (Key, error) {
+	var key Key
+	pos1, pos2, hasEnd := strings.Cut(s, ",")
+
+	var err error
+	key.StartField, key.StartChar, err = parsePos(pos1, &key.Options)
+	if err != nil {
+		return Key{}, fmt.Errorf("invalid key %q: %w", s, err)
+	}
+	if key.StartChar == 0 {
+		if strings.Contains(pos1, ".") {
+			return Key{}, fmt.Errorf("invalid key %q: character offset is zero", s)
+		}
+		key.StartChar = 1
+	}
+	if hasEnd {
+		key.EndField, key.EndChar, err = parsePos(pos2, &key.Options)
+		if err != nil {
+			return Key{}, fmt.Errorf("invalid key %q: %w", s, err)
+		}
+	}
+	return key, nil
+}
+
+func parsePos(s string, opts *KeyOptions) (int, int, error) {
+	idx := strings.IndexFunc(s, func(r rune) bool { return r < '0' || r > '9' })
+	if idx == -1 {
+		idx = len(s)
+	}
+	field, err := strconv.Atoi(s[:idx])
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid field number %q", s[:idx])
+	}
+	if field <= 0 {
+		return 0, 0, fmt.Errorf("field number is zero")
+	}
+	s = s[idx:]
+
+	var char int
+	if strings.HasPrefix(s, ".") {
+		s = s[1:]
+		idx := strings.IndexFunc(s, func(r rune) bool { return r < '0' || r > '9' })
+		if idx == -1 {
+			idx = len(s)
+		}
+		char, err = strconv.Atoi(s[:idx])
+		if err != nil {
+			return 0, 0, fmt.Errorf("invalid character offset %q", s[:idx])
+		}
+		s = s[idx:]
+	}
+
+	for _, r := range s {
+		switch r {
+		case 'b':
+			opts.IgnoreLeadingBlanks = true
+		case 'f':
+			opts.IgnoreCase = true
+		case 'n':
+			opts.Numeric = true
+		case 'h':
+			opts.Human = true
+		case 'V':
+			opts.Version = true
+		case 'r':
+			opts.Reverse = true
+		default:
+			return 0, 0, fmt.Errorf("invalid ordering option %q", r)
+		}
+	}
+	return field, char, nil
+}
+
+func isBlank(b byte) bool {
+	return b == ' ' || b == '\t'
+}
+
+// fieldStart returns an offset of the field n, where fields are separated by sep, or
+// by a transition from a non-blank to a blank character if sep is nil. Then a
+// field includes its leading blanks.
+func fieldStart(line []byte, n int, sep []byte) int {
+	pos := 0
+	for ; n > 1 && pos < len(line); n-- {
+		if sep != nil {
+			idx := bytes.Index(line[pos:], sep)
+			if idx == -1 {
+				return len(line)
+			}
+			pos += idx + len(sep)
+			continue
+		}
+		for pos < len(line) && isBlank(line[pos]) {
+			pos++
+		}
+		for pos < len(line) && !isBlank(line[pos]) {
+			pos++
+		}
+	}
+	return pos
+}
+
+// fieldEnd returns an offset of the end of field starting at pos
+func fieldEnd(line []byte, pos int, sep []byte) int {
+	if sep != nil {
+		idx := bytes.Index(line[pos:], sep)
+		if idx == -1 {
+			return len(line)
+		}
+		return pos + idx
+	}
+	for pos < len(line) && isBlank(line[pos]) {
+		pos++
+	}
+	for pos < len(line) && !isBlank(line[pos]) {
+		pos++
+	}
+	return pos
+}
+
+func skipBlanks(line []byte, pos int) int {
+	for pos < len(line) && isBlank(line[pos]) {
+		pos++
+	}
+	return pos
+}
+
+// extract returns the part of line the key compares
+func (k Key) extract(line []byte, sep []byte) []byte {
+	start := fieldStart(line, k.StartField, sep)
+	if k.Options.IgnoreLeadingBlanks {
+		start = skipBlanks(line, start)
+	}
+	start += k.StartChar - 1
+	if start > len(line) {
+		start = len(line)
+	}
+
+	end := len(line)
+	if k.EndField > 0 {
+		end = fieldStart(line, k.EndField, sep)
+		if k.EndChar == 0 {
+			end = fieldEnd(line, end, sep)
+		} else {
+			if k.Options.IgnoreLeadingBlanks {
+				end = skipBlanks(line, end)
+			}
+			end += k.EndChar
+			if end > len(line) {
+				end = len(line)
+			}
+		}
+	}
+	if end < start {
+		return nil
+	}
+	return line[start:end]
+}
+
+// compare compares extracted keys, reverse is not applied
+func (k Key) compare(a, b []byte) int {
+	o := k.Options
+	switch {
+	case o.Numeric:
+		return compareFloat(numeric(a), numeric(b))
+	case o.Human:
+		return compareFloat(human(a), human(b))
+	case o.Version:
+		return versionCompare(a, b)
+	case o.IgnoreCase:
+		return bytes.Compare(bytes.ToUpper(a), bytes.ToUpper(b))
+	}
+	return bytes.Compare(a, b)
+}
+
+func compareFloat(a, b float64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// numberPrefix returns the leading -?[0-9]*(\.[0-9]*)? part of s, leading blanks are ignored
+func numberPrefix(s []byte) []byte {
+	s = s[skipBlanks(s, 0):]
+	idx := 0
+	if idx < len(s) && s[idx] == '-' {
+		idx++
+	}
+	for idx < len(s) && s[idx] >= '0' && s[idx] <= '9' {
+		idx++
+	}
+	if idx < len(s) && s[idx] == '.' {
+		idx++
+		for idx < len(s) && s[idx] >= '0' && s[idx] <= '9' {
+			idx++
+		}
+	}
+	return s[:idx]
+}
+
+// numeric returns a numeric value of a string, non-numbers sort as zero
+func numeric(s []byte) float64 {
+	f, err := strconv.ParseFloat(string(numberPrefix(s)), 64)
+	if err != nil {
+		return 0
+	}
+	return f
+}
+
+// human returns a value of a number with SI suffix like 2K or 1.5G as internal.ParseByte does
+func human(s []byte) float64 {
+	prefix := numberPrefix(s)
+	num := string(prefix)
+	rest := s[skipBlanks(s, 0)+len(prefix):]
+	if len(rest) > 0 && strings.IndexByte("KMGTPEZYk", rest[0]) != -1 {
+		num += strings.ToUpper(string(rest[0]))
+	}
+	b, err := internal.ParseByte(num)
+	if err != nil {
+		return 0
+	}
+	return float64(b)
+}
+
+// versionCompare compares strings as versions like Debian and GNU sort -V does: digit
+// sequences compare numerically, letters sort before other characters and tilde
+// before anything, even the end of string.
+func versionCompare(a, b []byte) int {
+	isDigit := func(s []byte, i int) bool {
+		return i < len(s) && s[i] >= '0' && s[i] <= '9'
+	}
+	order := func(s []byte, i int) int {
+		if i >= len(s) {
+			return 0
+		}
+		c := s[i]
+		switch {
+		case c >= '0' && c <= '9':
+			return 0
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z':
+			return int(c)
+		case c == '~':
+			return -1
+		}
+		return int(c) + 256
+	}
+
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		for (i < len(a) && !isDigit(a, i)) || (j < len(b) && !isDigit(b, j)) {
+			ac, bc := order(a, i), order(b, j)
+			if ac != bc {
+				return compareInt(ac, bc)
+			}
+			i++
+			j++
+		}
+		for i < len(a) && a[i] == '0' {
+			i++
+		}
+		for j < len(b) && b[j] == '0' {
+			j++
+		}
+		firstDiff := 0
+		for isDigit(a, i) && isDigit(b, j) {
+			if firstDiff == 0 {
+				firstDiff = compareInt(int(a[i]), int(b[j]))
+			}
+			i++
+			j++
+		}
+		if isDigit(a, i) {
+			return 1
+		}
+		if isDigit(b, j) {
+			return -1
+		}
+		if firstDiff != 0 {
+			return firstDiff
+		}
+	}
+	return 0
+}
+
+func compareInt(a, b int) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,101 @@
+// Copyright 2023 Michal Vyskocil. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package sort
+
+import (
+	"bufio"
+	"bytes"
+	"container/heap"
+	"context"
+	"errors"
+	"io"
+	"os"
+)
+
+// source is the current line of a sorted temporary file
+type source struct {
+	idx  int
+	line []byte
+	r    *bufio.Reader
+}
+
+// mergeHeap orders sources by their current line, equal lines are ordered by
+// the index of a file, which keeps the merge stable
+type mergeHeap struct {
+	s       *sorter
+	sources []*source
+}
+
+func (h mergeHeap) Len() int { return len(h.sources) }
+func (h mergeHeap) Less(i, j int) bool {
+	r := h.s.compare(h.sources[i].line, h.sources[j].line)
+	if r != 0 {
+		return r < 0
+	}
+	return h.sources[i].idx < h.sources[j].idx
+}
+func (h mergeHeap) Swap(i, j int) { h.sources[i], h.sources[j] = h.sources[j], h.sources[i] }
+func (h *mergeHeap) Push(x any)   { h.sources = append(h.sources, x.(*source)) }
+func (h *mergeHeap) Pop() any {
+	old := h.sources
+	n := len(old)
+	x := old[n-1]
+	h.sources = old[:n-1]
+	return x
+}
+
+// next reads the next line, returns false at the end of file
+func (src *source) next(delim byte) (bool, error) {
+	line, err := src.r.ReadBytes(delim)
+	if len(line) > 0 {
+		src.line = bytes.TrimSuffix(line, []byte{delim})
+		return true, nil
+	}
+	if errors.Is(err, io.EOF) {
+		return false, nil
+	}
+	return false, err
+}
+
+// merge merges sorted files calling emit for each line
+func (s *sorter) merge(ctx context.Context, names []string, emit func([]byte) error) error {
+	h := &mergeHeap{s: s}
+	for idx, name := range names {
+		f, err := os.Open(name)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		src := &source{idx: idx, r: bufio.NewReaderSize(f, 64*1024)}
+		ok, err := src.next(s.delim)
+		if err != nil {
+			return err
+		}
+		if ok {
+			h.sources = append(h.sources, src)
+		}
+	}
+	heap.Init(h)
+
+	for h.Len() > 0 {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		src := h.sources[0]
+		if err := emit(src.line); err != nil {
+			return err
+		}
+		ok, err := src.next(s.delim)
+		if err != nil {
+			return err
+		}
+		if ok {
+			heap.Fix(h, 0)
+		} else {
+			heap.Pop(h)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,469 @@
+// Copyright 2023 Michal Vyskocil. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+/*
+sort sorts lines of text files
+
+	-k/--key POS1[,POS2]        sort via a key, POS is F[.C][OPTS], see ParseKey
+	-t/--field-separator SEP    use SEP instead of non-blank to blank transition
+	-b/--ignore-leading-blanks  ignore leading blanks
+	-f/--ignore-case            fold lower case to upper case characters
+	-n/--numeric-sort           compare according to string numerical value
+	-h/--human-numeric-sort     compare human readable numbers (e.g., 2K 1G)
+	-V/--version-sort           natural sort of (version) numbers within text
+	-r/--reverse                reverse the result of comparisons
+	-u/--unique                 output only the first of an equal run
+	-s/--stable                 disable last-resort comparison
+	-z/--zero-terminated        line delimiter is NUL, not newline
+	-S/--buffer-size SIZE       use SIZE for main memory buffer
+	-T/--temporary-directory    use DIR for temporaries, not $TMPDIR or /tmp
+
+Input bigger than the buffer size is sorted in chunks stored in temporary
+files, which are merged together at the end.
+*/
+package sort
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"os"
+	stdsort "sort"
+
+	"github.com/gomoni/gio/pipe"
+	"github.com/gomoni/gio/unix"
+	"github.com/gomoni/gonix"
+	"github.com/gomoni/gonix/internal"
+	"github.com/gomoni/gonix/internal/dbg"
+	"github.com/spf13/pflag"
+)
+
+const (
+	// DefaultBufferSize is a memory budget for the in memory sort
+	DefaultBufferSize = 64 * internal.MebiByte
+	// maxMerge is a maximum number of temporary files merged at once
+	maxMerge = 16
+	// lineOverhead is an estimated memory overhead of each line
+	lineOverhead = 24
+)
+
+type Sort struct {
+	debug          bool
+	keys           []Key
+	options        KeyOptions
+	separator      string
+	unique         bool
+	stable         bool
+	zeroTerminated bool
+	bufferSize     int64
+	tempDir        string
+	fsys           fs.FS
+	files          []string
+}
+
+func New() Sort {
+	return Sort{
+		bufferSize: int64(DefaultBufferSize),
+	}
+}
+
+func init() {
+	gonix.Register(gonix.Cmd{
+		Name:  "sort",
+		Usage: "sort lines of text files",
+		New:   gonix.FromArgs(New().FromArgs),
+	})
+}
+
+// FromArgs build a Sort from standard argv except the command name (os.Argv[1:])
+func (c Sort) FromArgs(argv []string) (Sort, error) {
+	flag := pflag.FlagSet{}
+
+	keys := flag.StringArrayP("key", "k", nil, "sort via a key; KEYDEF gives location and type")
+	separator := flag.StringP("field-separator", "t", "", "use SEP instead of non-blank to blank transition")
+	flag.BoolVarP(&c.options.IgnoreLeadingBlanks, "ignore-leading-blanks", "b", false, "ignore leading blanks")
+	flag.BoolVarP(&c.options.IgnoreCase, "ignore-case", "f", false, "fold lower case to upper case characters")
+	flag.BoolVarP(&c.options.Numeric, "numeric-sort", "n", false, "compare according to string numerical value")
+	flag.BoolVarP(&c.options.Human, "human-numeric-sort", "h", false, "compare human readable numbers (e.g., 2K 1G)")
+	flag.BoolVarP(&c.options.Version, "version-sort", "V", false, "natural sort of (version) numbers within text")
+	flag.BoolVarP(&c.options.Reverse, "reverse", "r", false, "reverse the result of comparisons")
+	flag.BoolVarP(&c.unique, "unique", "u", false, "output only the first of an equal run")
+	flag.BoolVarP(&c.stable, "stable", "s", false, "stabilize sort by disabling last-resort comparison")
+	flag.BoolVarP(&c.zeroTerminated, "zero-terminated", "z", false, "line delimiter is NUL, not newline")
+	bufferSize := internal.Byte(c.bufferSize)
+	flag.VarP(&bufferSize, "buffer-size", "S", "use SIZE for main memory buffer")
+	flag.StringVarP(&c.tempDir, "temporary-directory", "T", c.tempDir, "use DIR for temporaries, not $TMPDIR or /tmp")
+
+	err := flag.Parse(argv)
+	if err != nil {
+		return Sort{}, pipe.NewErrorf(1, "sort: parsing failed: %w", err)
+	}
+
+	for _, s := range *keys {
+		key, err := ParseKey(s)
+		if err != nil {
+			return Sort{}, pipe.NewErrorf(1, "sort: %w", err)
+		}
+		c.keys = append(c.keys, key)
+	}
+
+	if flag.Changed("field-separator") {
+		switch *separator {
+		case "":
+			return Sort{}, pipe.NewErrorf(1, "sort: empty tab")
+		case "\\0":
+			c.separator = "\x00"
+		default:
+			c.separator = *separator
+		}
+	}
+	c.bufferSize = int64(bufferSize)
+
+	err = c.validate()
+	if err != nil {
+		return Sort{}, pipe.NewErrorf(1, "sort: %w", err)
+	}
+
+	if len(flag.Args()) > 0 {
+		c.files = flag.Args()
+	}
+	return c, nil
+}
+
+func (c Sort) validate() error {
+	check := func(o KeyOptions) error {
+		var n int
+		for _, b := range []bool{o.Numeric, o.Human, o.Version} {
+			if b {
+				n++
+			}
+		}
+		if n > 1 {
+			return errors.New("options -n, -h and -V are incompatible")
+		}
+		return nil
+	}
+	if err := check(c.options); err != nil {
+		return err
+	}
+	for _, key := range c.keys {
+		if err := check(key.Options); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Files are input files, where - denotes stdin
+func (c Sort) Files(f ...string) Sort {
+	c.files = append(c.files, f...)
+	return c
+}
+
+// FS sets a filesystem used to open files, nil means the OS filesystem
+func (c Sort) FS(fsys fs.FS) Sort {
+	c.fsys = fsys
+	return c
+}
+
+// Keys adds sort keys, see ParseKey
+func (c Sort) Keys(keys ...Key) Sort {
+	c.keys = append(c.keys, keys...)
+	return c
+}
+
+// Separator splits fields on sep instead of a non-blank to blank transition
+func (c Sort) Separator(sep string) Sort {
+	c.separator = sep
+	return c
+}
+
+func (c Sort) IgnoreLeadingBlanks(b bool) Sort {
+	c.options.IgnoreLeadingBlanks = b
+	return c
+}
+
+func (c Sort) IgnoreCase(b bool) Sort {
+	c.options.IgnoreCase = b
+	return c
+}
+
+func (c Sort) Numeric(b bool) Sort {
+	c.options.Numeric = b
+	return c
+}
+
+// Human compares numbers with SI suffixes like 2K or 1G
+func (c Sort) Human(b bool) Sort {
+	c.options.Human = b
+	return c
+}
+
+// Version compares digit sequences inside text numerically like 1.2 < 1.10
+func (c Sort) Version(b bool) Sort {
+	c.options.Version = b
+	return c
+}
+
+func (c Sort) Reverse(b bool) Sort {
+	c.options.Reverse = b
+	return c
+}
+
+// Unique outputs only the first line of lines comparing equal
+func (c Sort) Unique(b bool) Sort {
+	c.unique = b
+	return c
+}
+
+// Stable disables the last-resort comparison of whole lines
+func (c Sort) Stable(b bool) Sort {
+	c.stable = b
+	return c
+}
+
+func (c Sort) ZeroTerminated(zeroTerminated bool) Sort {
+	c.zeroTerminated = zeroTerminated
+	return c
+}
+
+// BufferSize is a memory budget, bigger inputs are sorted with temporary files
+func (c Sort) BufferSize(size int64) Sort {
+	c.bufferSize = size
+	return c
+}
+
+// TempDir is a directory for temporary files, os.TempDir is used if empty
+func (c Sort) TempDir(dir string) Sort {
+	c.tempDir = dir
+	return c
+}
+
+func (c Sort) SetDebug(debug bool) Sort {
+	c.debug = debug
+	return c
+}
+
+func (c Sort) Run(ctx context.Context, stdio unix.StandardIO) error {
+	debug := dbg.Logger(c.debug, "sort", stdio.Stderr())
+	debug.Printf("c=%+v", c)
+
+	s := newSorter(c, debug)
+	defer s.cleanup()
+
+	read := func(ctx context.Context, stdio unix.StandardIO, _ int, _ string) error {
+		err := s.read(ctx, stdio.Stdin())
+		if err != nil {
+			return internal.NewError(1, fmt.Errorf("sort: fail to run: %w", err))
+		}
+		return nil
+	}
+	err := internal.NewRunFiles(c.files, stdio, read).FS(c.fsys).Do(ctx)
+	if err != nil {
+		return err
+	}
+
+	err = s.write(ctx, stdio.Stdout())
+	if err != nil {
+		return internal.NewError(1, fmt.Errorf("sort: fail to run: %w", err))
+	}
+	return nil
+}
+
+// sorter holds lines in memory and spills them sorted to temporary files
+type sorter struct {
+	debug  *log.Logger
+	c      Sort
+	keys   []Key
+	sep    []byte
+	delim  byte
+	lines  [][]byte
+	size   int64
+	chunks []string
+}
+
+func newSorter(c Sort, debug *log.Logger) *sorter {
+	s := &sorter{
+		debug: debug,
+		c:     c,
+		delim: '\n',
+	}
+	if c.zeroTerminated {
+		s.delim = 0
+	}
+	if c.separator != "" {
+		s.sep = []byte(c.separator)
+	}
+	if len(c.keys) == 0 {
+		s.keys = []Key{{StartField: 1, StartChar: 1, Options: c.options}}
+	} else {
+		s.keys = make([]Key, len(c.keys))
+		for idx, key := range c.keys {
+			if key.Options.zero() {
+				key.Options = c.options
+			}
+			s.keys[idx] = key
+		}
+	}
+	return s
+}
+
+func (s *sorter) compare(a, b []byte) int {
+	for _, key := range s.keys {
+		r := key.compare(key.extract(a, s.sep), key.extract(b, s.sep))
+		if r != 0 {
+			if key.Options.Reverse {
+				return -r
+			}
+			return r
+		}
+	}
+	if s.c.stable || s.c.unique {
+		return 0
+	}
+	r := bytes.Compare(a, b)
+	if s.c.options.Reverse {
+		return -r
+	}
+	return r
+}
+
+func (s *sorter) read(ctx context.Context, in io.Reader) error {
+	r := bufio.NewReaderSize(in, 64*1024)
+	for {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		line, err := r.ReadBytes(s.delim)
+		if len(line) > 0 {
+			line = bytes.TrimSuffix(line, []byte{s.delim})
+			s.lines = append(s.lines, line)
+			s.size += int64(len(line)) + lineOverhead
+			if s.size > s.c.bufferSize {
+				if err := s.spill(); err != nil {
+					return err
+				}
+			}
+		}
+		if errors.Is(err, io.EOF) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+	}
+}
+
+func (s *sorter) sort() {
+	stdsort.SliceStable(s.lines, func(i, j int) bool {
+		return s.compare(s.lines[i], s.lines[j]) < 0
+	})
+}
+
+// spill sorts lines in memory and writes them to the temporary file
+func (s *sorter) spill() error {
+	s.sort()
+	name, err := s.writeTemp(func(w *bufio.Writer) error {
+		for _, line := range s.lines {
+			if err := s.writeLine(w, line); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	s.debug.Printf("spill: %d lines of %d bytes to %s", len(s.lines), s.size, name)
+	s.lines = nil
+	s.size = 0
+	return nil
+}
+
+func (s *sorter) writeTemp(fill func(*bufio.Writer) error) (string, error) {
+	f, err := os.CreateTemp(s.c.tempDir, "gonix-sort-*")
+	if err != nil {
+		return "", err
+	}
+	s.chunks = append(s.chunks, f.Name())
+	w := bufio.NewWriterSize(f, 64*1024)
+	err = fill(w)
+	if err == nil {
+		err = w.Flush()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return f.Name(), err
+}
+
+func (s *sorter) writeLine(w *bufio.Writer, line []byte) error {
+	_, err := w.Write(line)
+	if err != nil {
+		return err
+	}
+	return w.WriteByte(s.delim)
+}
+
+// write outputs sorted lines, merging the temporary files if any
+func (s *sorter) write(ctx context.Context, out io.Writer) error {
+	w := bufio.NewWriterSize(out, 64*1024)
+	var prev []byte
+	emit := func(line []byte) error {
+		if s.c.unique && prev != nil && s.compare(prev, line) == 0 {
+			return nil
+		}
+		prev = line
+		return s.writeLine(w, line)
+	}
+
+	if len(s.chunks) == 0 {
+		s.sort()
+		for _, line := range s.lines {
+			if err := emit(line); err != nil {
+				return err
+			}
+		}
+		return w.Flush()
+	}
+
+	if len(s.lines) > 0 {
+		if err := s.spill(); err != nil {
+			return err
+		}
+	}
+	chunks := s.chunks
+	for len(chunks) > maxMerge {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		// merge the oldest chunks first, so equal lines keep the input order
+		group := chunks[:maxMerge]
+		name, err := s.writeTemp(func(w *bufio.Writer) error {
+			return s.merge(ctx, group, func(line []byte) error { return s.writeLine(w, line) })
+		})
+		if err != nil {
+			return err
+		}
+		chunks = append([]string{name}, chunks[maxMerge:]...)
+	}
+	err := s.merge(ctx, chunks, emit)
+	if err != nil {
+		return err
+	}
+	return w.Flush()
+}
+
+func (s *sorter) cleanup() {
+	for _, name := range s.chunks {
+		err := os.Remove(name)
+		if err != nil {
+			s.debug.Printf("cleanup: %s", err)
+		}
+	}
+}
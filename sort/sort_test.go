@@ -0,0 +1,195 @@
+// Copyright 2023 Michal Vyskocil. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package sort_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/gomoni/gio/unix"
+	"github.com/gomoni/gonix/internal/test"
+	. "github.com/gomoni/gonix/sort"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSort(t *testing.T) {
+	test.Parallel(t)
+	testCases := []test.Case[Sort]{
+		{
+			Name:     "default",
+			Filter:   New(),
+			FromArgs: fromArgs(t, []string{}),
+			Input:    "three\nsmall\npigs",
+			Expected: "pigs\nsmall\nthree\n",
+		},
+		{
+			Name:     "--reverse",
+			Filter:   New().Reverse(true),
+			FromArgs: fromArgs(t, []string{"--reverse"}),
+			Input:    "three\nsmall\npigs\n",
+			Expected: "three\nsmall\npigs\n",
+		},
+		{
+			Name:     "-n",
+			Filter:   New().Numeric(true),
+			FromArgs: fromArgs(t, []string{"-n"}),
+			Input:    "10\n9\n-1.5\n  2\nx\n",
+			Expected: "-1.5\nx\n  2\n9\n10\n",
+		},
+		{
+			Name:     "-h",
+			Filter:   New().Human(true),
+			FromArgs: fromArgs(t, []string{"-h"}),
+			Input:    "1G\n2K\n1.5M\n1023\n3k\n",
+			Expected: "1023\n2K\n3k\n1.5M\n1G\n",
+		},
+		{
+			Name:     "-V",
+			Filter:   New().Version(true),
+			FromArgs: fromArgs(t, []string{"-V"}),
+			Input:    "gonix-1.10\ngonix-1.2\ngonix-1.2~rc1\ngonix-1.02a\n",
+			Expected: "gonix-1.2~rc1\ngonix-1.2\ngonix-1.02a\ngonix-1.10\n",
+		},
+		{
+			Name:     "-k 2",
+			Filter:   New().Keys(Key{StartField: 2, StartChar: 1}),
+			FromArgs: fromArgs(t, []string{"-k", "2"}),
+			Input:    "1 pigs\n2 three\n3 small\n",
+			Expected: "1 pigs\n3 small\n2 three\n",
+		},
+		{
+			Name:     "-t : -k 2,2n -k 1,1r",
+			Filter:   New().Separator(":").Keys(Key{StartField: 2, StartChar: 1, EndField: 2, Options: KeyOptions{Numeric: true}}, Key{StartField: 1, StartChar: 1, EndField: 1, Options: KeyOptions{Reverse: true}}),
+			FromArgs: fromArgs(t, []string{"-t", ":", "-k", "2,2n", "-k", "1,1r"}),
+			Input:    "a:10:x\nb:9:y\nc:10:z\n",
+			Expected: "b:9:y\nc:10:z\na:10:x\n",
+		},
+		{
+			Name:     "-k 1.2,1.3",
+			Filter:   New().Keys(Key{StartField: 1, StartChar: 2, EndField: 1, EndChar: 3}),
+			FromArgs: fromArgs(t, []string{"-k", "1.2,1.3"}),
+			Input:    "xcb\nyba\nzaz\n",
+			Expected: "zaz\nyba\nxcb\n",
+		},
+		{
+			Name:     "-b -k 2",
+			Filter:   New().IgnoreLeadingBlanks(true).Keys(Key{StartField: 2, StartChar: 1}),
+			FromArgs: fromArgs(t, []string{"-b", "-k", "2"}),
+			Input:    "1    b\n2 a\n",
+			Expected: "2 a\n1    b\n",
+		},
+		{
+			Name:     "-u",
+			Filter:   New().Unique(true),
+			FromArgs: fromArgs(t, []string{"-u"}),
+			Input:    "b\na\nb\na\n",
+			Expected: "a\nb\n",
+		},
+		{
+			Name:     "-f -u",
+			Filter:   New().IgnoreCase(true).Unique(true),
+			FromArgs: fromArgs(t, []string{"-f", "-u"}),
+			Input:    "b\nA\na\nB\n",
+			Expected: "A\nb\n",
+		},
+		{
+			Name:     "-s -k 1,1",
+			Filter:   New().Stable(true).Keys(Key{StartField: 1, StartChar: 1, EndField: 1}),
+			FromArgs: fromArgs(t, []string{"-s", "-k", "1,1"}),
+			Input:    "b 2\na 3\nb 1\na 1\n",
+			Expected: "a 3\na 1\nb 2\nb 1\n",
+		},
+		{
+			Name:     "-k 1,1",
+			Filter:   New().Keys(Key{StartField: 1, StartChar: 1, EndField: 1}),
+			FromArgs: fromArgs(t, []string{"-k", "1,1"}),
+			Input:    "b 2\na 3\nb 1\na 1\n",
+			Expected: "a 1\na 3\nb 1\nb 2\n",
+		},
+		{
+			Name:     "-z",
+			Filter:   New().ZeroTerminated(true),
+			FromArgs: fromArgs(t, []string{"-z"}),
+			Input:    "b\nx\x00a\x00",
+			Expected: "a\x00b\nx\x00",
+		},
+	}
+	test.RunAll(t, testCases)
+}
+
+func TestExternal(t *testing.T) {
+	test.Parallel(t)
+
+	var input strings.Builder
+	var expected strings.Builder
+	for i := 0; i < 1000; i++ {
+		fmt.Fprintf(&input, "%d\n", (i*7919)%1000)
+		fmt.Fprintf(&expected, "%d\n", i)
+	}
+
+	dir := t.TempDir()
+	for _, size := range []string{"64", "1K", "1M"} {
+		size := size
+		t.Run(size, func(t *testing.T) {
+			sort := fromArgs(t, []string{"-n", "-S", size, "-T", dir})
+			var out strings.Builder
+			stdio := unix.NewStdio(strings.NewReader(input.String()), &out, os.Stderr)
+			err := sort.Run(context.Background(), stdio)
+			require.NoError(t, err)
+			require.Equal(t, expected.String(), out.String())
+		})
+	}
+
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	require.Empty(t, entries, "temporary files were not removed")
+}
+
+func TestExternalStable(t *testing.T) {
+	test.Parallel(t)
+
+	var input strings.Builder
+	for i := 0; i < 500; i++ {
+		fmt.Fprintf(&input, "%d %d\n", i%3, i)
+	}
+	sort := New().Stable(true).Keys(Key{StartField: 1, StartChar: 1, EndField: 1})
+
+	var inmemory strings.Builder
+	stdio := unix.NewStdio(strings.NewReader(input.String()), &inmemory, os.Stderr)
+	require.NoError(t, sort.Run(context.Background(), stdio))
+
+	var external strings.Builder
+	stdio = unix.NewStdio(strings.NewReader(input.String()), &external, os.Stderr)
+	require.NoError(t, sort.BufferSize(100).TempDir(t.TempDir()).Run(context.Background(), stdio))
+
+	require.Equal(t, inmemory.String(), external.String())
+	require.True(t, strings.HasPrefix(external.String(), "0 0\n0 3\n0 6\n"))
+}
+
+func TestFromArgsErr(t *testing.T) {
+	test.Parallel(t)
+	for _, argv := range [][]string{
+		{"-n", "-h"},
+		{"-k", "0"},
+		{"-k", "1.0"},
+		{"-k", "1x"},
+		{"-t", ""},
+		{"-S", "x"},
+	} {
+		_, err := New().FromArgs(argv)
+		require.Error(t, err, argv)
+	}
+}
+
+func fromArgs(t *testing.T, argv []string) Sort {
+	t.Helper()
+	n := New()
+	f, err := n.FromArgs(argv)
+	require.NoError(t, err)
+	return f
+}
@@ -0,0 +1,529 @@
+// Copyright 2023 Michal Vyskocil. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+/*
+tail prints the last part of files
+
+	-n/--lines N    print the last N lines, default 10
+	-n/--lines +N   print lines starting with line N
+	-c/--bytes N    print the last N bytes
+	-c/--bytes +N   print bytes starting with byte N
+	-z              line delimiter is NUL, not newline
+	-q/-v           never or always print headers
+	-f/--follow     output appended data as the file grows
+
+N may have a multiplier suffix like 1K or 1MiB, see internal.Byte.
+
+Regular files are read from the end, so the last lines of a big log are
+printed without reading it all. Other inputs keep only the last N lines or
+bytes in memory.
+
+--follow polls named files every --sleep-interval seconds until the context
+is canceled. Standard input is never followed.
+*/
+package tail
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/gomoni/gio/pipe"
+	"github.com/gomoni/gio/unix"
+	"github.com/gomoni/gonix"
+	"github.com/gomoni/gonix/internal"
+	"github.com/gomoni/gonix/internal/dbg"
+	"github.com/spf13/pflag"
+)
+
+type unit int
+
+const (
+	lines unit = 0
+	bytes unit = 1
+)
+
+type Tail struct {
+	debug          bool
+	unit           unit
+	count          int64
+	fromStart      bool
+	zeroTerminated bool
+	quiet          bool
+	verbose        bool
+	follow         bool
+	sleepInterval  time.Duration
+	fsys           fs.FS
+	files          []string
+}
+
+func New() Tail {
+	return Tail{
+		count:         10,
+		sleepInterval: time.Second,
+	}
+}
+
+func init() {
+	gonix.Register(gonix.Cmd{
+		Name:  "tail",
+		Usage: "output the last part of files",
+		New:   gonix.FromArgs(New().FromArgs),
+	})
+}
+
+// FromArgs build a Tail from standard argv except the command name (os.Argv[1:])
+func (c Tail) FromArgs(argv []string) (Tail, error) {
+	flag := pflag.FlagSet{}
+
+	linesArg := flag.StringP("lines", "n", "10", "print the last N lines, +N prints lines starting with line N")
+	bytesArg := flag.StringP("bytes", "c", "", "print the last N bytes, +N prints bytes starting with byte N")
+	flag.BoolVarP(&c.zeroTerminated, "zero-terminated", "z", false, "line delimiter is NUL")
+	flag.BoolVarP(&c.quiet, "quiet", "q", false, "never print headers giving file names")
+	flag.BoolVar(&c.quiet, "silent", false, "same as --quiet")
+	flag.BoolVarP(&c.verbose, "verbose", "v", false, "always print headers giving file names")
+	flag.BoolVarP(&c.follow, "follow", "f", false, "output appended data as the file grows")
+	sleepInterval := flag.Float64P("sleep-interval", "s", 1.0, "with -f, sleep for approximately N seconds between iterations")
+
+	err := flag.Parse(argv)
+	if err != nil {
+		return Tail{}, pipe.NewErrorf(1, "tail: parsing failed: %w", err)
+	}
+
+	if flag.Changed("bytes") {
+		c.unit = bytes
+		c.count, c.fromStart, err = parseCount(*bytesArg)
+	} else {
+		c.unit = lines
+		c.count, c.fromStart, err = parseCount(*linesArg)
+	}
+	if err != nil {
+		return Tail{}, pipe.NewErrorf(1, "tail: %w", err)
+	}
+	if *sleepInterval < 0 {
+		return Tail{}, pipe.NewErrorf(1, "tail: invalid number of seconds: %g", *sleepInterval)
+	}
+	c.sleepInterval = time.Duration(*sleepInterval * float64(time.Second))
+
+	if len(flag.Args()) > 0 {
+		c.files = flag.Args()
+	}
+	return c, nil
+}
+
+// parseCount parses N, -N or +N with optional multiplier suffix
+func parseCount(s string) (int64, bool, error) {
+	fromStart := strings.HasPrefix(s, "+")
+	s = strings.TrimPrefix(s, "+")
+	s = strings.TrimPrefix(s, "-")
+	n, err := internal.ParseByte(s)
+	if err != nil {
+		return 0, false, err
+	}
+	if float64(n) > math.MaxInt64 {
+		return math.MaxInt64, fromStart, nil
+	}
+	return int64(math.Round(float64(n))), fromStart, nil
+}
+
+// Files are input files, where - denotes stdin
+func (c Tail) Files(f ...string) Tail {
+	c.files = append(c.files, f...)
+	return c
+}
+
+// FS sets a filesystem used to open files, nil means the OS filesystem
+func (c Tail) FS(fsys fs.FS) Tail {
+	c.fsys = fsys
+	return c
+}
+
+// Lines prints the last n lines
+func (c Tail) Lines(n int64) Tail {
+	c.unit = lines
+	c.count = n
+	return c
+}
+
+// Bytes prints the last n bytes
+func (c Tail) Bytes(n int64) Tail {
+	c.unit = bytes
+	c.count = n
+	return c
+}
+
+// FromStart changes Lines or Bytes to mean the first line or byte to print, like +N does
+func (c Tail) FromStart(b bool) Tail {
+	c.fromStart = b
+	return c
+}
+
+func (c Tail) ZeroTerminated(zeroTerminated bool) Tail {
+	c.zeroTerminated = zeroTerminated
+	return c
+}
+
+// Quiet never prints headers with file names
+func (c Tail) Quiet(b bool) Tail {
+	c.quiet = b
+	return c
+}
+
+// Verbose always prints headers with file names
+func (c Tail) Verbose(b bool) Tail {
+	c.verbose = b
+	return c
+}
+
+// Follow outputs appended data as files grow until the context is canceled
+func (c Tail) Follow(b bool) Tail {
+	c.follow = b
+	return c
+}
+
+// SleepInterval is a polling interval for Follow, defaults to one second
+func (c Tail) SleepInterval(d time.Duration) Tail {
+	c.sleepInterval = d
+	return c
+}
+
+func (c Tail) SetDebug(debug bool) Tail {
+	c.debug = debug
+	return c
+}
+
+// followed is a file position to watch with --follow
+type followed struct {
+	name   string
+	offset int64
+	ok     bool
+}
+
+func (c Tail) Run(ctx context.Context, stdio unix.StandardIO) error {
+	debug := dbg.Logger(c.debug, "tail", stdio.Stderr())
+	debug.Printf("c=%+v", c)
+
+	headers := c.verbose || (len(c.files) > 1 && !c.quiet)
+	follows := make([]followed, len(c.files))
+	printed := -1
+
+	tail := func(ctx context.Context, stdio unix.StandardIO, idx int, name string) error {
+		if headers {
+			if printed != -1 {
+				fmt.Fprintln(stdio.Stdout())
+			}
+			fmt.Fprintf(stdio.Stdout(), "==> %s <==\n", displayName(name))
+		}
+		printed = idx
+		err := c.tail(ctx, stdio.Stdin(), stdio.Stdout(), debug)
+		if err != nil {
+			return internal.NewError(1, fmt.Errorf("tail: fail to run: %w", err))
+		}
+		if !c.follow || name == "" || name == "-" {
+			return nil
+		}
+		if seeker, ok := stdio.Stdin().(io.Seeker); ok {
+			offset, err := seeker.Seek(0, io.SeekCurrent)
+			if err == nil {
+				follows[idx] = followed{name: name, offset: offset, ok: true}
+			}
+		}
+		return nil
+	}
+
+	runFiles := internal.NewRunFiles(c.files, stdio, tail).FS(c.fsys)
+	errs := runFiles.Do(ctx)
+	if !c.follow {
+		return errs
+	}
+
+	err := c.followFiles(ctx, stdio, follows, headers, printed, debug)
+	if errs != nil {
+		return errs
+	}
+	return err
+}
+
+func (c Tail) tail(ctx context.Context, in io.Reader, out io.Writer, debug *log.Logger) error {
+	delim := byte('\n')
+	if c.zeroTerminated {
+		delim = 0
+	}
+
+	switch {
+	case c.fromStart && c.unit == bytes:
+		return fromStartBytes(in, out, c.count)
+	case c.fromStart:
+		return fromStartLines(ctx, in, out, c.count, delim)
+	case c.count == 0:
+		// read the input anyway, so --follow starts at the end
+		_, err := io.Copy(io.Discard, in)
+		return err
+	}
+
+	if seeker, ok := in.(io.ReadSeeker); ok {
+		done, err := lastSeek(seeker, out, c.unit, c.count, delim)
+		if done || err != nil {
+			debug.Printf("lastSeek: done=%t err=%v", done, err)
+			return err
+		}
+	}
+
+	if c.unit == bytes {
+		return lastBytes(ctx, in, out, c.count)
+	}
+	return lastLines(ctx, in, out, c.count, delim)
+}
+
+// fromStartBytes prints all bytes starting with the byte n
+func fromStartBytes(in io.Reader, out io.Writer, n int64) error {
+	if n > 1 {
+		_, err := io.CopyN(io.Discard, in, n-1)
+		if errors.Is(err, io.EOF) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+	}
+	_, err := io.Copy(out, in)
+	return err
+}
+
+// fromStartLines prints all lines starting with the line n
+func fromStartLines(ctx context.Context, in io.Reader, out io.Writer, n int64, delim byte) error {
+	r := bufio.NewReaderSize(in, 64*1024)
+	for line := int64(1); line < n; {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		_, err := r.ReadSlice(delim)
+		if errors.Is(err, bufio.ErrBufferFull) {
+			continue
+		} else if errors.Is(err, io.EOF) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		line++
+	}
+	_, err := r.WriteTo(out)
+	return err
+}
+
+// lastSeek prints the last n lines or bytes reading a file from the end. Returns false
+// if input can't seek, so caller must read it. Data before the current position, like
+// a part of stdin read by a previous command, are not printed.
+func lastSeek(in io.ReadSeeker, out io.Writer, unit unit, n int64, delim byte) (bool, error) {
+	cur, err := in.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return false, nil
+	}
+	size, err := in.Seek(0, io.SeekEnd)
+	if err != nil {
+		return false, nil
+	}
+	if size < cur {
+		size = cur
+	}
+
+	start := cur
+	if unit == bytes {
+		if n < size-cur {
+			start = size - n
+		}
+	} else {
+		start, err = lastLinesOffset(in, cur, size, n, delim)
+		if err != nil {
+			return true, err
+		}
+	}
+
+	_, err = in.Seek(start, io.SeekStart)
+	if err != nil {
+		return true, err
+	}
+	_, err = io.Copy(out, in)
+	return true, err
+}
+
+// lastLinesOffset returns an offset of the n-th line from the end of file,
+// which is not before the offset from
+func lastLinesOffset(in io.ReadSeeker, from, size int64, n int64, delim byte) (int64, error) {
+	var buf [32 * 1024]byte
+	var count int64
+	pos := size
+	for pos > from {
+		chunk := int64(len(buf))
+		if pos-from < chunk {
+			chunk = pos - from
+		}
+		pos -= chunk
+		_, err := in.Seek(pos, io.SeekStart)
+		if err != nil {
+			return 0, err
+		}
+		_, err = io.ReadFull(in, buf[:chunk])
+		if err != nil {
+			return 0, err
+		}
+		for idx := chunk - 1; idx >= 0; idx-- {
+			if buf[idx] != delim || pos+idx == size-1 {
+				// the trailing delimiter does not start a new line
+				continue
+			}
+			count++
+			if count == n {
+				return pos + idx + 1, nil
+			}
+		}
+	}
+	return from, nil
+}
+
+// lastBytes keeps last n bytes in memory and prints them at the end of input
+func lastBytes(ctx context.Context, in io.Reader, out io.Writer, n int64) error {
+	var buf []byte
+	var chunk [32 * 1024]byte
+	for {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		nr, err := in.Read(chunk[:])
+		buf = append(buf, chunk[:nr]...)
+		if int64(len(buf))-n > n {
+			buf = append(buf[:0], buf[int64(len(buf))-n:]...)
+		}
+		if errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return err
+		}
+	}
+	if int64(len(buf)) > n {
+		buf = buf[int64(len(buf))-n:]
+	}
+	_, err := out.Write(buf)
+	return err
+}
+
+// lastLines keeps last n lines in a ring buffer and prints them at the end of input
+func lastLines(ctx context.Context, in io.Reader, out io.Writer, n int64, delim byte) error {
+	r := bufio.NewReaderSize(in, 64*1024)
+	ring := make([][]byte, 0, 64)
+	head := 0
+	for {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		line, err := r.ReadBytes(delim)
+		if len(line) > 0 {
+			if int64(len(ring)) < n {
+				ring = append(ring, line)
+			} else {
+				ring[head] = line
+				head = (head + 1) % len(ring)
+			}
+		}
+		if errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return err
+		}
+	}
+
+	w := bufio.NewWriter(out)
+	for idx := 0; idx < len(ring); idx++ {
+		_, err := w.Write(ring[(head+idx)%len(ring)])
+		if err != nil {
+			return err
+		}
+	}
+	return w.Flush()
+}
+
+// followFiles polls the files and prints the appended data until the context is canceled
+func (c Tail) followFiles(ctx context.Context, stdio unix.StandardIO, follows []followed, headers bool, last int, debug *log.Logger) error {
+	var watching bool
+	for _, f := range follows {
+		watching = watching || f.ok
+	}
+	if !watching {
+		debug.Printf("follow: nothing to follow")
+		return nil
+	}
+
+	interval := c.sleepInterval
+	if interval <= 0 {
+		interval = time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return internal.NewError(1, fmt.Errorf("tail: %w", ctx.Err()))
+		case <-ticker.C:
+		}
+
+		for idx := range follows {
+			f := &follows[idx]
+			if !f.ok {
+				continue
+			}
+			st, err := internal.Stat(c.fsys, f.name)
+			if err != nil {
+				debug.Printf("follow: stat %q: %s", f.name, err)
+				continue
+			}
+			if st.Size() < f.offset {
+				fmt.Fprintf(stdio.Stderr(), "tail: %s: file truncated\n", f.name)
+				f.offset = 0
+			}
+			if st.Size() == f.offset {
+				continue
+			}
+			if headers && last != idx {
+				fmt.Fprintf(stdio.Stdout(), "\n==> %s <==\n", f.name)
+				last = idx
+			}
+			n, err := copyFrom(c.fsys, f.name, f.offset, stdio.Stdout())
+			f.offset += n
+			if err != nil {
+				return internal.NewError(1, fmt.Errorf("tail: %w", err))
+			}
+		}
+	}
+}
+
+func copyFrom(fsys fs.FS, name string, offset int64, out io.Writer) (int64, error) {
+	f, err := internal.Open(fsys, name)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	if seeker, ok := f.(io.Seeker); ok {
+		_, err = seeker.Seek(offset, io.SeekStart)
+	} else {
+		_, err = io.CopyN(io.Discard, f, offset)
+	}
+	if err != nil {
+		return 0, err
+	}
+	return io.Copy(out, f)
+}
+
+func displayName(name string) string {
+	if name == "" || name == "-" {
+		return "standard input"
+	}
+	return name
+}
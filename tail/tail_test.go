@@ -0,0 +1,256 @@
+// Copyright 2023 Michal Vyskocil. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package tail_test
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gomoni/gio/unix"
+	"github.com/gomoni/gonix/internal/test"
+	. "github.com/gomoni/gonix/tail"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTail(t *testing.T) {
+	test.Parallel(t)
+	testCases := []test.Case[Tail]{
+		{
+			Name:     "default",
+			Filter:   New(),
+			FromArgs: fromArgs(t, []string{}),
+			Input:    "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12\n",
+			Expected: "3\n4\n5\n6\n7\n8\n9\n10\n11\n12\n",
+		},
+		{
+			Name:     "--lines 2",
+			Filter:   New().Lines(2),
+			FromArgs: fromArgs(t, []string{"--lines", "2"}),
+			Input:    "1\n2\n3\n4\n",
+			Expected: "3\n4\n",
+		},
+		{
+			Name:     "-n -2 no trailing newline",
+			Filter:   New().Lines(2),
+			FromArgs: fromArgs(t, []string{"-n", "-2"}),
+			Input:    "1\n2\n3\n4",
+			Expected: "3\n4",
+		},
+		{
+			Name:     "-n 0",
+			Filter:   New().Lines(0),
+			FromArgs: fromArgs(t, []string{"-n", "0"}),
+			Input:    "1\n2\n3\n4\n",
+			Expected: "",
+		},
+		{
+			Name:     "-n +3",
+			Filter:   New().Lines(3).FromStart(true),
+			FromArgs: fromArgs(t, []string{"-n", "+3"}),
+			Input:    "1\n2\n3\n4\n",
+			Expected: "3\n4\n",
+		},
+		{
+			Name:     "-n +0",
+			Filter:   New().Lines(0).FromStart(true),
+			FromArgs: fromArgs(t, []string{"-n", "+0"}),
+			Input:    "1\n2\n",
+			Expected: "1\n2\n",
+		},
+		{
+			Name:     "-c 3",
+			Filter:   New().Bytes(3),
+			FromArgs: fromArgs(t, []string{"-c", "3"}),
+			Input:    "1\n2\n3\n4\n",
+			Expected: "\n4\n",
+		},
+		{
+			Name:     "-c +3",
+			Filter:   New().Bytes(3).FromStart(true),
+			FromArgs: fromArgs(t, []string{"-c", "+3"}),
+			Input:    "1\n2\n3\n4\n",
+			Expected: "2\n3\n4\n",
+		},
+		{
+			Name:     "-c 1K",
+			Filter:   New().Bytes(1024),
+			FromArgs: fromArgs(t, []string{"-c", "1K"}),
+			Input:    "1\n2\n",
+			Expected: "1\n2\n",
+		},
+		{
+			Name:     "-c 5E",
+			Filter:   New().Bytes(5 << 60),
+			FromArgs: fromArgs(t, []string{"-c", "5E"}),
+			Input:    "abc",
+			Expected: "abc",
+		},
+		{
+			Name:     "-z -n 2",
+			Filter:   New().Lines(2).ZeroTerminated(true),
+			FromArgs: fromArgs(t, []string{"-z", "-n", "2"}),
+			Input:    "1\x002\x003\x00",
+			Expected: "2\x003\x00",
+		},
+		{
+			Name:     "-v",
+			Filter:   New().Lines(1).Verbose(true),
+			FromArgs: fromArgs(t, []string{"-v", "-n", "1"}),
+			Input:    "1\n2\n",
+			Expected: "==> standard input <==\n2\n",
+		},
+	}
+	test.RunAll(t, testCases)
+}
+
+func TestTailFiles(t *testing.T) {
+	test.Parallel(t)
+	pigs := test.Testdata(t, "three-small-pigs")
+
+	testCases := []struct {
+		name     string
+		filter   Tail
+		expected string
+	}{
+		{
+			name:     "seek lines",
+			filter:   New().Lines(2).Files(pigs),
+			expected: "small\npigs\n",
+		},
+		{
+			name:     "seek bytes",
+			filter:   New().Bytes(5).Files(pigs),
+			expected: "pigs\n",
+		},
+		{
+			name:     "more lines than file",
+			filter:   New().Lines(42).Files(pigs),
+			expected: "three\nsmall\npigs\n",
+		},
+		{
+			name:     "headers",
+			filter:   New().Lines(1).Files(pigs, "-"),
+			expected: "==> " + pigs + " <==\npigs\n\n==> standard input <==\nstdin\n",
+		},
+		{
+			name:     "quiet",
+			filter:   New().Lines(1).Files(pigs, pigs).Quiet(true),
+			expected: "pigs\npigs\n",
+		},
+	}
+
+	for _, tt := range testCases {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			test.Parallel(t)
+			var out strings.Builder
+			stdio := unix.NewStdio(
+				strings.NewReader("stdin\n"),
+				&out,
+				os.Stderr,
+			)
+			err := tt.filter.Run(context.Background(), stdio)
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, out.String())
+		})
+	}
+}
+
+// TestTailSeekStdin checks a part of stdin read before is not printed like
+// in (read line; tail -n 20) < file
+func TestTailSeekStdin(t *testing.T) {
+	test.Parallel(t)
+	testCases := []struct {
+		name     string
+		filter   Tail
+		expected string
+	}{
+		{"lines", New().Lines(20), "2\n3\n4\n5\n6\n7\n8\n9\n10\n"},
+		{"bytes", New().Bytes(30), "2\n3\n4\n5\n6\n7\n8\n9\n10\n"},
+		{"last lines", New().Lines(2), "9\n10\n"},
+		{"last bytes", New().Bytes(3), "10\n"},
+	}
+	for _, tt := range testCases {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			test.Parallel(t)
+			stdin := strings.NewReader("1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n")
+			_, err := stdin.Seek(2, io.SeekStart)
+			require.NoError(t, err)
+			var out strings.Builder
+			err = tt.filter.Run(context.Background(), unix.NewStdio(stdin, &out, io.Discard))
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, out.String())
+		})
+	}
+}
+
+func TestTailFollow(t *testing.T) {
+	test.Parallel(t)
+	path := filepath.Join(t.TempDir(), "log")
+	err := os.WriteFile(path, []byte("1\n2\n3\n"), 0600)
+	require.NoError(t, err)
+
+	out := &syncBuffer{}
+	stdio := unix.NewStdio(nil, out, os.Stderr)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	tail := New().Lines(1).Files(path).Follow(true).SleepInterval(10 * time.Millisecond)
+	errch := make(chan error, 1)
+	go func() {
+		errch <- tail.Run(ctx, stdio)
+	}()
+
+	require.Eventually(t, func() bool { return out.String() == "3\n" }, time.Second, 5*time.Millisecond)
+
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0)
+	require.NoError(t, err)
+	_, err = f.WriteString("4\n5\n")
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	require.Eventually(t, func() bool { return out.String() == "3\n4\n5\n" }, time.Second, 5*time.Millisecond)
+
+	cancel()
+	select {
+	case err = <-errch:
+		require.ErrorIs(t, err, context.Canceled)
+	case <-time.After(time.Second):
+		t.Fatalf("tail --follow was not canceled")
+	}
+}
+
+func fromArgs(t *testing.T, argv []string) Tail {
+	t.Helper()
+	n := New()
+	f, err := n.FromArgs(argv)
+	require.NoError(t, err)
+	return f
+}
+
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
@@ -0,0 +1,243 @@
+// Copyright 2023 Michal Vyskocil. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+/*
+timeout runs a filter with a time limit
+
+	timeout [OPTION] DURATION COMMAND [ARG]...
+
+	-k/--kill-after DURATION  stop waiting for the command DURATION after the time limit
+	--preserve-status         exit with the status of the command even on a timeout
+
+DURATION is a floating point number with an optional suffix s, m, h or d,
+zero disables the time limit. COMMAND is looked up in gonix.Default registry.
+
+A time limit cancels the context of the command and closes its standard
+input like a broken pipe. Filters can't be killed, so timeout waits for the
+command to return for kill-after, or a Grace period by default. A command,
+which does not return in time, is abandoned. Timeout returns 137
+(128+SIGKILL) if kill-after was given and 124 otherwise.
+
+The exit status is 124 if the command timed out, 125 if timeout itself fails,
+127 if COMMAND is not found or the status of the command otherwise. With
+--preserve-status a command stopped by the time limit exits with 143
+(128+SIGTERM), or 137 if it was abandoned. A command, which returns on its
+own, keeps its status.
+*/
+package timeout
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/gomoni/gio/pipe"
+	"github.com/gomoni/gio/unix"
+	"github.com/gomoni/gonix"
+	"github.com/gomoni/gonix/internal"
+	"github.com/gomoni/gonix/internal/dbg"
+	"github.com/gomoni/gonix/pipeline"
+	"github.com/spf13/pflag"
+)
+
+const (
+	// Failed is an exit status of timeout failures like wrong arguments
+	Failed = 125
+	// Killed is an exit status of a command abandoned after kill-after
+	Killed = pipeline.Signaled + 9
+	// Terminated is an exit status of a command stopped by the time limit,
+	// used with --preserve-status
+	Terminated = pipeline.Signaled + 15
+	// Grace is how long a command has to return after the time limit when
+	// kill-after is not set
+	Grace = 100 * time.Millisecond
+)
+
+type Timeout struct {
+	debug          bool
+	duration       time.Duration
+	killAfter      time.Duration
+	preserveStatus bool
+	filter         unix.Filter
+	name           string
+	args           []string
+}
+
+func New() Timeout {
+	return Timeout{}
+}
+
+func init() {
+	gonix.Register(gonix.Cmd{
+		Name:  "timeout",
+		Usage: "run a command with a time limit",
+		New:   gonix.FromArgs(New().FromArgs),
+	})
+}
+
+// FromArgs build a Timeout from standard argv except the command name (os.Argv[1:])
+func (c Timeout) FromArgs(argv []string) (Timeout, error) {
+	flag := pflag.FlagSet{}
+	// options after DURATION belong to the command
+	flag.SetInterspersed(false)
+
+	var killAfter internal.Duration
+	flag.VarP(&killAfter, "kill-after", "k", "stop waiting for the command DURATION after the time limit")
+	flag.BoolVar(&c.preserveStatus, "preserve-status", false, "exit with the status of the command even on a timeout")
+
+	err := flag.Parse(argv)
+	if err != nil {
+		return Timeout{}, pipe.NewErrorf(Failed, "timeout: parsing failed: %w", err)
+	}
+	if flag.NArg() < 2 {
+		return Timeout{}, pipe.NewErrorf(Failed, "timeout: missing operand")
+	}
+
+	duration, err := internal.ParseDuration(flag.Arg(0))
+	if err != nil {
+		return Timeout{}, pipe.NewErrorf(Failed, "timeout: %w", err)
+	}
+	filter, err := gonix.New(flag.Arg(1), flag.Args()[2:])
+	if err != nil {
+		return Timeout{}, err
+	}
+
+	c = c.Duration(time.Duration(duration)).KillAfter(time.Duration(killAfter)).Filter(filter)
+	return c, nil
+}
+
+// Duration is a time limit of the command, zero disables it
+func (c Timeout) Duration(d time.Duration) Timeout {
+	c.duration = d
+	return c
+}
+
+// KillAfter abandons the command if it does not return d after the time
+// limit, zero means Grace
+func (c Timeout) KillAfter(d time.Duration) Timeout {
+	c.killAfter = d
+	return c
+}
+
+// PreserveStatus returns the status of the command instead of 124 on a timeout
+func (c Timeout) PreserveStatus(b bool) Timeout {
+	c.preserveStatus = b
+	return c
+}
+
+// Filter is the command to run
+func (c Timeout) Filter(filter unix.Filter) Timeout {
+	c.filter = filter
+	c.name = ""
+	c.args = nil
+	return c
+}
+
+// Command is the command from gonix.Default registry to run, it is looked up
+// when Run is called
+func (c Timeout) Command(name string, args ...string) Timeout {
+	c.filter = nil
+	c.name = name
+	c.args = args
+	return c
+}
+
+// SetDebug additional debugging messages on stderr
+func (c Timeout) SetDebug(debug bool) Timeout {
+	c.debug = debug
+	return c
+}
+
+func (c Timeout) Run(ctx context.Context, stdio unix.StandardIO) error {
+	debug := dbg.Logger(c.debug, "timeout", stdio.Stderr())
+	filter := c.filter
+	if filter == nil {
+		if c.name == "" {
+			return pipe.NewErrorf(Failed, "timeout: missing operand")
+		}
+		var err error
+		filter, err = gonix.New(c.name, c.args)
+		if err != nil {
+			return err
+		}
+	}
+
+	if c.duration <= 0 {
+		return filter.Run(ctx, stdio)
+	}
+
+	tctx, cancel := context.WithTimeout(ctx, c.duration)
+	defer cancel()
+	done := make(chan error, 1)
+	go func() {
+		done <- filter.Run(tctx, stdio)
+	}()
+
+	var err error
+	select {
+	case err = <-done:
+	case <-tctx.Done():
+		debug.Printf("context done: %s", tctx.Err())
+		if ctx.Err() == nil {
+			// unblock a command reading its input
+			internal.CloseStdin(stdio.Stdin())
+		}
+		var ok bool
+		err, ok = c.wait(done)
+		if !ok {
+			debug.Printf("command abandoned")
+			return c.abandoned(ctx)
+		}
+	}
+
+	switch {
+	case ctx.Err() != nil || !errors.Is(tctx.Err(), context.DeadlineExceeded):
+		return err
+	case c.preserveStatus:
+		return terminated(err)
+	}
+	return c.timedOut()
+}
+
+// wait waits for the command for kill-after or Grace, false means it did
+// not return in time
+func (c Timeout) wait(done <-chan error) (error, bool) {
+	grace := c.killAfter
+	if grace <= 0 {
+		grace = Grace
+	}
+	timer := time.NewTimer(grace)
+	defer timer.Stop()
+	select {
+	case err := <-done:
+		return err, true
+	case <-timer.C:
+		return nil, false
+	}
+}
+
+// abandoned is an error of a command, which did not return in time
+func (c Timeout) abandoned(ctx context.Context) error {
+	switch {
+	case ctx.Err() != nil:
+		return ctx.Err()
+	case c.killAfter > 0 || c.preserveStatus:
+		return pipe.NewErrorf(Killed, "timeout: command did not stop after the time limit")
+	}
+	return c.timedOut()
+}
+
+func (c Timeout) timedOut() error {
+	return pipe.NewErrorf(pipeline.Timeout, "timeout: timed out after %s: %w", c.duration, context.DeadlineExceeded)
+}
+
+// terminated returns Terminated for a command stopped by the time limit, so
+// the deadline is not mistaken for a timeout of timeout itself, other
+// statuses of the command are kept
+func terminated(err error) error {
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled) {
+		return pipe.NewErrorf(Terminated, "timeout: command terminated: %s", pipe.FromError(err).Err)
+	}
+	return err
+}
@@ -0,0 +1,203 @@
+// Copyright 2023 Michal Vyskocil. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package timeout_test
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gomoni/gonix/cat"
+	"github.com/gomoni/gonix/internal/test"
+	"github.com/gomoni/gonix/pipeline"
+	. "github.com/gomoni/gonix/timeout"
+	"github.com/gomoni/gonix/wc"
+
+	"github.com/gomoni/gio/pipe"
+	"github.com/gomoni/gio/unix"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/goleak"
+)
+
+func TestMain(m *testing.M) {
+	goleak.VerifyTestMain(m)
+}
+
+func TestTimeout(t *testing.T) {
+	test.Parallel(t)
+	testCases := []test.Case[Timeout]{
+		{
+			Name:     "timeout 1m cat",
+			Filter:   New().Duration(time.Minute).Filter(cat.New()),
+			FromArgs: fromArgs(t, []string{"1m", "cat"}),
+			Input:    "three\nsmall\npigs\n",
+			Expected: "three\nsmall\npigs\n",
+		},
+		{
+			Name:     "timeout -k 1s 0.5h wc -l",
+			Filter:   New().Duration(30 * time.Minute).KillAfter(time.Second).Filter(wc.New().Lines(true)),
+			FromArgs: fromArgs(t, []string{"-k", "1s", "0.5h", "wc", "-l"}),
+			Input:    "three\nsmall\npigs\n",
+			Expected: "3\n",
+		},
+		{
+			Name:     "timeout 0 cat",
+			Filter:   New().Filter(cat.New()),
+			FromArgs: fromArgs(t, []string{"0", "cat"}),
+			Input:    "three\nsmall\npigs\n",
+			Expected: "three\nsmall\npigs\n",
+		},
+		{
+			Name:     "timeout 1d wc -l",
+			Filter:   New().Duration(24*time.Hour).Command("wc", "-l"),
+			Input:    "three\nsmall\npigs\n",
+			Expected: "3\n",
+		},
+	}
+	test.RunAll(t, testCases)
+}
+
+func TestTimedOut(t *testing.T) {
+	test.Parallel(t)
+	ctx := context.Background()
+	stdio := unix.NewStdio(strings.NewReader(""), io.Discard, io.Discard)
+
+	t.Run("timed out", func(t *testing.T) {
+		test.Parallel(t)
+		err := New().Duration(10*time.Millisecond).Filter(wait{code: 3}).Run(ctx, stdio)
+		require.Error(t, err)
+		require.Equal(t, pipeline.Timeout, pipe.FromError(err).Code)
+		require.ErrorIs(t, err, context.DeadlineExceeded)
+	})
+	t.Run("preserve status", func(t *testing.T) {
+		test.Parallel(t)
+		err := New().Duration(10*time.Millisecond).PreserveStatus(true).Filter(stop{code: 3}).Run(ctx, stdio)
+		require.Error(t, err)
+		require.Equal(t, 3, pipe.FromError(err).Code)
+	})
+	t.Run("preserve status terminated", func(t *testing.T) {
+		test.Parallel(t)
+		for _, filter := range []wait{{}, {code: 1}, {code: pipeline.Timeout}} {
+			err := New().Duration(10*time.Millisecond).PreserveStatus(true).Filter(filter).Run(ctx, stdio)
+			require.Error(t, err)
+			require.Equal(t, Terminated, pipeline.Code(err))
+			require.NotErrorIs(t, err, context.DeadlineExceeded)
+		}
+	})
+	t.Run("abandoned", func(t *testing.T) {
+		stuck := make(chan struct{})
+		defer close(stuck)
+		start := time.Now()
+		err := New().Duration(10*time.Millisecond).Filter(stubborn{stuck}).Run(ctx, stdio)
+		require.Error(t, err)
+		require.Equal(t, pipeline.Timeout, pipe.FromError(err).Code)
+		require.Less(t, time.Since(start), 10*time.Millisecond+2*Grace)
+	})
+	t.Run("kill after", func(t *testing.T) {
+		stuck := make(chan struct{})
+		defer close(stuck)
+		err := New().Duration(10*time.Millisecond).KillAfter(10*time.Millisecond).Filter(stubborn{stuck}).Run(ctx, stdio)
+		require.Error(t, err)
+		require.Equal(t, Killed, pipe.FromError(err).Code)
+	})
+	t.Run("canceled", func(t *testing.T) {
+		test.Parallel(t)
+		ctx, cancel := context.WithCancel(ctx)
+		cancel()
+		err := New().Duration(time.Minute).Filter(wait{code: 3}).Run(ctx, stdio)
+		require.Error(t, err)
+		require.Equal(t, 3, pipe.FromError(err).Code)
+		require.Equal(t, 3, pipeline.Code(err))
+		require.ErrorIs(t, err, context.Canceled)
+	})
+}
+
+// TestPipeline puts a time limit on a stage blocked on reading its input
+func TestPipeline(t *testing.T) {
+	test.Parallel(t)
+	stdin, w := io.Pipe()
+	defer w.Close()
+	var stdout strings.Builder
+	stdio := unix.NewStdio(stdin, &stdout, io.Discard)
+
+	res := pipeline.NewLine().Pipefail(true).Run(
+		context.Background(),
+		stdio,
+		New().Duration(10*time.Millisecond).Filter(cat.New()),
+		wc.New().Lines(true),
+	)
+	require.Equal(t, "0\n", stdout.String())
+	require.Equal(t, pipeline.Timeout, res.Status)
+	require.Equal(t, []int{pipeline.Timeout, 0}, res.PipeStatus)
+}
+
+func TestFromArgsError(t *testing.T) {
+	test.Parallel(t)
+	testCases := []struct {
+		name string
+		argv []string
+		code int
+	}{
+		{"missing operand", []string{"1s"}, Failed},
+		{"invalid duration", []string{"1w", "cat"}, Failed},
+		{"negative duration", []string{"-k", "-1", "1s", "cat"}, Failed},
+		{"unknown option", []string{"--unknown", "1s", "cat"}, Failed},
+		{"not found", []string{"1s", "not-found"}, pipe.NotFound},
+		{"wrong argument", []string{"1s", "cat", "--unknown"}, 1},
+	}
+	for _, tt := range testCases {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			test.Parallel(t)
+			_, err := New().FromArgs(tt.argv)
+			require.Error(t, err)
+			require.Equal(t, tt.code, pipe.FromError(err).Code)
+		})
+	}
+}
+
+func fromArgs(t *testing.T, argv []string) Timeout {
+	t.Helper()
+	f, err := New().FromArgs(argv)
+	require.NoError(t, err)
+	return f
+}
+
+// wait returns an error with code when the context is done, zero code
+// returns the error of the context
+type wait struct {
+	code int
+}
+
+func (w wait) Run(ctx context.Context, _ unix.StandardIO) error {
+	<-ctx.Done()
+	if w.code == 0 {
+		return fmt.Errorf("wait: %w", ctx.Err())
+	}
+	return pipe.NewErrorf(w.code, "wait: %w", ctx.Err())
+}
+
+// stop returns an own error with code when the context is done
+type stop struct {
+	code int
+}
+
+func (s stop) Run(ctx context.Context, _ unix.StandardIO) error {
+	<-ctx.Done()
+	return pipe.NewErrorf(s.code, "stop: %d", s.code)
+}
+
+// stubborn ignores the context
+type stubborn struct {
+	stuck chan struct{}
+}
+
+func (s stubborn) Run(context.Context, unix.StandardIO) error {
+	<-s.stuck
+	return nil
+}
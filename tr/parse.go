@@ -0,0 +1,415 @@
+// Copyright 2023 Michal Vyskocil. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package tr
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"unicode"
+	"unicode/utf8"
+)
+
+var (
+	errMisalignedUpperAndLower = errors.New("misaligned [:upper:] and/or [:lower:] construct")
+	errNoUpperNeitherLower     = errors.New("when translating, the only character classes that may appear in array2 are 'upper' and 'lower'")
+	errEmptyArray2             = errors.New("when not truncating array1, array2 must be non-empty")
+)
+
+// invalid is the first of runes representing bytes of invalid utf-8 sequences
+const invalid = unicode.MaxRune + 1
+
+// element is a parsed part of ARRAY
+//
+//	runes:  CHAR, CHAR1-CHAR2, [=CHAR=]
+//	class:  [:class:]
+//	repeat: [CHAR*] and [CHAR*REPEAT], where zero count means to fill array2
+type element struct {
+	runes  []rune
+	class  string
+	repeat int
+}
+
+var trClasses = map[string]func(rune) bool{
+	"alnum":  alnum,
+	"alpha":  unicode.IsLetter,
+	"blank":  blank,
+	"cntrl":  unicode.IsControl,
+	"digit":  unicode.IsDigit,
+	"graph":  graph,
+	"lower":  unicode.IsLower,
+	"print":  unicode.IsPrint,
+	"punct":  unicode.IsPunct,
+	"space":  space,
+	"upper":  unicode.IsUpper,
+	"xdigit": xdigit,
+}
+
+// [:alnum:]
+func alnum(in rune) bool {
+	return unicode.IsLetter(in) || unicode.IsDigit(in)
+}
+
+// [:blank:]
+func blank(in rune) bool {
+	return in == ' ' || in == '\t'
+}
+
+// [:graph:]
+func graph(in rune) bool {
+	return unicode.IsPrint(in) && in != ' '
+}
+
+// [:space:]
+func space(in rune) bool {
+	return unicode.Is(unicode.White_Space, in)
+}
+
+// [:xdigit:]
+func xdigit(in rune) bool {
+	return (in >= '0' && in <= '9') || (in >= 'a' && in <= 'f') || (in >= 'A' && in <= 'F')
+}
+
+// parse parses ARRAY, [CHAR*REPEAT] is recognized in array2 only
+func parse(array string, array2 bool) ([]element, error) {
+	s := []rune(array)
+	at := func(idx int) rune {
+		if idx < 0 || idx >= len(s) {
+			return -1
+		}
+		return s[idx]
+	}
+	// find returns an index of needle followed by ] or -1
+	find := func(from int, needle rune) int {
+		for idx := from; idx < len(s)-1; idx++ {
+			if s[idx] == needle && s[idx+1] == ']' {
+				return idx
+			}
+		}
+		return -1
+	}
+
+	var ret []element
+	for idx := 0; idx < len(s); {
+		if at(idx) == '[' && at(idx+1) == ':' {
+			if end := find(idx+2, ':'); end != -1 {
+				name := string(s[idx+2 : end])
+				if _, ok := trClasses[name]; !ok {
+					return nil, fmt.Errorf("invalid character class %q", name)
+				}
+				ret = append(ret, element{class: name, repeat: -1})
+				idx = end + 2
+				continue
+			}
+		}
+
+		if at(idx) == '[' && at(idx+1) == '=' {
+			if end := find(idx+2, '='); end != -1 {
+				rn, next := char(s, idx+2)
+				if next != end {
+					return nil, fmt.Errorf("%s: equivalence class operand must be a single character", string(s[idx+2:end]))
+				}
+				ret = append(ret, element{runes: []rune{rn}, repeat: -1})
+				idx = end + 2
+				continue
+			}
+		}
+
+		if array2 && at(idx) == '[' && idx+1 < len(s) {
+			if rn, count, next, ok := repeat(s, idx); ok {
+				ret = append(ret, element{runes: []rune{rn}, repeat: count})
+				idx = next
+				continue
+			}
+		}
+
+		from, next := char(s, idx)
+		if at(next) == '-' && next+1 < len(s) {
+			to, end := char(s, next+1)
+			if to < from {
+				return nil, fmt.Errorf("range-endpoints of '%s' are in reverse collating sequence order", string(s[idx:end]))
+			}
+			runes := make([]rune, 0, to-from+1)
+			for rn := from; rn <= to; rn++ {
+				runes = append(runes, rn)
+			}
+			ret = append(ret, element{runes: runes, repeat: -1})
+			idx = end
+			continue
+		}
+		ret = append(ret, element{runes: []rune{from}, repeat: -1})
+		idx = next
+	}
+	return ret, nil
+}
+
+// char returns a rune at idx with escape sequences interpreted and an index
+// of the next rune
+func char(s []rune, idx int) (rune, int) {
+	if s[idx] != '\\' || idx+1 == len(s) {
+		return s[idx], idx + 1
+	}
+	idx++
+	if octal(s[idx]) {
+		n := 0
+		end := idx
+		for ; end < len(s) && end < idx+3 && octal(s[end]); end++ {
+			if next := n*8 + int(s[end]-'0'); next <= 0377 {
+				n = next
+				continue
+			}
+			break
+		}
+		return rune(n), end
+	}
+	switch s[idx] {
+	case 'a':
+		return '\a', idx + 1
+	case 'b':
+		return '\b', idx + 1
+	case 'f':
+		return '\f', idx + 1
+	case 'n':
+		return '\n', idx + 1
+	case 'r':
+		return '\r', idx + 1
+	case 't':
+		return '\t', idx + 1
+	case 'v':
+		return '\v', idx + 1
+	}
+	return s[idx], idx + 1
+}
+
+func octal(rn rune) bool {
+	return rn >= '0' && rn <= '7'
+}
+
+// repeat parses [CHAR*] or [CHAR*REPEAT] at idx, REPEAT is octal with a leading zero
+func repeat(s []rune, idx int) (rune, int, int, bool) {
+	rn, next := char(s, idx+1)
+	if next >= len(s) || s[next] != '*' {
+		return 0, 0, idx, false
+	}
+	start := next + 1
+	end := start
+	for end < len(s) && s[end] >= '0' && s[end] <= '9' {
+		end++
+	}
+	if end >= len(s) || s[end] != ']' {
+		return 0, 0, idx, false
+	}
+	if start == end {
+		return rn, 0, end + 1, true
+	}
+	base := 10
+	if s[start] == '0' {
+		base = 8
+	}
+	count, err := strconv.ParseInt(string(s[start:end]), base, 32)
+	if err != nil {
+		return 0, 0, idx, false
+	}
+	return rn, int(count), end + 1, true
+}
+
+// set is a set of runes for deleting and squeezing
+type set struct {
+	runes      map[rune]struct{}
+	preds      []func(rune) bool
+	complement bool
+}
+
+func newSet(elements []element, complement bool) set {
+	s := set{runes: make(map[rune]struct{}), complement: complement}
+	for _, e := range elements {
+		if e.class != "" {
+			s.preds = append(s.preds, trClasses[e.class])
+			continue
+		}
+		for _, rn := range e.runes {
+			s.runes[rn] = struct{}{}
+		}
+	}
+	return s
+}
+
+func (s set) has(rn rune) bool {
+	return s.in(rn) != s.complement
+}
+
+func (s set) in(rn rune) bool {
+	if _, ok := s.runes[rn]; ok {
+		return true
+	}
+	for _, pred := range s.preds {
+		if pred(rn) {
+			return true
+		}
+	}
+	return false
+}
+
+// span is a character class expanded in array1 on position start
+type span struct {
+	start int
+	runes []rune
+	class string
+}
+
+// expand1 expands array1 into runes, character classes are listed in an
+// ascending order
+func expand1(elements []element) ([]rune, []span) {
+	var ret []rune
+	var spans []span
+	for _, e := range elements {
+		if e.class == "" {
+			ret = append(ret, e.runes...)
+			continue
+		}
+		pred := trClasses[e.class]
+		var runes []rune
+		for rn := rune(0); rn <= unicode.MaxRune; rn++ {
+			if pred(rn) && utf8.ValidRune(rn) {
+				runes = append(runes, rn)
+			}
+		}
+		spans = append(spans, span{start: len(ret), runes: runes, class: e.class})
+		ret = append(ret, runes...)
+	}
+	return ret, spans
+}
+
+// expand2 expands array2 into runes, which corresponds to array1 of length
+// size. Classes [:upper:] and [:lower:] must be aligned with [:upper:] or
+// [:lower:] in array1.
+func expand2(elements []element, size int, spans []span) ([]rune, error) {
+	var ret []rune
+	fill := -1
+	var fillRune rune
+	for _, e := range elements {
+		switch {
+		case e.class != "":
+			if e.class != "upper" && e.class != "lower" {
+				return nil, errNoUpperNeitherLower
+			}
+			var aligned *span
+			for idx := range spans {
+				if spans[idx].start == len(ret) && (spans[idx].class == "upper" || spans[idx].class == "lower") {
+					aligned = &spans[idx]
+					break
+				}
+			}
+			if aligned == nil || fill != -1 {
+				return nil, errMisalignedUpperAndLower
+			}
+			toCase := unicode.ToLower
+			if e.class == "upper" {
+				toCase = unicode.ToUpper
+			}
+			for _, rn := range aligned.runes {
+				ret = append(ret, toCase(rn))
+			}
+		case e.repeat == 0:
+			if fill != -1 {
+				return nil, errors.New("only one [c*] repeat construct may appear in array2")
+			}
+			fill, fillRune = len(ret), e.runes[0]
+		case e.repeat > 0:
+			for i := 0; i < e.repeat; i++ {
+				ret = append(ret, e.runes[0])
+			}
+		default:
+			ret = append(ret, e.runes...)
+		}
+	}
+	if fill != -1 {
+		count := size - len(ret)
+		if count < 1 {
+			count = 1
+		}
+		filled := make([]rune, 0, len(ret)+count)
+		filled = append(filled, ret[:fill]...)
+		for i := 0; i < count; i++ {
+			filled = append(filled, fillRune)
+		}
+		ret = append(filled, ret[fill:]...)
+	}
+	return ret, nil
+}
+
+// translation maps runes from array1 to array2
+type translation struct {
+	m map[rune]rune
+	// complement translation maps all runes not in a set to pad
+	set     set
+	pad     rune
+	padding bool
+}
+
+func newTranslation(elements1, elements2 []element, complement, truncate bool) (translation, error) {
+	t := translation{m: make(map[rune]rune)}
+	if complement {
+		for _, e := range elements2 {
+			if e.class != "" {
+				return t, errors.New("when translating with a complement, array2 can't contain character classes")
+			}
+		}
+		array2, err := expand2(elements2, 0, nil)
+		if err != nil {
+			return t, err
+		}
+		if len(array2) == 0 {
+			if truncate {
+				return t, nil
+			}
+			return t, errEmptyArray2
+		}
+		t.set = newSet(elements1, false)
+		idx := 0
+		for rn := rune(0); rn <= unicode.MaxRune && idx < len(array2); rn++ {
+			if t.set.in(rn) || !utf8.ValidRune(rn) {
+				continue
+			}
+			t.m[rn] = array2[idx]
+			idx++
+		}
+		t.pad = array2[len(array2)-1]
+		t.padding = !truncate
+		return t, nil
+	}
+
+	array1, spans := expand1(elements1)
+	array2, err := expand2(elements2, len(array1), spans)
+	if err != nil {
+		return t, err
+	}
+	if len(array2) < len(array1) {
+		switch {
+		case truncate:
+			array1 = array1[:len(array2)]
+		case len(array2) == 0:
+			return t, errEmptyArray2
+		}
+	}
+	for idx, rn := range array1 {
+		to := array2[len(array2)-1]
+		if idx < len(array2) {
+			to = array2[idx]
+		}
+		t.m[rn] = to
+	}
+	return t, nil
+}
+
+func (t translation) tr(rn rune) rune {
+	if to, ok := t.m[rn]; ok {
+		return to
+	}
+	if t.padding && !t.set.in(rn) {
+		return t.pad
+	}
+	return rn
+}
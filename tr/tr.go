@@ -0,0 +1,269 @@
+// Copyright 2023 Michal Vyskocil. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+/*
+tr translates, squeezes and deletes runes
+
+	tr [-c] [-s] [-t] ARRAY1 ARRAY2
+	tr -s [-c] ARRAY1
+	tr -d [-c] ARRAY1
+	tr -d -s [-c] ARRAY1 ARRAY2
+
+	-c/-C/--complement      use the complement of ARRAY1
+	-d/--delete             delete characters in ARRAY1, do not translate
+	-s/--squeeze-repeats    replace each sequence of a repeated character listed in the
+	                        last specified ARRAY with a single occurrence of that character
+	-t/--truncate-set1      first truncate ARRAY1 to length of ARRAY2
+
+ARRAY supports
+
+	CHAR            a character, \NNN octal and \\ \a \b \f \n \r \t \v escapes
+	CHAR1-CHAR2     all characters from CHAR1 to CHAR2 in ascending order
+	[CHAR*]         in ARRAY2, copies of CHAR until length of ARRAY1
+	[CHAR*REPEAT]   REPEAT copies of CHAR, REPEAT octal if starting with 0
+	[:alnum:] ...   all characters in the class, classes are from the unicode package
+	[=CHAR=]        all characters equivalent to CHAR, which is the CHAR only
+
+Working on runes makes it backward compatible with POSIX tr and supports
+utf-8 well. Ignores unicode combining characters though, user is expected to
+use NFC forms of input. Bytes of invalid utf-8 sequences are copied to the
+output, unless they are deleted or translated as a part of a complement.
+
+When translating, the only classes allowed in ARRAY2 are [:upper:] and
+[:lower:] and they must be aligned with [:upper:] or [:lower:] in ARRAY1.
+ARRAY2 shorter than ARRAY1 is extended by its last character unless -t is
+specified.
+*/
+package tr
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"unicode/utf8"
+
+	"github.com/gomoni/gio/pipe"
+	"github.com/gomoni/gio/unix"
+	"github.com/gomoni/gonix"
+	"github.com/gomoni/gonix/internal"
+	"github.com/gomoni/gonix/internal/dbg"
+	"github.com/spf13/pflag"
+)
+
+type Tr struct {
+	debug      bool
+	array1     string
+	array2     string
+	complement bool // use complement of ARRAY1
+	del        bool // delete characters in ARRAY1
+	squeeze    bool // squeeze repeats in the last ARRAY
+	truncate   bool // truncate ARRAY1 to the length of ARRAY2
+	fsys       fs.FS
+	files      []string
+}
+
+func New() Tr {
+	return Tr{}
+}
+
+func init() {
+	gonix.Register(gonix.Cmd{
+		Name:  "tr",
+		Usage: "translate, squeeze or delete characters",
+		New:   gonix.FromArgs(New().FromArgs),
+	})
+}
+
+// FromArgs build a Tr from standard argv except the command name (os.Argv[1:])
+func (c Tr) FromArgs(argv []string) (Tr, error) {
+	flag := pflag.FlagSet{}
+	flag.BoolVarP(&c.complement, "complement", "c", false, "use the complement of ARRAY1")
+	complement := flag.BoolP("C", "C", false, "same as -c")
+	flag.BoolVarP(&c.del, "delete", "d", false, "delete characters in ARRAY1, do not translate")
+	flag.BoolVarP(&c.squeeze, "squeeze-repeats", "s", false, "squeeze repeated characters listed in the last ARRAY")
+	flag.BoolVarP(&c.truncate, "truncate-set1", "t", false, "first truncate ARRAY1 to length of ARRAY2")
+
+	err := flag.Parse(argv)
+	if err != nil {
+		return Tr{}, pipe.NewErrorf(1, "tr: parsing failed: %w", err)
+	}
+	c.complement = c.complement || *complement
+
+	args := flag.Args()
+	translate := !c.del && !c.squeeze
+	twoArrays := translate || (c.del && c.squeeze)
+	switch {
+	case len(args) == 0:
+		return Tr{}, pipe.NewErrorf(1, "tr: missing operand")
+	case len(args) == 1 && twoArrays:
+		return Tr{}, pipe.NewErrorf(1, "tr: missing operand after %q", args[0])
+	case len(args) == 2 && c.del && !c.squeeze:
+		return Tr{}, pipe.NewErrorf(1, "tr: extra operand %q: only one string may be given when deleting without squeezing repeats", args[1])
+	case len(args) > 2:
+		return Tr{}, pipe.NewErrorf(1, "tr: extra operand %q", args[2])
+	}
+
+	c.array1 = args[0]
+	if len(args) == 2 {
+		c.array2 = args[1]
+	}
+
+	_, err = c.makeTr()
+	if err != nil {
+		return Tr{}, pipe.NewErrorf(1, "tr: %w", err)
+	}
+	return c, nil
+}
+
+func (c Tr) Array1(in string) Tr {
+	c.array1 = in
+	return c
+}
+
+func (c Tr) Array2(in string) Tr {
+	c.array2 = in
+	return c
+}
+
+func (c Tr) Complement(b bool) Tr {
+	c.complement = b
+	return c
+}
+
+func (c Tr) Delete(b bool) Tr {
+	c.del = b
+	return c
+}
+
+// Squeeze replaces a sequence of repeated characters in the last array by a single one
+func (c Tr) Squeeze(b bool) Tr {
+	c.squeeze = b
+	return c
+}
+
+// Truncate truncates array1 to the length of array2
+func (c Tr) Truncate(b bool) Tr {
+	c.truncate = b
+	return c
+}
+
+// Files are input files, where - denotes stdin
+func (c Tr) Files(f ...string) Tr {
+	c.files = append(c.files, f...)
+	return c
+}
+
+// FS sets a filesystem used to open files, nil means the OS filesystem
+func (c Tr) FS(fsys fs.FS) Tr {
+	c.fsys = fsys
+	return c
+}
+
+func (c Tr) SetDebug(debug bool) Tr {
+	c.debug = debug
+	return c
+}
+
+func (c Tr) Run(ctx context.Context, stdio unix.StandardIO) error {
+	debug := dbg.Logger(c.debug, "tr", stdio.Stderr())
+	t, err := c.makeTr()
+	if err != nil {
+		return pipe.NewErrorf(1, "tr: %w", err)
+	}
+	debug.Printf("del=%t, translate=%t, squeeze=%t", c.del, t.translate, c.squeeze)
+
+	stdout := bufio.NewWriterSize(stdio.Stdout(), 4096)
+	tr := func(ctx context.Context, stdio unix.StandardIO, _ int, _ string) error {
+		err := t.run(ctx, stdio.Stdin(), stdout)
+		if err != nil {
+			return internal.NewError(1, fmt.Errorf("tr: fail to run: %w", err))
+		}
+		return nil
+	}
+	errs := internal.NewRunFiles(c.files, stdio, tr).FS(c.fsys).Do(ctx)
+	if err := stdout.Flush(); err != nil {
+		return internal.NewError(1, fmt.Errorf("tr: fail to run: %w", err))
+	}
+	return errs
+}
+
+// trState is a compiled tr, squeeze state is preserved across files
+type trState struct {
+	del         set
+	translate   bool
+	translation translation
+	squeeze     bool
+	squeezeSet  set
+	last        rune
+}
+
+func (c Tr) makeTr() (*trState, error) {
+	elements1, err := parse(c.array1, false)
+	if err != nil {
+		return nil, err
+	}
+	elements2, err := parse(c.array2, true)
+	if err != nil {
+		return nil, err
+	}
+
+	t := &trState{squeeze: c.squeeze, last: -1}
+	switch {
+	case c.del:
+		t.del = newSet(elements1, c.complement)
+		if c.squeeze {
+			t.squeezeSet = newSet(elements2, false)
+		}
+	case c.squeeze && c.array2 == "":
+		t.squeezeSet = newSet(elements1, c.complement)
+	default:
+		t.translate = true
+		t.translation, err = newTranslation(elements1, elements2, c.complement, c.truncate)
+		if err != nil {
+			return nil, err
+		}
+		t.squeezeSet = newSet(elements2, false)
+	}
+	return t, nil
+}
+
+func (t *trState) run(ctx context.Context, in io.Reader, out *bufio.Writer) error {
+	r := bufio.NewReader(in)
+	for {
+		rn, size, err := r.ReadRune()
+		if errors.Is(err, io.EOF) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		if rn == utf8.RuneError && size == 1 {
+			_ = r.UnreadRune()
+			b, _ := r.ReadByte()
+			rn = invalid + rune(b)
+		}
+
+		if t.del.runes != nil && t.del.has(rn) {
+			continue
+		}
+		if t.translate {
+			rn = t.translation.tr(rn)
+		}
+		if t.squeeze && rn == t.last && t.squeezeSet.has(rn) {
+			continue
+		}
+		t.last = rn
+
+		if rn >= invalid {
+			err = out.WriteByte(byte(rn - invalid))
+		} else {
+			_, err = out.WriteRune(rn)
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,280 @@
+// Copyright 2023 Michal Vyskocil. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package tr
+
+import (
+	"testing"
+
+	"github.com/gomoni/gonix/internal/test"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/goleak"
+)
+
+func TestMain(m *testing.M) {
+	goleak.VerifyTestMain(m)
+}
+
+func TestTr(t *testing.T) {
+	test.Parallel(t)
+	testCases := []test.Case[Tr]{
+		{
+			Name:     "tr -d aeiou",
+			Filter:   New().Array1("aeiou").Delete(true),
+			Input:    "three\nsmall\npigs\n",
+			Expected: "thr\nsmll\npgs\n",
+		},
+		{
+			Name:     "tr -d [:space:]",
+			Filter:   New().Array1("[:space:]").Delete(true),
+			Input:    "three\nsmall\npigs\n",
+			Expected: "threesmallpigs",
+		},
+		{
+			Name:     "tr -d \\n",
+			Filter:   New().Array1("\\n").Delete(true),
+			Input:    "three\nsmall\npigs\n",
+			Expected: "threesmallpigs",
+		},
+		{
+			Name:     "tr -d [=t=]\\n",
+			Filter:   New().Array1("[=t=]\\n").Delete(true),
+			Input:    "three\nsmall\npigs\n",
+			Expected: "hreesmallpigs",
+		},
+		{
+			Name:     "tr -d [:digit:][=t=]\\n",
+			Filter:   New().Array1("[:digit:][=t=]\\n").Delete(true),
+			Input:    "1:three\n2:small\n3:pigs\n",
+			Expected: ":hree:small:pigs",
+		},
+		{
+			Name:     "tr -d [:digit:][=t=]\\n\145",
+			Filter:   New().Array1("[:digit:][=t=]\\n\\145").Delete(true),
+			Input:    "1:three\n2:small\n3:pigs\n",
+			Expected: ":hr:small:pigs",
+		},
+		{
+			Name:     "tr -c -d aeiou",
+			Filter:   New().Array1("aeiou").Delete(true).Complement(true),
+			Input:    "three\nsmall\npigs\n",
+			Expected: "eeai",
+		},
+		{
+			Name:     "tr -c -d [:space:]",
+			Filter:   New().Array1("[:space:]").Delete(true).Complement(true),
+			Input:    "three\nsmall\npigs\n",
+			Expected: "\n\n\n",
+		},
+		{
+			Name:     "tr -c -d \\n",
+			Filter:   New().Array1("\\n").Delete(true).Complement(true),
+			Input:    "three\nsmall\npigs\n",
+			Expected: "\n\n\n",
+		},
+		{
+			Name:     "tr -c -d [=t=]\\n",
+			Filter:   New().Array1("[=t=]\\n").Delete(true).Complement(true),
+			Input:    "three\nsmall\npigs\n",
+			Expected: "t\n\n\n",
+		},
+		{
+			Name:     "tr -c -d [:digit:][=t=]\\n",
+			Filter:   New().Array1("[:digit:][=t=]\\n").Delete(true).Complement(true),
+			Input:    "1:three\n2:small\n3:pigs\n",
+			Expected: "1t\n2\n3\n",
+		},
+		{
+			Name:     "tr -c -d [:digit:][=t=]\\n\145",
+			Filter:   New().Array1("[:digit:][=t=]\\n\\145").Delete(true).Complement(true),
+			Input:    "1:three\n2:small\n3:pigs\n",
+			Expected: "1tee\n2\n3\n",
+		},
+		{
+			Name:     "tr e a",
+			Filter:   New().Array1("e").Array2("a"),
+			Input:    "three\nsmall\npigs\n",
+			Expected: "thraa\nsmall\npigs\n",
+		},
+		{
+			Name:     "tr el a",
+			Filter:   New().Array1("el").Array2("a"),
+			Input:    "three\nsmall\npigs\n",
+			Expected: "thraa\nsmaaa\npigs\n",
+		},
+		{
+			Name:     "tr el\\n a",
+			Filter:   New().Array1("el\\n").Array2("a"),
+			Input:    "three\nsmall\npigs\n",
+			Expected: "thraaasmaaaapigsa",
+		},
+		{
+			Name:     "tr el\\n aX",
+			Filter:   New().Array1("el\\n").Array2("aX"),
+			Input:    "three\nsmall\npigs\n",
+			Expected: "thraaXsmaXXXpigsX",
+		},
+		{
+			Name:     "tr e xy",
+			Filter:   New().Array1("e").Array2("xy"),
+			Input:    "three\nsmall\npigs\n",
+			Expected: "thrxx\nsmall\npigs\n",
+		},
+		{
+			Name:     "tr [=e=] xy",
+			Filter:   New().Array1("e").Array2("xy"),
+			Input:    "three\nsmall\npigs\n",
+			Expected: "thrxx\nsmall\npigs\n",
+		},
+		{
+			Name:     "tr [:digit:] X",
+			Filter:   New().Array1("[:digit:]").Array2("X"),
+			Input:    "1:three\n2:small\n3:pigs\n",
+			Expected: "X:three\nX:small\nX:pigs\n",
+		},
+		{
+			Name:     "tr [:digit:] XY",
+			Filter:   New().Array1("[:digit:]").Array2("XY"),
+			Input:    "1:three\n2:small\n3:pigs\n",
+			Expected: "Y:three\nY:small\nY:pigs\n",
+		},
+		{
+			Name:     "tr e[:digit:] XY",
+			Filter:   New().Array1("e[:digit:]").Array2("XY"),
+			Input:    "1:three\n2:small\n3:pigs\n",
+			Expected: "Y:thrXX\nY:small\nY:pigs\n",
+		},
+		{
+			Name:     "tr e[:digit:] X",
+			Filter:   New().Array1("e[:digit:]").Array2("X"),
+			Input:    "1:three\n2:small\n3:pigs\n",
+			Expected: "X:thrXX\nX:small\nX:pigs\n",
+		},
+		{
+			Name:     "tr [:digit:]e X",
+			Filter:   New().Array1("[:digit:]e").Array2("X"),
+			Input:    "1:three\n2:small\n3:pigs\n",
+			Expected: "X:thrXX\nX:small\nX:pigs\n",
+		},
+		{
+			Name:     "tr [:digit:]e XY",
+			Filter:   New().Array1("[:digit:]e").Array2("XY"),
+			Input:    "1:three\n2:small\n3:pigs\n",
+			Expected: "Y:thrYY\nY:small\nY:pigs\n",
+		},
+		{
+			Name:     "tr -s ' '",
+			Filter:   New().Array1(" ").Squeeze(true),
+			FromArgs: fromArgs(t, []string{"-s", " "}),
+			Input:    "three   small  pigs\n",
+			Expected: "three small pigs\n",
+		},
+		{
+			Name:     "tr -t abc xy",
+			Filter:   New().Array1("abc").Array2("xy").Truncate(true),
+			FromArgs: fromArgs(t, []string{"-t", "abc", "xy"}),
+			Input:    "abc",
+			Expected: "xyc",
+		},
+		{
+			Name:     "tr abc xy",
+			Filter:   New().Array1("abc").Array2("xy"),
+			FromArgs: fromArgs(t, []string{"abc", "xy"}),
+			Input:    "abc",
+			Expected: "xyy",
+		},
+		{
+			Name:     "tr a-e x[y*2]z",
+			Filter:   New().Array1("a-e").Array2("x[y*2]z"),
+			FromArgs: fromArgs(t, []string{"a-e", "x[y*2]z"}),
+			Input:    "abcde",
+			Expected: "xyyzz",
+		},
+		{
+			Name:     "tr a-e x[y*]z",
+			Filter:   New().Array1("a-e").Array2("x[y*]z"),
+			FromArgs: fromArgs(t, []string{"a-e", "x[y*]z"}),
+			Input:    "abcde",
+			Expected: "xyyyz",
+		},
+		{
+			Name:     "tr -c a-z\\n _",
+			Filter:   New().Array1("a-z\\n").Array2("_").Complement(true),
+			FromArgs: fromArgs(t, []string{"-c", "a-z\\n", "_"}),
+			Input:    "Hello, World!\n",
+			Expected: "_ello___orld_\n",
+		},
+		{
+			Name:     "tr -cs a-z \\n",
+			Filter:   New().Array1("a-z").Array2("\\n").Complement(true).Squeeze(true),
+			FromArgs: fromArgs(t, []string{"-cs", "a-z", "\\n"}),
+			Input:    "hello, world\n",
+			Expected: "hello\nworld\n",
+		},
+		{
+			Name:     "tr -ds a b",
+			Filter:   New().Array1("a").Array2("b").Delete(true).Squeeze(true),
+			FromArgs: fromArgs(t, []string{"-ds", "a", "b"}),
+			Input:    "aabbcc",
+			Expected: "bcc",
+		},
+		{
+			Name:     "tr [:lower:] [:upper:]",
+			Filter:   New().Array1("[:lower:]").Array2("[:upper:]"),
+			FromArgs: fromArgs(t, []string{"[:lower:]", "[:upper:]"}),
+			Input:    "žluťoučký kůň\n",
+			Expected: "ŽLUŤOUČKÝ KŮŇ\n",
+		},
+		{
+			Name:     "tr a[:upper:] b[:lower:]",
+			Filter:   New().Array1("a[:upper:]").Array2("b[:lower:]"),
+			FromArgs: fromArgs(t, []string{"a[:upper:]", "b[:lower:]"}),
+			Input:    "aŽLUŤ\n",
+			Expected: "bžluť\n",
+		},
+		{
+			Name:     "tr -d invalid utf-8",
+			Filter:   New().Array1("a").Delete(true),
+			FromArgs: fromArgs(t, []string{"-d", "a"}),
+			Input:    "a\xffb",
+			Expected: "\xffb",
+		},
+		{
+			Name:     "tr -C -d a invalid utf-8",
+			Filter:   New().Array1("a").Delete(true).Complement(true),
+			FromArgs: fromArgs(t, []string{"-C", "-d", "a"}),
+			Input:    "a\xffb",
+			Expected: "a",
+		},
+	}
+	test.RunAll(t, testCases)
+}
+
+func TestFromArgsErrors(t *testing.T) {
+	test.Parallel(t)
+	for _, argv := range [][]string{
+		{},
+		{"a"},
+		{"-d", "a", "b"},
+		{"-ds", "a"},
+		{"a", "b", "c"},
+		{"a-z", "[:digit:]"},
+		{"[:lower:]", "x[:upper:]"},
+		{"z-a", "x"},
+		{"-d", "[:foo:]"},
+		{"a", "[x*][y*]"},
+		{"a", ""},
+	} {
+		_, err := New().FromArgs(argv)
+		require.Error(t, err, argv)
+	}
+}
+
+func fromArgs(t *testing.T, argv []string) Tr {
+	t.Helper()
+	n := New()
+	f, err := n.FromArgs(argv)
+	require.NoError(t, err)
+	return f
+}
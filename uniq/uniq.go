@@ -0,0 +1,359 @@
+// Copyright 2023 Michal Vyskocil. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+/*
+uniq reports or omits repeated lines
+
+	uniq [OPTION]... [INPUT [OUTPUT]]
+
+	-c/--count                  prefix lines by the number of occurrences
+	-d/--repeated               only print duplicate lines, one for each group
+	-D/--all-repeated[=METHOD]  print all duplicate lines, METHOD is none, prepend or separate
+	-u/--unique                 only print unique lines
+	-i/--ignore-case            ignore differences in case when comparing
+	-f/--skip-fields N          avoid comparing the first N fields
+	-s/--skip-chars N           avoid comparing the first N characters
+	-w/--check-chars N          compare no more than N characters in lines
+	-z/--zero-terminated        line delimiter is NUL, not newline
+
+A field is a run of blanks followed by non-blank characters. Fields are
+skipped before characters. Characters are UTF-8 runes.
+
+Only adjacent lines are compared, so the input is usually sorted first.
+*/
+package uniq
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"unicode/utf8"
+
+	"github.com/gomoni/gio/pipe"
+	"github.com/gomoni/gio/unix"
+	"github.com/gomoni/gonix"
+	"github.com/gomoni/gonix/internal"
+	"github.com/gomoni/gonix/internal/dbg"
+	"github.com/spf13/pflag"
+)
+
+// Method controls how are groups of all repeated lines delimited
+type Method int
+
+const (
+	// None does not delimit the groups
+	None Method = iota
+	// Prepend prints an empty line before each group
+	Prepend
+	// Separate prints an empty line between groups
+	Separate
+)
+
+type Uniq struct {
+	debug          bool
+	count          bool
+	repeated       bool
+	allRepeated    bool
+	method         Method
+	unique         bool
+	ignoreCase     bool
+	skipFields     int
+	skipChars      int
+	checkChars     int
+	zeroTerminated bool
+	fsys           fs.FS
+	input          string
+	output         string
+}
+
+func New() Uniq {
+	return Uniq{
+		checkChars: -1,
+	}
+}
+
+func init() {
+	gonix.Register(gonix.Cmd{
+		Name:  "uniq",
+		Usage: "report or omit repeated lines",
+		New:   gonix.FromArgs(New().FromArgs),
+	})
+}
+
+// FromArgs build a Uniq from standard argv except the command name (os.Argv[1:])
+func (c Uniq) FromArgs(argv []string) (Uniq, error) {
+	flag := pflag.FlagSet{}
+
+	flag.BoolVarP(&c.count, "count", "c", false, "prefix lines by the number of occurrences")
+	flag.BoolVarP(&c.repeated, "repeated", "d", false, "only print duplicate lines, one for each group")
+	allRepeated := flag.StringP("all-repeated", "D", "", "print all duplicate lines, delimit groups by none, prepend or separate")
+	flag.Lookup("all-repeated").NoOptDefVal = "none"
+	flag.BoolVarP(&c.unique, "unique", "u", false, "only print unique lines")
+	flag.BoolVarP(&c.ignoreCase, "ignore-case", "i", false, "ignore differences in case when comparing")
+	flag.IntVarP(&c.skipFields, "skip-fields", "f", 0, "avoid comparing the first N fields")
+	flag.IntVarP(&c.skipChars, "skip-chars", "s", 0, "avoid comparing the first N characters")
+	flag.IntVarP(&c.checkChars, "check-chars", "w", c.checkChars, "compare no more than N characters in lines")
+	flag.BoolVarP(&c.zeroTerminated, "zero-terminated", "z", false, "line delimiter is NUL, not newline")
+
+	err := flag.Parse(argv)
+	if err != nil {
+		return Uniq{}, pipe.NewErrorf(1, "uniq: parsing failed: %w", err)
+	}
+
+	if flag.Changed("all-repeated") {
+		c.allRepeated = true
+		switch *allRepeated {
+		case "none":
+			c.method = None
+		case "prepend":
+			c.method = Prepend
+		case "separate":
+			c.method = Separate
+		default:
+			return Uniq{}, pipe.NewErrorf(1, "uniq: invalid argument %q for --all-repeated", *allRepeated)
+		}
+	}
+	if c.skipFields < 0 || c.skipChars < 0 || (flag.Changed("check-chars") && c.checkChars < 0) {
+		return Uniq{}, pipe.NewErrorf(1, "uniq: invalid number of fields or characters")
+	}
+	if c.allRepeated && c.count {
+		return Uniq{}, pipe.NewErrorf(1, "uniq: printing all duplicated lines and repeat counts is meaningless")
+	}
+
+	args := flag.Args()
+	switch len(args) {
+	case 0:
+	case 1:
+		c.input = args[0]
+	case 2:
+		c.input, c.output = args[0], args[1]
+	default:
+		return Uniq{}, pipe.NewErrorf(1, "uniq: extra operand %q", args[2])
+	}
+	return c, nil
+}
+
+// Count prefixes lines by the number of occurrences
+func (c Uniq) Count(b bool) Uniq {
+	c.count = b
+	return c
+}
+
+// Repeated prints only one line of each duplicate group
+func (c Uniq) Repeated(b bool) Uniq {
+	c.repeated = b
+	return c
+}
+
+// AllRepeated prints all lines of each duplicate group delimited by method
+func (c Uniq) AllRepeated(b bool, method Method) Uniq {
+	c.allRepeated = b
+	c.method = method
+	return c
+}
+
+// Unique prints only lines which are not repeated
+func (c Uniq) Unique(b bool) Uniq {
+	c.unique = b
+	return c
+}
+
+func (c Uniq) IgnoreCase(b bool) Uniq {
+	c.ignoreCase = b
+	return c
+}
+
+// SkipFields avoids comparing the first n fields
+func (c Uniq) SkipFields(n int) Uniq {
+	c.skipFields = n
+	return c
+}
+
+// SkipChars avoids comparing the first n characters
+func (c Uniq) SkipChars(n int) Uniq {
+	c.skipChars = n
+	return c
+}
+
+// CheckChars compares no more than n characters, negative value means all
+func (c Uniq) CheckChars(n int) Uniq {
+	c.checkChars = n
+	return c
+}
+
+func (c Uniq) ZeroTerminated(b bool) Uniq {
+	c.zeroTerminated = b
+	return c
+}
+
+// Input is a file to read, empty or - means stdin
+func (c Uniq) Input(name string) Uniq {
+	c.input = name
+	return c
+}
+
+// FS sets a filesystem used to open the input, nil means the OS filesystem.
+// Output is always written to the OS filesystem.
+func (c Uniq) FS(fsys fs.FS) Uniq {
+	c.fsys = fsys
+	return c
+}
+
+// Output is a file to write, empty or - means stdout
+func (c Uniq) Output(name string) Uniq {
+	c.output = name
+	return c
+}
+
+func (c Uniq) SetDebug(debug bool) Uniq {
+	c.debug = debug
+	return c
+}
+
+func (c Uniq) Run(ctx context.Context, stdio unix.StandardIO) error {
+	debug := dbg.Logger(c.debug, "uniq", stdio.Stderr())
+	debug.Printf("input=%q, output=%q", c.input, c.output)
+
+	var in io.Reader = stdio.Stdin()
+	if c.input != "" && c.input != "-" {
+		f, err := internal.Open(c.fsys, c.input)
+		if err != nil {
+			return internal.NewError(1, fmt.Errorf("uniq: %w", err))
+		}
+		defer f.Close()
+		in = f
+	}
+
+	var out io.Writer = stdio.Stdout()
+	var outFile *os.File
+	if c.output != "" && c.output != "-" {
+		f, err := os.Create(c.output)
+		if err != nil {
+			return internal.NewError(1, fmt.Errorf("uniq: %w", err))
+		}
+		defer f.Close()
+		out, outFile = f, f
+	}
+
+	err := c.uniq(ctx, in, out)
+	if err == nil && outFile != nil {
+		err = outFile.Close()
+	}
+	if err != nil {
+		return internal.NewError(1, fmt.Errorf("uniq: fail to run: %w", err))
+	}
+	return nil
+}
+
+func (c Uniq) uniq(ctx context.Context, in io.Reader, w io.Writer) error {
+	delim := byte('\n')
+	if c.zeroTerminated {
+		delim = 0
+	}
+	reader := bufio.NewReader(in)
+	out := bufio.NewWriter(w)
+
+	var first []byte
+	var count int
+	var groups int
+
+	write := func(line []byte) {
+		out.Write(line)
+		out.WriteByte(delim)
+	}
+
+	flush := func() {
+		if count == 0 || c.allRepeated {
+			return
+		}
+		if (c.repeated && count == 1) || (c.unique && count > 1) {
+			return
+		}
+		if c.count {
+			fmt.Fprintf(out, "%7d ", count)
+		}
+		write(first)
+	}
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		line, err := reader.ReadBytes(delim)
+		if len(line) > 0 && line[len(line)-1] == delim {
+			line = line[:len(line)-1]
+		} else if len(line) == 0 && errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil && !errors.Is(err, io.EOF) {
+			return err
+		}
+
+		if count > 0 && c.equal(first, line) {
+			count++
+			if c.allRepeated {
+				if count == 2 {
+					if (c.method == Prepend) || (c.method == Separate && groups > 0) {
+						out.WriteByte(delim)
+					}
+					groups++
+					write(first)
+				}
+				write(line)
+			}
+		} else {
+			flush()
+			first = line
+			count = 1
+		}
+
+		if err != nil {
+			break
+		}
+	}
+	flush()
+	return out.Flush()
+}
+
+func (c Uniq) equal(a, b []byte) bool {
+	a, b = c.key(a), c.key(b)
+	if c.ignoreCase {
+		return bytes.EqualFold(a, b)
+	}
+	return bytes.Equal(a, b)
+}
+
+// key returns a part of the line used for comparison
+func (c Uniq) key(line []byte) []byte {
+	for i := 0; i < c.skipFields; i++ {
+		for len(line) > 0 && isBlank(line[0]) {
+			line = line[1:]
+		}
+		for len(line) > 0 && !isBlank(line[0]) {
+			line = line[1:]
+		}
+	}
+	for i := 0; i < c.skipChars && len(line) > 0; i++ {
+		_, size := utf8.DecodeRune(line)
+		line = line[size:]
+	}
+	if c.checkChars >= 0 {
+		var end int
+		for i := 0; i < c.checkChars && end < len(line); i++ {
+			_, size := utf8.DecodeRune(line[end:])
+			end += size
+		}
+		line = line[:end]
+	}
+	return line
+}
+
+func isBlank(b byte) bool {
+	return b == ' ' || b == '\t'
+}
@@ -0,0 +1,170 @@
+// Copyright 2023 Michal Vyskocil. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package uniq_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gomoni/gio/unix"
+	"github.com/gomoni/gonix/internal/test"
+	. "github.com/gomoni/gonix/uniq"
+	"github.com/stretchr/testify/require"
+)
+
+const input = "a\na\nb\nc\nc\nc\nd\n"
+
+func TestUniq(t *testing.T) {
+	test.Parallel(t)
+	testCases := []test.Case[Uniq]{
+		{
+			Name:     "uniq",
+			Filter:   New(),
+			FromArgs: fromArgs(t, []string{}),
+			Input:    input,
+			Expected: "a\nb\nc\nd\n",
+		},
+		{
+			Name:     "-c",
+			Filter:   New().Count(true),
+			FromArgs: fromArgs(t, []string{"-c"}),
+			Input:    input,
+			Expected: "      2 a\n      1 b\n      3 c\n      1 d\n",
+		},
+		{
+			Name:     "-d",
+			Filter:   New().Repeated(true),
+			FromArgs: fromArgs(t, []string{"-d"}),
+			Input:    input,
+			Expected: "a\nc\n",
+		},
+		{
+			Name:     "-u",
+			Filter:   New().Unique(true),
+			FromArgs: fromArgs(t, []string{"-u"}),
+			Input:    input,
+			Expected: "b\nd\n",
+		},
+		{
+			Name:     "-D",
+			Filter:   New().AllRepeated(true, None),
+			FromArgs: fromArgs(t, []string{"-D"}),
+			Input:    input,
+			Expected: "a\na\nc\nc\nc\n",
+		},
+		{
+			Name:     "--all-repeated=separate",
+			Filter:   New().AllRepeated(true, Separate),
+			FromArgs: fromArgs(t, []string{"--all-repeated=separate"}),
+			Input:    input,
+			Expected: "a\na\n\nc\nc\nc\n",
+		},
+		{
+			Name:     "--all-repeated=prepend",
+			Filter:   New().AllRepeated(true, Prepend),
+			FromArgs: fromArgs(t, []string{"--all-repeated=prepend"}),
+			Input:    input,
+			Expected: "\na\na\n\nc\nc\nc\n",
+		},
+		{
+			Name:     "-i",
+			Filter:   New().IgnoreCase(true).Count(true),
+			FromArgs: fromArgs(t, []string{"-i", "-c"}),
+			Input:    "Žluť\nžLUŤ\nx\n",
+			Expected: "      2 Žluť\n      1 x\n",
+		},
+		{
+			Name:     "-f 1",
+			Filter:   New().SkipFields(1),
+			FromArgs: fromArgs(t, []string{"-f", "1"}),
+			Input:    "1 pig\n2 pig\n3 wolf\n",
+			Expected: "1 pig\n3 wolf\n",
+		},
+		{
+			Name:     "-s 2",
+			Filter:   New().SkipChars(2),
+			FromArgs: fromArgs(t, []string{"-s", "2"}),
+			Input:    "ěšpig\nxxpig\nxxwolf\n",
+			Expected: "ěšpig\nxxwolf\n",
+		},
+		{
+			Name:     "-w 1",
+			Filter:   New().CheckChars(1),
+			FromArgs: fromArgs(t, []string{"-w", "1"}),
+			Input:    "pigs\npony\nwolf\n",
+			Expected: "pigs\nwolf\n",
+		},
+		{
+			Name:     "-f 1 -s 1 -w 2",
+			Filter:   New().SkipFields(1).SkipChars(1).CheckChars(2),
+			FromArgs: fromArgs(t, []string{"-f1", "-s1", "-w2"}),
+			Input:    "a xab1\nb xac2\nc yab3\n",
+			Expected: "a xab1\nc yab3\n",
+		},
+		{
+			Name:     "-z",
+			Filter:   New().ZeroTerminated(true),
+			FromArgs: fromArgs(t, []string{"-z"}),
+			Input:    "a\x00a\x00b\nb",
+			Expected: "a\x00b\nb\x00",
+		},
+		{
+			Name:     "missing newline",
+			Filter:   New(),
+			FromArgs: fromArgs(t, []string{}),
+			Input:    "a\na",
+			Expected: "a\n",
+		},
+	}
+	test.RunAll(t, testCases)
+}
+
+func TestInputOutput(t *testing.T) {
+	test.Parallel(t)
+	pigs := test.Testdata(t, "three-small-pigs")
+	output := filepath.Join(t.TempDir(), "out")
+
+	uniq := fromArgs(t, []string{"-c", pigs, output})
+	require.Equal(t, New().Count(true).Input(pigs).Output(output), uniq)
+
+	var out strings.Builder
+	stdio := unix.NewStdio(strings.NewReader("stdin\n"), &out, os.Stderr)
+	err := uniq.Run(context.Background(), stdio)
+	require.NoError(t, err)
+	require.Empty(t, out.String())
+
+	b, err := os.ReadFile(output)
+	require.NoError(t, err)
+	require.Equal(t, "      1 three\n      1 small\n      1 pigs\n", string(b))
+}
+
+func TestErrors(t *testing.T) {
+	test.Parallel(t)
+	for _, argv := range [][]string{
+		{"-D", "-c"},
+		{"--all-repeated=never"},
+		{"-f", "-1"},
+		{"a", "b", "c"},
+	} {
+		_, err := New().FromArgs(argv)
+		require.Error(t, err, argv)
+	}
+
+	var out strings.Builder
+	stdio := unix.NewStdio(strings.NewReader(input), &out, &out)
+	err := New().Input("does-not-exist").Run(context.Background(), stdio)
+	require.Error(t, err)
+}
+
+func fromArgs(t *testing.T, argv []string) Uniq {
+	t.Helper()
+	n := New()
+	f, err := n.FromArgs(argv)
+	require.NoError(t, err)
+	return f
+}
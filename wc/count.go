@@ -0,0 +1,229 @@
+// Copyright 2023 Michal Vyskocil. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package wc
+
+import (
+	"context"
+	"errors"
+	"io"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/width"
+)
+
+// wc counts a raw stream of bytes split into blocks. Each block is counted
+// independently into a partial, which contains enough information to merge
+// it with a previous one, so blocks can be counted concurrently. Blocks are
+// split on rune boundaries only.
+//
+// Characters are UTF-8 runes, an invalid byte counts as one character. Words
+// are delimited by unicode.IsSpace. Display width counts East Asian wide
+// runes as 2, control characters, combining marks and invalid bytes as 0 and
+// tabs advance to the next multiple of 8. \r and \f end the line like \n
+// does for a width, but are not counted as lines.
+
+// span is a display width of a text which does not start on column zero
+type span struct {
+	width int  // width up to the first tab
+	tab   bool // span contains a tab
+	after int  // width after the first tab
+}
+
+// at returns a column after the span starting on col
+func (s span) at(col int) int {
+	if !s.tab {
+		return col + s.width
+	}
+	return tabstop(col+s.width) + s.after
+}
+
+// partial are stats of one block
+type partial struct {
+	stats
+	startsWord bool // the first rune is not a space
+	endsWord   bool // the last rune is not a space
+	broken     bool // block contains a line break
+	head       span // text up to the first line break
+	tail       int  // width of the text after the last line break
+}
+
+// countBlock counts the block, width is computed only if widths is true
+func countBlock(b []byte, widths bool) partial {
+	p := partial{stats: stats{bytes: len(b)}}
+	var col, headWidth int
+	var headTab, inWord bool
+
+	for i := 0; i < len(b); {
+		r, size := rune(b[i]), 1
+		if r >= utf8.RuneSelf {
+			r, size = utf8.DecodeRune(b[i:])
+		}
+		p.chars++
+		if r == '\n' {
+			p.lines++
+		}
+
+		if unicode.IsSpace(r) {
+			inWord = false
+		} else if !inWord {
+			inWord = true
+			p.words++
+			if i == 0 {
+				p.startsWord = true
+			}
+		}
+
+		if widths {
+			switch r {
+			case '\n', '\r', '\f':
+				if !p.broken {
+					p.head = makeSpan(headTab, headWidth, col)
+					p.broken = true
+				} else if col > p.maxLineLength {
+					p.maxLineLength = col
+				}
+				col = 0
+			case '\t':
+				if !p.broken && !headTab {
+					headTab, headWidth = true, col
+				}
+				col = tabstop(col)
+			default:
+				col += runeWidth(r, size)
+			}
+		}
+		i += size
+	}
+
+	p.endsWord = inWord
+	if !p.broken {
+		p.head = makeSpan(headTab, headWidth, col)
+	} else {
+		p.tail = col
+	}
+	return p
+}
+
+func makeSpan(tab bool, headWidth, col int) span {
+	if !tab {
+		return span{width: col}
+	}
+	return span{width: headWidth, tab: true, after: col - tabstop(headWidth)}
+}
+
+// tabstop returns the next multiple of 8
+func tabstop(col int) int {
+	return (col/8 + 1) * 8
+}
+
+func runeWidth(r rune, size int) int {
+	switch {
+	case r == utf8.RuneError && size == 1:
+		return 0
+	case r < 0x20 || r == 0x7f:
+		return 0
+	case r < utf8.RuneSelf:
+		return 1
+	case unicode.In(r, unicode.Mn, unicode.Me, unicode.Cf) || !unicode.IsGraphic(r):
+		return 0
+	}
+	switch width.LookupRune(r).Kind() {
+	case width.EastAsianWide, width.EastAsianFullwidth:
+		return 2
+	}
+	return 1
+}
+
+// total merges partials in the order of blocks
+type total struct {
+	stats
+	col    int
+	inWord bool
+}
+
+func (t *total) merge(p partial) {
+	t.bytes += p.bytes
+	t.chars += p.chars
+	t.lines += p.lines
+	t.words += p.words
+	if t.inWord && p.startsWord {
+		// a word split between blocks
+		t.words--
+	}
+	t.inWord = p.endsWord
+
+	col := p.head.at(t.col)
+	if !p.broken {
+		t.col = col
+		return
+	}
+	if col > t.maxLineLength {
+		t.maxLineLength = col
+	}
+	if p.maxLineLength > t.maxLineLength {
+		t.maxLineLength = p.maxLineLength
+	}
+	t.col = p.tail
+}
+
+// result returns stats including the last unterminated line
+func (t total) result() stats {
+	s := t.stats
+	if t.col > s.maxLineLength {
+		s.maxLineLength = t.col
+	}
+	return s
+}
+
+// readBlocks reads in by blocks split on rune boundaries and calls fn for
+// each of them. newBuf returns a buffer for the next block, so it can be
+// reused if fn does not retain the block.
+func readBlocks(ctx context.Context, in io.Reader, newBuf func() []byte, fn func([]byte) error) error {
+	var tmp [utf8.UTFMax]byte
+	var carry []byte
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		buf := newBuf()
+		n0 := copy(buf, carry)
+		n, err := io.ReadFull(in, buf[n0:])
+		buf = buf[:n0+n]
+		eof := errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF)
+		if err != nil && !eof {
+			return err
+		}
+
+		block := buf
+		carry = nil
+		if !eof {
+			cut := runeCut(buf)
+			block, carry = buf[:cut], append(tmp[:0], buf[cut:]...)
+		}
+		if len(block) > 0 {
+			if err := fn(block); err != nil {
+				return err
+			}
+		}
+		if eof {
+			return nil
+		}
+	}
+}
+
+// runeCut returns an index where the buffer can be split, so no rune spans
+// the boundary
+func runeCut(buf []byte) int {
+	for i := len(buf) - 1; i > 0 && i > len(buf)-utf8.UTFMax; i-- {
+		if utf8.RuneStart(buf[i]) {
+			if utf8.FullRune(buf[i:]) {
+				return len(buf)
+			}
+			return i
+		}
+	}
+	return len(buf)
+}
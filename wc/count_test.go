@@ -0,0 +1,66 @@
+// Copyright 2023 Michal Vyskocil. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package wc
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCountBlock(t *testing.T) {
+	t.Parallel()
+	testCases := []struct {
+		input    string
+		expected stats
+	}{
+		{"", stats{}},
+		{"no newline", stats{bytes: 10, chars: 10, words: 2, maxLineLength: 10}},
+		{"three\nsmall\npigs\n", stats{bytes: 17, chars: 17, lines: 3, words: 3, maxLineLength: 5}},
+		{"日本\tx\n", stats{bytes: 9, chars: 5, lines: 1, words: 2, maxLineLength: 9}},
+		{"x\ty\tzz\t\n12345678\tq", stats{bytes: 18, chars: 18, lines: 1, words: 5, maxLineLength: 24}},
+		{"abc\rde\n", stats{bytes: 7, chars: 7, lines: 1, words: 2, maxLineLength: 3}},
+		{"e\u0301\x00\n", stats{bytes: 5, chars: 4, lines: 1, words: 1, maxLineLength: 1}},
+		{"\xff\xfe ab\n", stats{bytes: 6, chars: 6, lines: 1, words: 2, maxLineLength: 3}},
+	}
+
+	for _, tt := range testCases {
+		var whole total
+		whole.merge(countBlock([]byte(tt.input), true))
+		require.Equal(t, tt.expected, whole.result(), tt.input)
+	}
+}
+
+// TestMerge splits inputs on all rune boundaries and compares the merged result
+func TestMerge(t *testing.T) {
+	t.Parallel()
+	inputs := []string{
+		"The three žluťoučká\nsmall\npigs\n",
+		"\t日本\tab\tcdefghijkl\tmn\n\t\tx\r\fyy\ty",
+		"  words  split\tby　spaces  \n",
+	}
+	for _, input := range inputs {
+		var whole total
+		whole.merge(countBlock([]byte(input), true))
+		expected := whole.result()
+
+		for i := 1; i < len(input); i++ {
+			for j := i; j < len(input); j++ {
+				b := []byte(input)
+				if runeCut(b[:i]) != i || runeCut(b[:j]) != j {
+					continue
+				}
+				var split total
+				for _, block := range [][]byte{b[:i], b[i:j], b[j:]} {
+					if len(block) > 0 {
+						split.merge(countBlock(block, true))
+					}
+				}
+				require.Equal(t, expected, split.result(), fmt.Sprintf("%q split at %d, %d", input, i, j))
+			}
+		}
+	}
+}
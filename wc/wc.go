@@ -12,29 +12,37 @@
 
    --files0-from=F
           read input from the files specified by NUL-terminated names in file F; If F is - then read names from standard input
+          each file is printed once counted without an alignment
+
+   -j, --threads=N
+          count N files concurrently, a single input is split into chunks counted concurrently; 0 means GOMAXPROCS
 
    --version
           output version information and exit
 
+Input is counted in blocks of raw bytes, so there is no limit for a line length. Characters are
+UTF-8 runes, an invalid byte counts as one. Maximum line length is a display width, where East
+Asian wide characters are 2 columns and tabs advance to the next multiple of 8.
+
 */
 
 package wc
 
 import (
-	"bufio"
-	"bytes"
 	"context"
 	"fmt"
 	"io"
+	"io/fs"
 	"log"
-	"sort"
+	"runtime"
 	"strconv"
 	"strings"
+	"sync"
 	"text/tabwriter"
-	"unicode/utf8"
 
 	"github.com/gomoni/gio/pipe"
 	"github.com/gomoni/gio/unix"
+	"github.com/gomoni/gonix"
 	"github.com/gomoni/gonix/internal"
 	"github.com/gomoni/gonix/internal/dbg"
 	"github.com/spf13/pflag"
@@ -42,11 +50,14 @@ import (
 
 type Wc struct {
 	debug         bool
+	threads       uint
+	files0From    string
 	bytes         bool
 	chars         bool
 	lines         bool
 	maxLineLength bool
 	words         bool
+	fsys          fs.FS
 	files         []string
 }
 
@@ -54,6 +65,14 @@ func New() Wc {
 	return Wc{}
 }
 
+func init() {
+	gonix.Register(gonix.Cmd{
+		Name:  "wc",
+		Usage: "print newline, word, and byte counts for each file",
+		New:   gonix.FromArgs(New().FromArgs),
+	})
+}
+
 // FromArgs builds a WcFilter from standard argv except the command name (os.Argv[1:])
 func (c Wc) FromArgs(argv []string) (Wc, error) {
 	if len(argv) == 0 {
@@ -67,12 +86,20 @@ func (c Wc) FromArgs(argv []string) (Wc, error) {
 	flag.BoolVarP(&c.lines, "lines", "l", false, "print number of lines")
 	flag.BoolVarP(&c.maxLineLength, "max-line-length", "L", false, "print maximum display width")
 	flag.BoolVarP(&c.words, "words", "w", false, "print number of words")
+	flag.StringVar(&c.files0From, "files0-from", "", "read input from the files specified by NUL-terminated names in file F")
+	flag.UintVarP(&c.threads, "threads", "j", 0, "count using N goroutines, 0 equals GOMAXPROCS")
 
 	err := flag.Parse(argv)
 	if err != nil {
 		return Wc{}, pipe.NewErrorf(1, "wc: parsing failed: %w", err)
 	}
+	if !c.bytes && !c.chars && !c.lines && !c.maxLineLength && !c.words {
+		c = c.Bytes(true).Lines(true).Words(true)
+	}
 	if len(flag.Args()) > 0 {
+		if c.files0From != "" {
+			return Wc{}, pipe.NewErrorf(1, "wc: %w", internal.ErrFiles0Operands(flag.Args()[0]))
+		}
 		c.files = flag.Args()
 	}
 
@@ -110,6 +137,25 @@ func (w Wc) Files(files ...string) Wc {
 	return w
 }
 
+// Files0From reads NUL terminated names of files from a file, - means stdin
+func (w Wc) Files0From(name string) Wc {
+	w.files0From = name
+	return w
+}
+
+// FS sets a filesystem used to open files, nil means the OS filesystem
+func (w Wc) FS(fsys fs.FS) Wc {
+	w.fsys = fsys
+	return w
+}
+
+// Parallel counts up to limit files concurrently, a single input is split
+// into chunks counted concurrently. Zero means GOMAXPROCS.
+func (w Wc) Parallel(limit uint) Wc {
+	w.threads = limit
+	return w
+}
+
 func (w Wc) SetDebug(debug bool) Wc {
 	w.debug = debug
 	return w
@@ -118,26 +164,49 @@ func (w Wc) SetDebug(debug bool) Wc {
 func (c Wc) Run(ctx context.Context, stdio unix.StandardIO) error {
 	debug := dbg.Logger(c.debug, "wc", stdio.Stderr())
 
+	if c.threads == 0 {
+		c.threads = uint(runtime.GOMAXPROCS(0))
+	}
+	debug.Printf("running with --threads %d", c.threads)
+
+	if c.files0From != "" {
+		return c.runFiles0(ctx, stdio, debug)
+	}
+
 	files := c.files
 	if len(files) == 0 {
 		files = []string{""}
 	}
-	stat := make([]stats, 0, len(c.files))
-	total := stats{fileName: "total"}
+	// a single input is split into chunks, more inputs are counted concurrently
+	chunkThreads := uint(1)
+	if len(files) == 1 {
+		chunkThreads = c.threads
+	}
 
-	wc := func(ctx context.Context, stdio unix.StandardIO, _ int, name string) error {
-		st, err := c.runFile(ctx, stdio.Stdin(), debug)
+	// each file writes own slot, so the order does not depend on scheduling
+	slots := make([]*stats, len(files))
+	wc := func(ctx context.Context, stdio unix.StandardIO, idx int, name string) error {
+		st, err := c.count(ctx, stdio.Stdin(), chunkThreads, debug)
 		if err != nil {
-			return pipe.NewError(1, fmt.Errorf("wc: fail to run: %w", err))
+			return internal.NewError(1, fmt.Errorf("wc: fail to run: %w", err))
 		}
 		st.fileName = name
-		total.add(st)
-		stat = append(stat, st)
+		slots[idx] = &st
 		return nil
 	}
 
-	runFiles := internal.NewRunFiles(c.files, stdio, wc)
-	errs := runFiles.Do(ctx)
+	runFiles := internal.NewRunFiles(c.files, stdio, wc).FS(c.fsys)
+	errs := runFiles.DoThreads(ctx, c.threads)
+
+	stat := make([]stats, 0, len(files))
+	total := stats{fileName: "total"}
+	for _, st := range slots {
+		if st == nil {
+			continue
+		}
+		total.add(*st)
+		stat = append(stat, *st)
+	}
 
 	percents, argsFn := c.percentsArgsFn()
 	stdinOnly := len(files) == 1 && files[0] == ""
@@ -147,7 +216,7 @@ func (c Wc) Run(ctx context.Context, stdio unix.StandardIO) error {
 	} else {
 		template = fmt.Sprintf("%s\t %%s\n", strings.Join(percents, "\t"))
 	}
-	minWidth := total.maxLen()
+	minWidth := total.maxLen(argsFn)
 	padding := 1
 	if len(stat) == 1 && len(argsFn) == 1 {
 		padding = 0
@@ -157,6 +226,9 @@ func (c Wc) Run(ctx context.Context, stdio unix.StandardIO) error {
 	w := tabwriter.NewWriter(stdio.Stdout(), minWidth-padding, 8, padding, ' ', tabwriter.AlignRight)
 
 	if stdinOnly {
+		if len(stat) == 0 {
+			return errs
+		}
 		args := make([]any, 0, len(argsFn))
 		for _, fn := range argsFn {
 			args = append(args, fn(stat[0]))
@@ -164,7 +236,7 @@ func (c Wc) Run(ctx context.Context, stdio unix.StandardIO) error {
 		fmt.Fprintf(w, template, args...)
 		err := w.Flush()
 		if err != nil {
-			return pipe.NewErrorf(1, "wc: pipe flush: %w", err)
+			return internal.NewError(1, fmt.Errorf("wc: pipe flush: %w", err))
 		}
 		if errs != nil {
 			return pipe.NewError(1, errs)
@@ -184,7 +256,7 @@ func (c Wc) Run(ctx context.Context, stdio unix.StandardIO) error {
 
 	err := w.Flush()
 	if err != nil {
-		return pipe.NewErrorf(1, "wc: tabwriter flush: %w", err)
+		return internal.NewError(1, fmt.Errorf("wc: tabwriter flush: %w", err))
 	}
 
 	debug.Printf("exiting")
@@ -194,39 +266,93 @@ func (c Wc) Run(ctx context.Context, stdio unix.StandardIO) error {
 	return nil
 }
 
-func (c Wc) runFile(ctx context.Context, in io.Reader, debug *log.Logger) (stats, error) {
-	var stat stats
-	s := bufio.NewScanner(in)
-	for s.Scan() {
-		if s.Err() != nil {
-			return stat, s.Err()
+// runFiles0 counts files from --files0-from list. The length of the list is
+// not known in advance, so each file is printed once counted without an
+// alignment like GNU wc does. The total is printed for more than one file.
+func (c Wc) runFiles0(ctx context.Context, stdio unix.StandardIO, debug *log.Logger) error {
+	_, argsFn := c.percentsArgsFn()
+	print := func(w io.Writer, st stats) error {
+		var b []byte
+		for _, fn := range argsFn {
+			b = strconv.AppendInt(b, int64(fn(st)), 10)
+			b = append(b, ' ')
 		}
-		if ctx.Err() != nil {
-			return stat, ctx.Err()
+		b = append(b, st.fileName...)
+		b = append(b, '\n')
+		_, err := w.Write(b)
+		return err
+	}
+
+	var mu sync.Mutex
+	var n int
+	total := stats{fileName: "total"}
+	wc := func(ctx context.Context, stdio unix.StandardIO, _ int, name string) error {
+		st, err := c.count(ctx, stdio.Stdin(), 1, debug)
+		if err != nil {
+			return internal.NewError(1, fmt.Errorf("wc: fail to run: %w", err))
 		}
-		if c.bytes {
-			// TODO: windows has two(?)
-			stat.bytes += len(s.Bytes()) + 1
+		st.fileName = name
+		mu.Lock()
+		total.add(st)
+		n++
+		mu.Unlock()
+		return print(stdio.Stdout(), st)
+	}
+
+	runFiles := internal.NewRunFiles(nil, stdio, wc).FS(c.fsys).Files0From(c.files0From)
+	errs := runFiles.DoThreads(ctx, c.threads)
+	if n > 1 {
+		err := print(stdio.Stdout(), total)
+		if err != nil {
+			return internal.NewError(1, fmt.Errorf("wc: %w", err))
 		}
-		if c.chars || c.maxLineLength {
-			count := utf8.RuneCount(s.Bytes())
-			// TODO: windows has two(?)
-			stat.chars += count + 1
-			if count > stat.maxLineLength {
-				// \n does not count to maxLineLength
-				stat.maxLineLength = count
-			}
+	}
+	return errs
+}
+
+// chunkSize is a size of a block read at once and counted by one goroutine
+const chunkSize = 1 << 20
+
+// count counts stats of in, if threads > 1, blocks are counted concurrently
+func (c Wc) count(ctx context.Context, in io.Reader, threads uint, debug *log.Logger) (stats, error) {
+	var t total
+	if threads <= 1 {
+		buf := make([]byte, chunkSize)
+		err := readBlocks(ctx, in, func() []byte { return buf }, func(block []byte) error {
+			t.merge(countBlock(block, c.maxLineLength))
+			return nil
+		})
+		return t.result(), err
+	}
+
+	// queue keeps blocks in order and bounds a number of blocks in memory
+	queue := make(chan chan partial, threads)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for r := range queue {
+			t.merge(<-r)
 		}
-		if c.words {
-			ws := bufio.NewScanner(bytes.NewReader(s.Bytes()))
-			ws.Split(bufio.ScanWords)
-			for ws.Scan() {
-				stat.words += 1
-			}
+	}()
+
+	var blocks int
+	err := readBlocks(ctx, in, func() []byte { return make([]byte, chunkSize) }, func(block []byte) error {
+		r := make(chan partial, 1)
+		select {
+		case queue <- r:
+		case <-ctx.Done():
+			return ctx.Err()
 		}
-		stat.lines++
-	}
-	return stat, nil
+		go func() {
+			r <- countBlock(block, c.maxLineLength)
+		}()
+		blocks++
+		return nil
+	})
+	close(queue)
+	<-done
+	debug.Printf("counted %d blocks concurrently", blocks)
+	return t.result(), err
 }
 
 // percentsArgsFn ensures wc prints in following order: newline, word,
@@ -270,18 +396,19 @@ func (s *stats) add(t stats) {
 	s.bytes += t.bytes
 	s.chars += t.chars
 	s.lines += t.lines
-	s.maxLineLength += t.maxLineLength
+	if t.maxLineLength > s.maxLineLength {
+		s.maxLineLength = t.maxLineLength
+	}
 	s.words += t.words
 }
 
-func (s stats) maxLen() int {
-	foo := [5]int{
-		s.bytes,
-		s.chars,
-		s.lines,
-		s.maxLineLength,
-		s.words,
+// maxLen returns the length of the biggest printed number
+func (s stats) maxLen(argsFn []func(stats) int) int {
+	var max int
+	for _, fn := range argsFn {
+		if n := fn(s); n > max {
+			max = n
+		}
 	}
-	sort.Ints(foo[:])
-	return len(strconv.Itoa(foo[4]))
+	return len(strconv.Itoa(max))
 }
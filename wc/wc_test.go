@@ -5,9 +5,16 @@
 package wc_test
 
 import (
+	"context"
 	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"testing/fstest"
 
+	"github.com/gomoni/gio/unix"
 	"github.com/gomoni/gonix/internal/test"
 	. "github.com/gomoni/gonix/wc"
 
@@ -45,6 +52,13 @@ func TestWc(t *testing.T) {
 			Input:    "The three žluťoučká\nsmall\npigs\n",
 			Expected: " 3 5 31 35 19\n",
 		},
+		{
+			Name:     "wc -cL long line without newline",
+			Filter:   New().Bytes(true).MaxLineLength(true),
+			FromArgs: fromArgs(t, []string{"-cL"}),
+			Input:    strings.Repeat("x", 1<<17),
+			Expected: " 131072 131072\n",
+		},
 		{
 			Name:     "wc -l - three-small-pigs",
 			Filter:   New().Lines(true).Files("-", threeSmallPigs),
@@ -52,11 +66,89 @@ func TestWc(t *testing.T) {
 			Input:    "1\n2\n3\n4\n",
 			Expected: fmt.Sprintf(" 4 -\n 3 %s\n 7 total\n", threeSmallPigs),
 		},
+		{
+			Name:     "wc -l -j 2 - three-small-pigs",
+			Filter:   New().Lines(true).Parallel(2).Files("-", threeSmallPigs),
+			FromArgs: fromArgs(t, []string{"-l", "-j", "2", "-", threeSmallPigs}),
+			Input:    "1\n2\n3\n4\n",
+			Expected: fmt.Sprintf(" 4 -\n 3 %s\n 7 total\n", threeSmallPigs),
+		},
 	}
 
 	test.RunAll(t, testCases)
 }
 
+func TestParallel(t *testing.T) {
+	test.Parallel(t)
+
+	// more than one chunk with a last line without newline
+	var sb strings.Builder
+	for sb.Len() < 3<<20 {
+		sb.WriteString("The three žluťoučká\tsmall pigs\n")
+	}
+	sb.WriteString("no newline")
+	input := sb.String()
+
+	temp := t.TempDir()
+	files := make([]string, 0, 16)
+	for i := 0; i < cap(files); i++ {
+		name := filepath.Join(temp, fmt.Sprintf("%02d", i))
+		require.NoError(t, os.WriteFile(name, []byte(strings.Repeat("x y\n", i)), 0600))
+		files = append(files, name)
+	}
+
+	for _, tt := range []struct {
+		name  string
+		wc    Wc
+		input string
+	}{
+		{"stdin", New().Bytes(true).Chars(true).Lines(true).MaxLineLength(true).Words(true), input},
+		{"files", New().Lines(true).Words(true).Files(files...), ""},
+	} {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			test.Parallel(t)
+			run := func(wc Wc) string {
+				var stdout strings.Builder
+				stdio := unix.NewStdio(strings.NewReader(tt.input), &stdout, io.Discard)
+				err := wc.Run(context.Background(), stdio)
+				require.NoError(t, err)
+				return stdout.String()
+			}
+			expected := run(tt.wc.Parallel(1))
+			for _, threads := range []uint{0, 2, 7} {
+				require.Equal(t, expected, run(tt.wc.Parallel(threads)), "threads=%d", threads)
+			}
+		})
+	}
+}
+
+func TestFiles0From(t *testing.T) {
+	test.Parallel(t)
+	fsys := fstest.MapFS{
+		"three": {Data: []byte("three\n")},
+		"pigs":  {Data: []byte("small\npigs\n")},
+	}
+	wc, err := New().FromArgs([]string{"-l", "--files0-from=-"})
+	require.NoError(t, err)
+
+	var stdout strings.Builder
+	stdio := unix.NewStdio(strings.NewReader("three\x00pigs\x00"), &stdout, io.Discard)
+	err = wc.FS(fsys).Run(context.Background(), stdio)
+	require.NoError(t, err)
+	// names are counted as read, so columns are not aligned like GNU does
+	require.Equal(t, "1 three\n2 pigs\n3 total\n", stdout.String())
+
+	stdout.Reset()
+	stdio = unix.NewStdio(strings.NewReader("pigs\x00"), &stdout, io.Discard)
+	err = wc.FS(fsys).Run(context.Background(), stdio)
+	require.NoError(t, err)
+	require.Equal(t, "2 pigs\n", stdout.String())
+
+	_, err = New().FromArgs([]string{"--files0-from=-", "three"})
+	require.Error(t, err)
+}
+
 func fromArgs(t *testing.T, argv []string) Wc {
 	t.Helper()
 	n := New()